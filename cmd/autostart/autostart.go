@@ -5,39 +5,78 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
-	"strings"
 	"syscall"
 	"time"
 
 	"github.com/go-co-op/gocron/v2"
-	geo "github.com/kellydunn/golang-geo"
 	"github.com/spf13/cobra"
 
 	"github.com/denysvitali/ekz-tesla/cmd/root"
 	"github.com/denysvitali/ekz-tesla/ekz"
+	"github.com/denysvitali/ekz-tesla/history"
+	"github.com/denysvitali/ekz-tesla/rules"
+	"github.com/denysvitali/ekz-tesla/state"
 	"github.com/denysvitali/ekz-tesla/teslamateapi"
 )
 
 var (
-	carID           int
-	teslaMateAPIURL string
-	maximumCharge   int
-	cronSchedule    string
-	highTariffTimes []string
+	carIDs           []int
+	teslaMateAPIURL  string
+	teslaMateToken   string
+	teslaMateTimeout time.Duration
+	maximumCharge    int
+	cronSchedule     string
+	highTariffTimes  []string
+	emergencySoC     int
+	emergencyTarget  int
+	useCarLimit      bool
+	maxDataAge       time.Duration
+	superviseOnce    bool
+	startTimeout     time.Duration
+	startRetry       bool
+	dryRun           bool
+	jsonOutput       bool
+	backoff          = state.DefaultBackoffPolicy
+	restartAttempts  int
+	restartDelay     time.Duration
+	carCommands      CarCommands
 )
 
 var AutostartCmd = &cobra.Command{
 	Use:   "autostart",
 	Short: "Automatically start charging based on conditions",
 	Long: `Autostart monitors your Tesla's location and battery status,
-automatically starting charging when conditions are met.`,
+automatically starting charging when conditions are met.
+
+The car data is read from TeslaMateApi by default, the vehicle section of the
+configuration selects TeslaMate's MQTT topics, an HTTP/JSON endpoint or static
+values instead.
+
+With --dry-run, all conditions are evaluated and the decision is logged,
+but no session is started or stopped.`,
 }
 
 var autostartOnceCmd = &cobra.Command{
 	Use:   "once",
 	Short: "Run autostart check once",
-	Long:  `Check conditions and start charging if needed, then exit.`,
-	RunE:  runAutostartOnce,
+	Long: `Check conditions and start charging if needed, then exit.
+With --json, the decision and the evaluated conditions are printed as JSON.`,
+	RunE: runAutostartOnce,
+}
+
+var autostartExplainCmd = &cobra.Command{
+	Use:   "explain",
+	Short: "Explain whether autostart would charge now, and why",
+	Long: `Evaluate every autostart condition (data freshness, charging state, plugged in,
+tariff, battery level against the target, location at the station, backoff after
+failed start attempts and station state) and print whether it passes, without
+starting or stopping anything.`,
+	Example: `  # Explain the current decision
+  ekz-tesla autostart explain --car-id 1 --teslamate-api-url http://teslamate-api:8080
+
+  # Print the decision as JSON
+  ekz-tesla autostart explain --car-id 1 --teslamate-api-url http://teslamate-api:8080 --json`,
+	RunE: runAutostartExplain,
 }
 
 var autostartScheduledCmd = &cobra.Command{
@@ -57,16 +96,57 @@ Default low-tariff times are outside of Monday-Friday 07:00-20:00.`,
 
 func init() {
 	// Common flags for all autostart commands
-	AutostartCmd.PersistentFlags().IntVar(&carID, "car-id", 0, "TeslaMate car ID (required)")
-	AutostartCmd.PersistentFlags().StringVar(&teslaMateAPIURL, "teslamate-api-url", "", "TeslaMate API URL (required)")
+	AutostartCmd.PersistentFlags().IntSliceVar(&carIDs, "car-id", nil,
+		"TeslaMate IDs of the cars sharing the station, repeatable (default: the cars in the config, or the only car known to TeslaMate)")
+	AutostartCmd.PersistentFlags().StringVar(&teslaMateAPIURL, "teslamate-api-url", "",
+		"TeslaMate API URL (required by the teslamateapi vehicle provider and car commands)")
+	AutostartCmd.PersistentFlags().StringVar(&teslaMateToken, "teslamate-api-token", os.Getenv("TESLAMATE_API_TOKEN"),
+		"TeslaMate API token, as configured in API_TOKEN (default: $TESLAMATE_API_TOKEN)")
+	AutostartCmd.PersistentFlags().DurationVar(&teslaMateTimeout, "teslamate-api-timeout", teslamateapi.DefaultTimeout,
+		"Timeout of the requests to the TeslaMate API")
 	AutostartCmd.PersistentFlags().IntVar(&maximumCharge, "maximum-charge", 90, "Maximum charge percentage")
-
-	if err := AutostartCmd.MarkPersistentFlagRequired("car-id"); err != nil {
-		panic(fmt.Sprintf("Failed to mark car-id flag as required: %v", err))
-	}
-	if err := AutostartCmd.MarkPersistentFlagRequired("teslamate-api-url"); err != nil {
-		panic(fmt.Sprintf("Failed to mark teslamate-api-url flag as required: %v", err))
-	}
+	AutostartCmd.PersistentFlags().IntVar(&emergencySoC, "emergency-soc", 0,
+		"Start charging immediately, even in high tariff, below this charge percentage (0 disables)")
+	AutostartCmd.PersistentFlags().IntVar(&emergencyTarget, "emergency-target", 50,
+		"Charge percentage at which an emergency charge stops until the low tariff begins")
+	AutostartCmd.PersistentFlags().BoolVar(&useCarLimit, "use-car-limit", false,
+		"Use the charge limit configured in the car as target instead of --maximum-charge")
+	AutostartCmd.PersistentFlags().DurationVar(&maxDataAge, "max-data-age", 0,
+		"Treat car data older than this (e.g. while the car is asleep) as unknown (0 disables)")
+	AutostartCmd.PersistentFlags().DurationVar(&startTimeout, "start-timeout", ekz.DefaultStartTimeout,
+		"How long to wait for the car to draw power after starting a session")
+	AutostartCmd.PersistentFlags().BoolVar(&startRetry, "start-retry", false,
+		"Retry the remote start once if it fails")
+	AutostartCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false,
+		"Evaluate the conditions and log the decision without starting or stopping sessions")
+	AutostartCmd.PersistentFlags().DurationVar(&backoff.Initial, "backoff", backoff.Initial,
+		"Wait after a failed start attempt, doubled after every further failure")
+	AutostartCmd.PersistentFlags().DurationVar(&backoff.Max, "backoff-max", backoff.Max,
+		"Maximum wait between failed start attempts")
+	AutostartCmd.PersistentFlags().IntVar(&backoff.MaxAttempts, "max-start-attempts", backoff.MaxAttempts,
+		"Give up after this many failed start attempts per tariff window (0 disables)")
+	AutostartCmd.PersistentFlags().IntVar(&backoff.Hysteresis, "hysteresis", backoff.Hysteresis,
+		"Once the target was reached, only charge again when the battery drops this many percent below it")
+	AutostartCmd.PersistentFlags().IntVar(&restartAttempts, "restart-attempts", defaultRestartAttempts,
+		"Restart a supervised session that ended without a stop command up to this many times (0 disables)")
+	AutostartCmd.PersistentFlags().DurationVar(&restartDelay, "restart-delay", defaultRestartDelay,
+		"Wait before restarting a session that ended without a stop command")
+	AutostartCmd.PersistentFlags().BoolVar(&carCommands.Wake, "wake-car", false,
+		"Wake an asleep car before evaluating the conditions (requires TeslaMateApi commands)")
+	AutostartCmd.PersistentFlags().BoolVar(&carCommands.SetChargeLimit, "set-charge-limit", false,
+		"Set the charge limit of the car to the target before starting a session (requires TeslaMateApi commands)")
+	AutostartCmd.PersistentFlags().BoolVar(&carCommands.ChargeStart, "car-charge-start", false,
+		"Tell the car to start charging once the EKZ session appears in the live data (requires TeslaMateApi commands)")
+
+	// Once-specific flags
+	autostartOnceCmd.Flags().BoolVar(&superviseOnce, "supervise", false,
+		"Wait for a started session and stop it when the target is reached or the car is unplugged")
+	autostartOnceCmd.Flags().BoolVar(&jsonOutput, "json", false, "Print the decision as JSON")
+
+	// Explain flags
+	autostartExplainCmd.Flags().BoolVar(&jsonOutput, "json", false, "Print the decision as JSON")
+	autostartExplainCmd.Flags().StringSliceVar(&highTariffTimes, "high-tariff-times", []string{},
+		"High tariff time ranges (format: 'HH:MM-HH:MM:Mon,Tue,Wed,Thu,Fri')")
 
 	// Scheduled-specific flags
 	autostartScheduledCmd.Flags().StringVar(&cronSchedule, "cron", "*/5 * * * *", "Cron schedule (default: every 5 minutes)")
@@ -79,6 +159,7 @@ func init() {
 	AutostartCmd.AddCommand(autostartOnceCmd)
 	AutostartCmd.AddCommand(autostartScheduledCmd)
 	AutostartCmd.AddCommand(autostartSmartCmd)
+	AutostartCmd.AddCommand(autostartExplainCmd)
 
 	root.RootCmd.AddCommand(AutostartCmd)
 }
@@ -89,7 +170,24 @@ func runAutostartOnce(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	return service.TryAutostart()
+	if superviseOnce {
+		ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer cancel()
+		service.EnableSupervision(ctx)
+	}
+
+	decision, err := service.tryAutostart(false, true)
+	if err != nil {
+		return err
+	}
+	if jsonOutput {
+		if err := printDecisionJSON(decision); err != nil {
+			return err
+		}
+	}
+
+	service.WaitForSupervisor()
+	return nil
 }
 
 func runScheduledAutostart(cmd *cobra.Command, args []string) error {
@@ -101,6 +199,16 @@ func runScheduledAutostart(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service.EnableSupervision(ctx)
+	if !dryRun {
+		service.RunDaemon(ctx)
+	}
+	if service.WatchChanges(ctx, nil) {
+		fmt.Println("Checking immediately when the car data changes")
+	}
+
 	// Create scheduler
 	s, err := gocron.NewScheduler()
 	if err != nil {
@@ -131,6 +239,8 @@ func runScheduledAutostart(cmd *cobra.Command, args []string) error {
 	// Wait for shutdown signal
 	<-sigChan
 	fmt.Println("\nShutting down scheduler...")
+	cancel()
+	service.WaitForSupervisor()
 
 	return nil
 }
@@ -144,28 +254,23 @@ func runSmartAutostart(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	// Parse custom high tariff times if provided
-	var tariffSchedule []ekz.TimeRange
-	if len(highTariffTimes) > 0 {
-		for _, timeStr := range highTariffTimes {
-			tr, err := ekz.ParseTimeRangeString(timeStr)
-			if err != nil {
-				return fmt.Errorf("failed to parse high tariff time '%s': %w", timeStr, err)
-			}
-			tariffSchedule = append(tariffSchedule, tr)
-		}
-		fmt.Printf("Using custom high tariff schedule: %v\n", highTariffTimes)
-	} else {
-		tariffSchedule = ekz.DefaultHighTariffSchedule()
-		fmt.Println("Using default high tariff schedule: Monday-Friday 07:00-20:00")
-	}
-
 	// Create schedule-based scheduler
-	scheduler := ekz.NewScheduleScheduler(service.TryAutostart, tariffSchedule)
+	scheduler, err := newTariffScheduler(service.TryAutostart, true)
+	if err != nil {
+		return err
+	}
+	scheduler.SetHighTariffFunc(service.TryHighTariffAutostart)
 
 	// Set up context for graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
+	service.EnableSupervision(ctx)
+	if !dryRun {
+		service.RunDaemon(ctx)
+	}
+	if service.WatchChanges(ctx, scheduler.IsHighTariffTime) {
+		fmt.Println("Checking immediately when the car data changes")
+	}
 
 	// Set up signal handling
 	sigChan := make(chan os.Signal, 1)
@@ -195,6 +300,8 @@ func runSmartAutostart(cmd *cobra.Command, args []string) error {
 
 	// Stop the scheduler
 	scheduler.Stop()
+	cancel()
+	service.WaitForSupervisor()
 	fmt.Println("Smart autostart scheduler stopped")
 
 	return nil
@@ -230,92 +337,106 @@ func createAutostartService() (*AutostartService, error) {
 		}
 	}
 
-	return NewAutostartService(client, teslaMateAPIURL, carID, maximumCharge, &cfg.ChargingStation)
-}
-
-func waitForTimeSync() {
-	epochPlus1Year := time.Unix(0, 0).Add(365 * 24 * time.Hour)
-	for time.Now().Before(epochPlus1Year) {
-		root.GetLogger().Debug("Waiting for time to be set...")
-		time.Sleep(1 * time.Second)
+	if err := cfg.Cars.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid cars config: %w", err)
 	}
-}
-
-// AutostartService handles the logic for automatically starting charging
-type AutostartService struct {
-	ekzClient       *ekz.Client
-	carAPI          *teslamateapi.Client
-	carID           int
-	maxCharge       int
-	chargingStation *ekz.ChargingStationConfig
-}
-
-// NewAutostartService creates a new autostart service
-func NewAutostartService(ekzClient *ekz.Client, teslaMateAPIURL string, carID int, maxCharge int, chargingStation *ekz.ChargingStationConfig) (*AutostartService, error) {
-	// Normalize the URL
-	teslaMateAPIURL = strings.TrimSuffix(teslaMateAPIURL, "/")
-
-	carAPI, err := teslamateapi.New(teslaMateAPIURL)
+	ids := carIDs
+	if len(ids) == 0 {
+		ids = cfg.Cars.IDs()
+	}
+	vehicles, err := root.NewVehicles(context.Background(), ids, teslaMateAPIURL,
+		teslamateapi.WithToken(teslaMateToken), teslamateapi.WithTimeout(teslaMateTimeout))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create TeslaMate API client: %w", err)
+		return nil, err
 	}
-
-	return &AutostartService{
-		ekzClient:       ekzClient,
-		carAPI:          carAPI,
-		carID:           carID,
-		maxCharge:       maxCharge,
-		chargingStation: chargingStation,
-	}, nil
-}
-
-// TryAutostart attempts to start charging if conditions are met
-func (as *AutostartService) TryAutostart() error {
-	log := root.GetLogger()
-	log.Debugf("Checking autostart conditions for car %d (max charge: %d%%)", as.carID, as.maxCharge)
-
-	status, err := as.carAPI.GetCarStatus(as.carID)
+	cars := make([]*Car, 0, len(vehicles))
+	for _, v := range vehicles {
+		carConfig := cfg.Cars.Car(v.CarID)
+		cars = append(cars, &Car{
+			ID:            v.CarID,
+			Name:          carConfig.Name,
+			Vehicle:       v.Provider,
+			Commander:     v.Commander,
+			MaxCharge:     carConfig.MaximumCharge,
+			ChargeTargets: carConfig.ChargeTargets,
+			Priority:      carConfig.Priority,
+			Capacity:      carConfig.Capacity(),
+		})
+	}
+	service := NewAutostartService(client, cars, maximumCharge, &cfg.ChargingStation)
+	geofence, err := ekz.NewGeofence(cfg.ChargingStation)
 	if err != nil {
-		return fmt.Errorf("failed to get car status: %w", err)
+		return nil, fmt.Errorf("invalid charging_station config: %w", err)
 	}
+	service.SetGeofence(geofence)
 
-	// Check if already charging
-	if status.Status.State == "charging" {
-		log.Info("Car is already charging")
-		return nil
+	if err := service.SetEmergencyCharge(emergencySoC, emergencyTarget); err != nil {
+		return nil, err
 	}
 
-	// Check if plugged in
-	if !status.Status.ChargingDetails.PluggedIn {
-		log.Warn("Car is not plugged in")
-		return nil
+	if err := cfg.ChargeTargets.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid charge_targets config: %w", err)
 	}
-
-	// Check battery level
-	if status.Status.BatteryDetails.BatteryLevel >= as.maxCharge {
-		log.Infof("Car battery at %d%% (max: %d%%)", status.Status.BatteryDetails.BatteryLevel, as.maxCharge)
-		return nil
+	service.SetChargeTargets(&cfg.ChargeTargets)
+	if err := service.SetBackoff(backoff); err != nil {
+		return nil, err
 	}
+	if err := service.SetRestart(restartAttempts, restartDelay); err != nil {
+		return nil, err
+	}
+	service.SetAlerts(cfg.Alerts.WebhookURL)
+	if err := service.SetCarCommands(carCommands); err != nil {
+		return nil, err
+	}
+	if err := service.SetFairness(cfg.Fairness); err != nil {
+		return nil, fmt.Errorf("invalid fairness config: %w", err)
+	}
+	service.useCarLimit = useCarLimit
+	service.maxDataAge = maxDataAge
+	service.startOptions = ekz.StartOptions{Timeout: startTimeout, Retry: startRetry}
+	ruleSet, err := rules.CompileSet(cfg.Rules.Start, cfg.Rules.Stop)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rules config: %w", err)
+	}
+	service.SetRules(ruleSet)
+	tariffScheduler, err := newTariffScheduler(nil, false)
+	if err != nil {
+		return nil, err
+	}
+	service.SetTariffs(tariffScheduler, cfg.Tariffs)
+	service.SetDryRun(dryRun)
+	service.history = history.New(history.DefaultPath())
+	service.state = state.New(state.DefaultPath())
+	service.catchUpWindow = cfg.Queue.CatchUpWindow
 
-	// Check distance from charging station
-	p1 := geo.NewPoint(as.chargingStation.Latitude, as.chargingStation.Longitude)
-	p2 := geo.NewPoint(status.Status.CarGeodata.Latitude, status.Status.CarGeodata.Longitude)
-
-	distanceKm := p1.GreatCircleDistance(p2)
-	distanceMeters := distanceKm * 1000
-	log.Debugf("Distance from charging station: %.1f meters", distanceMeters)
+	return service, nil
+}
 
-	if distanceMeters > 100 {
-		log.Warn("Car is not near the charging station")
-		return nil
+// newTariffScheduler creates the scheduler for the high tariff times given as flag,
+// or the configured tariff versions, or the default schedule
+func newTariffScheduler(autostartFunc func() error, verbose bool) (*ekz.ScheduleScheduler, error) {
+	cfg := root.GetConfig()
+	scheduler, err := ekz.NewTariffScheduler(autostartFunc, highTariffTimes, cfg.Tariffs)
+	if err != nil {
+		return nil, err
 	}
-
-	// All conditions met, start charging
-	log.Info("All conditions met, starting charge...")
-	if err := as.ekzClient.StartCharge(as.chargingStation.BoxId, as.chargingStation.ConnectorId); err != nil {
-		return fmt.Errorf("failed to start charge: %w", err)
+	if verbose {
+		switch {
+		case len(highTariffTimes) > 0:
+			fmt.Printf("Using custom high tariff schedule: %v\n", highTariffTimes)
+		case len(cfg.Tariffs) > 0:
+			fmt.Printf("Using %d configured tariff version(s), selected by date\n", len(cfg.Tariffs))
+		default:
+			fmt.Println("Using default high tariff schedule: Monday-Friday 07:00-20:00")
+		}
 	}
+	return scheduler, nil
+}
 
-	log.Info("✅ Successfully started charging")
-	return nil
-}
\ No newline at end of file
+func waitForTimeSync() {
+	epochPlus1Year := time.Unix(0, 0).Add(365 * 24 * time.Hour)
+	for time.Now().Before(epochPlus1Year) {
+		root.GetLogger().Debug("Waiting for time to be set...")
+		time.Sleep(1 * time.Second)
+	}
+}
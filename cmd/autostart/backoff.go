@@ -0,0 +1,110 @@
+package autostart
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/denysvitali/ekz-tesla/cmd/root"
+	"github.com/denysvitali/ekz-tesla/state"
+)
+
+// SetBackoff sets how often start attempts are made after failures
+func (as *AutostartService) SetBackoff(policy state.BackoffPolicy) error {
+	if policy.Initial < 0 || policy.Max < 0 || policy.MaxAttempts < 0 {
+		return fmt.Errorf("backoff durations and the maximum number of start attempts can't be negative")
+	}
+	if policy.Hysteresis < 0 || policy.Hysteresis > 100 {
+		return fmt.Errorf("hysteresis must be between 0 and 100")
+	}
+	as.backoff = policy
+	return nil
+}
+
+// tariffWindow identifies the tariff period containing now
+func (as *AutostartService) tariffWindow(now time.Time) string {
+	if as.tariffScheduler == nil {
+		return now.Format("2006-01-02")
+	}
+	return as.tariffScheduler.PeriodStart(now).Format("2006-01-02T15:04")
+}
+
+// stationState returns the persisted state of the configured station as of the given window
+func (as *AutostartService) stationState(window string) state.StationState {
+	var station state.StationState
+	if as.state != nil {
+		st, err := as.state.Load()
+		if err != nil {
+			root.GetLogger().Warnf("Failed to load the station state: %v", err)
+		} else {
+			station = *st.Station(as.chargingStation.BoxId, as.chargingStation.ConnectorId)
+		}
+	}
+	station.EnterWindow(window)
+	return station
+}
+
+// updateStation updates the persisted state of the configured station
+func (as *AutostartService) updateStation(window string, fn func(*state.StationState)) *state.StationState {
+	var station state.StationState
+	if as.state == nil {
+		fn(&station)
+		return &station
+	}
+	err := as.state.Update(func(st *state.State) error {
+		s := st.Station(as.chargingStation.BoxId, as.chargingStation.ConnectorId)
+		s.EnterWindow(window)
+		fn(s)
+		station = *s
+		return nil
+	})
+	if err != nil {
+		root.GetLogger().Warnf("Failed to save the station state: %v", err)
+	}
+	return &station
+}
+
+// backoffCheck checks whether a start attempt is allowed
+func (as *AutostartService) backoffCheck(station *state.StationState, now time.Time) Check {
+	phase := station.Phase(now)
+	check := Check{Name: "backoff", Passed: phase == state.StationReady, Value: string(phase), Want: string(state.StationReady)}
+	if as.backoff.MaxAttempts > 0 {
+		check.Value = fmt.Sprintf("%s (%d/%d failed attempts)", phase, station.WindowFailures, as.backoff.MaxAttempts)
+	}
+	if !check.Passed {
+		check.Message = station.Describe(now)
+	}
+	return check
+}
+
+// observe records the battery level for the hysteresis
+func (as *AutostartService) observe(d *Decision) {
+	if d.station == nil || d.Emergency || d.Target == 0 {
+		return
+	}
+	// Without a car at the station or fresh data there is nothing to observe
+	if check, failed := d.failed(); failed && (check.Name == "car" || check.Name == "data_fresh") {
+		return
+	}
+	as.updateStation(d.station.Window, func(s *state.StationState) {
+		s.SwitchCar(d.CarID)
+		s.ObserveSoC(d.batteryLevel, d.Target, as.backoff)
+	})
+}
+
+// recordStart records the outcome of a start attempt and alerts when autostart gives up
+func (as *AutostartService) recordStart(now time.Time, startErr error) {
+	log := root.GetLogger()
+	window := as.tariffWindow(now)
+	var gaveUp bool
+	station := as.updateStation(window, func(s *state.StationState) {
+		gaveUp = s.RecordAttempt(time.Now(), startErr, as.backoff)
+	})
+	switch {
+	case gaveUp:
+		as.alert("Autostart gave up", fmt.Sprintf("giving up on station %s/%d until the next tariff window: %d start attempts failed (last error: %s)",
+			as.chargingStation.BoxId, as.chargingStation.ConnectorId, station.WindowFailures, station.LastError))
+	case startErr != nil:
+		log.Warnf("Start attempt %d failed, next attempt at %s", station.WindowAttempts,
+			station.NextAttempt.Local().Format("15:04:05"))
+	}
+}
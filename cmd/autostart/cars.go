@@ -0,0 +1,236 @@
+package autostart
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/denysvitali/ekz-tesla/cmd/root"
+	"github.com/denysvitali/ekz-tesla/ekz"
+	"github.com/denysvitali/ekz-tesla/vehicle"
+)
+
+// minCorrelationEnergy is the energy in kWh a session charges before the car charging is
+// identified from the energy added to the batteries
+const minCorrelationEnergy = 1.0
+
+// Car is a car charged on the station
+type Car struct {
+	// ID is the TeslaMate ID of the car
+	ID      int
+	Name    string
+	Vehicle vehicle.Provider
+	// Commander sends commands to the car, nil without TeslaMateApi
+	Commander vehicle.Commander
+	// MaxCharge and ChargeTargets replace the ones of the service when set
+	MaxCharge     int
+	ChargeTargets *ekz.ChargeTargetConfig
+	// Priority orders the cars with the priority fairness policy
+	Priority int
+	// Capacity is the usable battery capacity in kWh
+	Capacity float64
+}
+
+// String returns the ID of the car, with its name if known
+func (c *Car) String() string {
+	if c.Name == "" {
+		return fmt.Sprintf("car %d", c.ID)
+	}
+	return fmt.Sprintf("car %d (%s)", c.ID, c.Name)
+}
+
+// carsString lists the cars for the log
+func (as *AutostartService) carsString() string {
+	names := make([]string, len(as.cars))
+	for i, car := range as.cars {
+		names[i] = car.String()
+	}
+	return strings.Join(names, ", ")
+}
+
+// carStatus is the status of a car, or the error reading it
+type carStatus struct {
+	car    *Car
+	status *vehicle.Status
+	err    error
+}
+
+// statuses reads the status of all cars concurrently, in the order of the cars
+func (as *AutostartService) statuses(ctx context.Context) []carStatus {
+	statuses := make([]carStatus, len(as.cars))
+	var wg sync.WaitGroup
+	for i, car := range as.cars {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			status, err := car.Vehicle.Status(ctx)
+			statuses[i] = carStatus{car: car, status: status, err: err}
+		}()
+	}
+	wg.Wait()
+	return statuses
+}
+
+// carByID returns the car with the given ID, nil if it isn't charged on the station
+func (as *AutostartService) carByID(id int) *Car {
+	for _, car := range as.cars {
+		if car.ID == id {
+			return car
+		}
+	}
+	return nil
+}
+
+// currentCar returns the car of the running session, otherwise the car selected by the last evaluation
+func (as *AutostartService) currentCar() *Car {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	return as.currentCarLocked()
+}
+
+func (as *AutostartService) currentCarLocked() *Car {
+	switch {
+	case as.sessionCar != nil:
+		return as.sessionCar
+	case as.selected != nil:
+		return as.selected
+	default:
+		return as.cars[0]
+	}
+}
+
+// selectCar determines the car at the station and returns it with its status. With several
+// cars, it is the car of the running session, otherwise the car plugged in near the station,
+// and the returned check tells how it was chosen. When several cars are plugged in near the
+// station, the one charging or needing the most charge is chosen; once a session runs,
+// identifyCar corrects the choice from the energy added to the batteries.
+func (as *AutostartService) selectCar(ctx context.Context, now time.Time) (*Car, *vehicle.Status, *Check, error) {
+	if len(as.cars) == 1 {
+		car := as.cars[0]
+		status, err := car.Vehicle.Status(ctx)
+		return car, status, nil, err
+	}
+
+	check := &Check{Name: "car", Passed: true, Want: "plugged in at the station"}
+	as.mu.Lock()
+	sessionCar := as.sessionCar
+	as.mu.Unlock()
+	if sessionCar != nil {
+		status, err := sessionCar.Vehicle.Status(ctx)
+		check.Value = sessionCar.String() + ", charging in the running session"
+		return sessionCar, status, check, err
+	}
+
+	var candidates, available []carStatus
+	for _, cs := range as.statuses(ctx) {
+		if cs.err != nil {
+			root.GetLogger().Warnf("Failed to get the status of %s: %v", cs.car, cs.err)
+			continue
+		}
+		available = append(available, cs)
+		if cs.status.PluggedIn && cs.status.CurrentChargingState() != vehicle.ChargingStateDisconnected && as.nearStation(*cs.status) {
+			candidates = append(candidates, cs)
+		}
+	}
+	if len(available) == 0 {
+		return nil, nil, nil, fmt.Errorf("failed to get the status of any car")
+	}
+
+	var chosen carStatus
+	switch len(candidates) {
+	case 0:
+		// Evaluate the first car, its checks tell why it can't charge
+		chosen = available[0]
+		check.Passed = false
+		check.Value = "none"
+		check.Message = "no car is plugged in at the station"
+	case 1:
+		chosen = candidates[0]
+		check.Value = chosen.car.String()
+	default:
+		chosen = as.mostInNeed(candidates, now)
+		var names []string
+		for _, cs := range candidates {
+			names = append(names, cs.car.String())
+		}
+		check.Value = fmt.Sprintf("%s, of %s", chosen.car, strings.Join(names, ", "))
+	}
+
+	as.mu.Lock()
+	as.selected = chosen.car
+	as.mu.Unlock()
+	return chosen.car, chosen.status, check, nil
+}
+
+// mostInNeed returns the car that is charging, otherwise the one furthest below its target
+func (as *AutostartService) mostInNeed(candidates []carStatus, now time.Time) carStatus {
+	best, bestNeed := candidates[0], -101
+	for _, cs := range candidates {
+		if cs.status.Charging() {
+			return cs
+		}
+		if need := as.targetAt(cs.car, now) - cs.status.SoC; need > bestNeed {
+			best, bestNeed = cs, need
+		}
+	}
+	return best
+}
+
+// readBaseline reads the energy added to the batteries of the cars when a session starts,
+// for identifyCar. It is nil with a single car, which needs no identification.
+func (as *AutostartService) readBaseline(ctx context.Context) map[int]float64 {
+	if len(as.cars) < 2 {
+		return nil
+	}
+	baseline := make(map[int]float64)
+	for _, cs := range as.statuses(ctx) {
+		if cs.err == nil {
+			baseline[cs.car.ID] = cs.status.ChargeEnergyAdded
+		}
+	}
+	return baseline
+}
+
+// identifyCar confirms or corrects the car of the running session once it charged enough, by
+// comparing the energy added to the batteries since the start with the energy metered by the station
+func (as *AutostartService) identifyCar(liveData *ekz.LiveDataResponse) {
+	as.mu.Lock()
+	baseline, sessionCar := as.baseline, as.sessionCar
+	as.mu.Unlock()
+	if baseline == nil || liveData == nil || liveData.ChargedEnergy < minCorrelationEnergy {
+		return
+	}
+
+	current := make(map[int]float64)
+	for _, cs := range as.statuses(as.context()) {
+		if cs.err == nil {
+			current[cs.car.ID] = cs.status.ChargeEnergyAdded
+		}
+	}
+	id, ok := vehicle.CorrelateEnergy(baseline, current, liveData.ChargedEnergy)
+	if !ok {
+		return
+	}
+	car := as.carByID(id)
+
+	log := root.GetLogger()
+	as.mu.Lock()
+	as.baseline = nil
+	if car == sessionCar {
+		as.mu.Unlock()
+		log.Debugf("The energy added to the battery confirms %s is charging", car)
+		return
+	}
+	as.sessionCar = car
+	if !as.emergencyActive {
+		as.sessionTarget = as.targetAt(car, time.Now())
+	}
+	target, emergency := as.sessionTarget, as.emergencyActive
+	as.mu.Unlock()
+
+	log.Warnf("The energy charged so far (%.2f kWh) was added to %s, not %s: continuing the session for %s with a target of %d%%",
+		liveData.ChargedEnergy, car, sessionCar, car, target)
+	as.updateOwnedSession(target, emergency, car.ID)
+}
@@ -0,0 +1,193 @@
+package autostart
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/ekz-tesla/ekz"
+	"github.com/denysvitali/ekz-tesla/vehicle"
+)
+
+// newTwoCarService creates a service charging car 1 (Model 3) and car 2 (Model Y), both at the
+// station and plugged in at 50% unless the given functions change their status
+func newTwoCarService(t *testing.T, status1, status2 func(*vehicle.Status)) (*AutostartService, [2]*Car, [2]*fakeVehicle) {
+	t.Helper()
+	var cars [2]*Car
+	var fakes [2]*fakeVehicle
+	for i, fn := range []func(*vehicle.Status){status1, status2} {
+		status := atStation()
+		if fn != nil {
+			fn(&status)
+		}
+		cars[i], fakes[i] = newTestCar(i+1, status)
+	}
+	cars[0].Name, cars[1].Name = "Model 3", "Model Y"
+	return newTestService(t, cars[0], cars[1]), cars, fakes
+}
+
+func unplugged(s *vehicle.Status) {
+	s.PluggedIn = false
+	s.ChargingState = vehicle.ChargingStateDisconnected
+}
+
+func TestSelectCar(t *testing.T) {
+	tests := []struct {
+		name             string
+		status1, status2 func(*vehicle.Status)
+		// unavailable makes the status of car 2 fail
+		unavailable bool
+		want        int
+		failed      bool
+		value       string
+	}{
+		{
+			name:    "only one car plugged in",
+			status1: unplugged,
+			want:    2,
+			value:   "car 2 (Model Y)",
+		},
+		{
+			name:    "no car plugged in",
+			status1: unplugged,
+			status2: unplugged,
+			want:    1,
+			failed:  true,
+			value:   "none",
+		},
+		{
+			name: "plugged in away from the station",
+			status1: func(s *vehicle.Status) {
+				s.Location = &vehicle.Location{Latitude: testLatitude + 0.01, Longitude: testLongitude}
+			},
+			want:  2,
+			value: "car 2 (Model Y)",
+		},
+		{
+			name:        "status of a car unavailable",
+			unavailable: true,
+			want:        1,
+			value:       "car 1 (Model 3)",
+		},
+		{
+			name:    "both plugged in, the emptier car is chosen",
+			status1: func(s *vehicle.Status) { s.SoC = 60 },
+			status2: func(s *vehicle.Status) { s.SoC = 40 },
+			want:    2,
+			value:   "car 2 (Model Y), of car 1 (Model 3), car 2 (Model Y)",
+		},
+		{
+			name: "both plugged in, the charging car is chosen",
+			status1: func(s *vehicle.Status) {
+				s.SoC = 70
+				s.ChargingState = vehicle.ChargingStateCharging
+			},
+			status2: func(s *vehicle.Status) { s.SoC = 40 },
+			want:    1,
+			value:   "car 1 (Model 3), of car 1 (Model 3), car 2 (Model Y)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			as, cars, fakes := newTwoCarService(t, tt.status1, tt.status2)
+			if tt.unavailable {
+				fakes[1].err = errors.New("TeslaMate unreachable")
+			}
+
+			car, status, check, err := as.selectCar(context.Background(), time.Now())
+			require.NoError(t, err)
+			require.NotNil(t, check)
+			assert.Equal(t, tt.want, car.ID)
+			assert.Equal(t, cars[tt.want-1], as.selected)
+			require.NotNil(t, status)
+			assert.Equal(t, !tt.failed, check.Passed)
+			assert.Equal(t, tt.value, check.Value)
+		})
+	}
+}
+
+func TestSelectCar_RunningSession(t *testing.T) {
+	as, cars, _ := newTwoCarService(t, func(s *vehicle.Status) { s.SoC = 40 }, nil)
+	as.sessionCar = cars[1]
+
+	car, _, check, err := as.selectCar(context.Background(), time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, cars[1], car)
+	assert.Equal(t, "car 2 (Model Y), charging in the running session", check.Value)
+}
+
+func TestSelectCar_NoStatus(t *testing.T) {
+	as, _, fakes := newTwoCarService(t, nil, nil)
+	fakes[0].err = errors.New("TeslaMate unreachable")
+	fakes[1].err = errors.New("TeslaMate unreachable")
+
+	_, _, _, err := as.selectCar(context.Background(), time.Now())
+	assert.ErrorContains(t, err, "failed to get the status of any car")
+}
+
+func TestMostInNeed(t *testing.T) {
+	as, cars, _ := newTwoCarService(t, nil, nil)
+	// Car 1 is further from the maximum charge of the service, car 2 from its own target
+	cars[1].MaxCharge = 95
+	candidates := []carStatus{
+		{car: cars[0], status: &vehicle.Status{SoC: 40}},
+		{car: cars[1], status: &vehicle.Status{SoC: 50}},
+	}
+	assert.Equal(t, cars[1], as.mostInNeed(candidates, time.Now()).car)
+
+	cars[1].MaxCharge = 0
+	assert.Equal(t, cars[0], as.mostInNeed(candidates, time.Now()).car)
+}
+
+func TestIdentifyCar(t *testing.T) {
+	tests := []struct {
+		name string
+		// added is the energy added to the batteries of the cars since the start
+		added   [2]float64
+		charged float64
+		want    int
+		// identified is whether the identification is done
+		identified bool
+	}{
+		{"not enough energy charged yet", [2]float64{0, 0.5}, 0.6, 1, false},
+		{"car re-identified after 1 kWh", [2]float64{0, 0.9}, 1, 2, true},
+		{"car confirmed", [2]float64{0.9, 0}, 1, 1, true},
+		{"both cars charged", [2]float64{0.9, 0.9}, 1, 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			as, cars, fakes := newTwoCarService(t, nil, nil)
+			cars[1].MaxCharge = 90
+			as.sessionCar = cars[0]
+			as.sessionTarget = 80
+			as.ownSession(1, 80, false, cars[0].ID)
+			as.baseline = map[int]float64{1: 10, 2: 20}
+			for i, fake := range fakes {
+				fake.set(func(s *vehicle.Status) { s.ChargeEnergyAdded = as.baseline[i+1] + tt.added[i] })
+			}
+
+			as.identifyCar(&ekz.LiveDataResponse{TransactionID: 1, ChargedEnergy: tt.charged})
+			assert.Equal(t, cars[tt.want-1], as.sessionCar)
+			assert.Equal(t, tt.identified, as.baseline == nil)
+
+			st, err := as.state.Load()
+			require.NoError(t, err)
+			session, ok := st.Session("1234", 1)
+			require.True(t, ok)
+			assert.Equal(t, tt.want, session.CarID)
+			if tt.want == 2 {
+				// The session continues with the target of the car charging
+				assert.Equal(t, 90, as.sessionTarget)
+				assert.Equal(t, 90, session.Target)
+			} else {
+				assert.Equal(t, 80, as.sessionTarget)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package autostart
+
+import (
+	"context"
+	"time"
+
+	"github.com/denysvitali/ekz-tesla/cmd/root"
+	"github.com/denysvitali/ekz-tesla/vehicle"
+)
+
+// changeDebounce coalesces bursts of changes, e.g. the retained values received on connect
+// or the latitude and longitude of a single position, into one evaluation
+const changeDebounce = 5 * time.Second
+
+// WatchChanges evaluates the conditions as soon as the vehicle provider reports a relevant change
+// of the car data, e.g. the car was plugged in, in addition to the scheduled checks. highTariff
+// tells whether the high tariff rules apply at a time, nil if they never do. It returns false if
+// no provider reports changes.
+func (as *AutostartService) WatchChanges(ctx context.Context, highTariff func(time.Time) bool) bool {
+	// A single pending evaluation is enough, it reads the latest data
+	changes := make(chan vehicle.Change, 1)
+	watching := false
+	for _, car := range as.cars {
+		notifier, ok := car.Vehicle.(vehicle.Notifier)
+		if !ok {
+			continue
+		}
+		watching = true
+		notifier.OnChange(func(change vehicle.Change) {
+			select {
+			case changes <- change:
+			default:
+			}
+		})
+	}
+	if !watching {
+		return false
+	}
+
+	as.supervisorWg.Add(1)
+	go func() {
+		defer as.supervisorWg.Done()
+		log := root.GetLogger()
+		for {
+			var change vehicle.Change
+			select {
+			case <-ctx.Done():
+				return
+			case change = <-changes:
+			}
+
+			timer := time.NewTimer(changeDebounce)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			select {
+			case <-changes:
+			default:
+			}
+
+			log.Debugf("Car %s changed to %s, checking autostart conditions", change.Name, change.Value)
+			high := highTariff != nil && highTariff(time.Now())
+			// Don't wake the car, it may have just fallen asleep
+			if _, err := as.tryAutostart(high, false); err != nil {
+				log.Errorf("Autostart failed: %v", err)
+			}
+		}
+	}()
+	return true
+}
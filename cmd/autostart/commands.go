@@ -0,0 +1,141 @@
+package autostart
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/denysvitali/ekz-tesla/cmd/root"
+	"github.com/denysvitali/ekz-tesla/ekz"
+)
+
+const (
+	// wakeTimeout is how long to wait for TeslaMate to report the car online after a wake-up
+	wakeTimeout      = time.Minute
+	wakePollInterval = 5 * time.Second
+)
+
+// CarCommands selects the commands autostart sends to the car through TeslaMateApi
+type CarCommands struct {
+	// Wake wakes an asleep car before evaluating the conditions, so that its data is fresh
+	Wake bool
+	// SetChargeLimit sets the charge limit of the car to the target before starting a session
+	SetChargeLimit bool
+	// ChargeStart makes the car start charging once the EKZ session appears in the live data
+	ChargeStart bool
+}
+
+// Any tells whether a command is selected
+func (c CarCommands) Any() bool {
+	return c.Wake || c.SetChargeLimit || c.ChargeStart
+}
+
+// SetCarCommands selects the commands sent to the cars through their commander. Commands
+// need a commander, which only TeslaMateApi offers.
+func (as *AutostartService) SetCarCommands(commands CarCommands) error {
+	for _, car := range as.cars {
+		if commands.Any() && car.Commander == nil {
+			return fmt.Errorf("car commands require the TeslaMate API URL")
+		}
+	}
+	as.carCommands = commands
+	return nil
+}
+
+// wakeCars wakes up the cars that may be charged. With several cars, only the cars last
+// seen plugged in are woken up, the others can't be charged anyway.
+func (as *AutostartService) wakeCars(ctx context.Context) {
+	if len(as.cars) == 1 {
+		as.wakeCar(ctx, as.cars[0])
+		return
+	}
+	var wg sync.WaitGroup
+	for _, cs := range as.statuses(ctx) {
+		if cs.err != nil || !cs.status.PluggedIn {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			as.wakeCar(ctx, cs.car)
+		}()
+	}
+	wg.Wait()
+}
+
+// wakeCar wakes the car up if the provider reports it asleep or offline, and waits until it is online
+func (as *AutostartService) wakeCar(ctx context.Context, car *Car) {
+	log := root.GetLogger()
+	status, err := car.Vehicle.Status(ctx)
+	if err != nil {
+		log.Warnf("Failed to get the status of %s before waking it up: %v", car, err)
+		return
+	}
+	switch status.State {
+	case "asleep", "offline", "suspended":
+	default:
+		return
+	}
+
+	log.Infof("%s is %s, waking it up", capitalize(car.String()), status.State)
+	if _, err := car.Commander.WakeUp(ctx); err != nil {
+		log.Warnf("Failed to wake %s up: %v", car, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, wakeTimeout)
+	defer cancel()
+	ticker := time.NewTicker(wakePollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Warnf("%s is not online %s after the wake-up, deciding on the data available", capitalize(car.String()), wakeTimeout)
+			return
+		case <-ticker.C:
+		}
+		status, err := car.Vehicle.Status(ctx)
+		if err == nil && status.State == "online" {
+			log.Infof("%s is online", capitalize(car.String()))
+			return
+		}
+	}
+}
+
+// prepareCar sets the charge limit of the car to the target of the session
+func (as *AutostartService) prepareCar(ctx context.Context, car *Car, target int) {
+	if !as.carCommands.SetChargeLimit || target == 0 {
+		return
+	}
+	if err := car.Commander.SetChargeLimit(ctx, target); err != nil {
+		root.GetLogger().Warnf("Failed to set the charge limit of %s to %d%%: %v", car, target, err)
+		return
+	}
+	root.GetLogger().Infof("Charge limit of %s set to %d%%", car, target)
+}
+
+// capitalize capitalizes the first letter of a log message
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// startOptionsForCar returns the start options, making the car start charging once the session
+// appears in the live data
+func (as *AutostartService) startOptionsForCar(car *Car) ekz.StartOptions {
+	opts := as.startOptions
+	if as.carCommands.ChargeStart {
+		opts.Started = func(ctx context.Context) error {
+			if err := car.Commander.ChargeStart(ctx); err != nil {
+				return fmt.Errorf("failed to start charging on %s: %w", car, err)
+			}
+			root.GetLogger().Debugf("%s told to start charging", capitalize(car.String()))
+			return nil
+		}
+	}
+	return opts
+}
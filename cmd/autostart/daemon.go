@@ -0,0 +1,219 @@
+package autostart
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/denysvitali/ekz-tesla/cmd/root"
+	"github.com/denysvitali/ekz-tesla/ekz"
+	"github.com/denysvitali/ekz-tesla/state"
+)
+
+// RunDaemon registers this process as the running daemon until ctx is cancelled: it resumes
+// the supervision of the sessions persisted by a previous daemon, supervises the sessions
+// handed over by `start --detach` and runs the queued actions
+func (as *AutostartService) RunDaemon(ctx context.Context) {
+	if as.state == nil {
+		return
+	}
+	as.recoverSessions()
+	as.interruptActions()
+
+	as.supervisorWg.Add(1)
+	go func() {
+		defer as.supervisorWg.Done()
+
+		ticker := time.NewTicker(state.DaemonHeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			as.daemonTick(ctx)
+			select {
+			case <-ctx.Done():
+				as.unregisterDaemon()
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// interruptActions marks the queued actions a previous daemon left running as failed, they
+// may have run partly and are not retried
+func (as *AutostartService) interruptActions() {
+	var interrupted []state.Action
+	if err := as.state.Update(func(st *state.State) error {
+		interrupted = st.InterruptRunning()
+		return nil
+	}); err != nil {
+		root.GetLogger().Warnf("Failed to update daemon state: %v", err)
+	}
+	for _, action := range interrupted {
+		root.GetLogger().Warnf("Queued action %d was interrupted by the previous daemon: %s", action.ID, action)
+	}
+}
+
+// daemonTick refreshes the heartbeat, picks up newly handed over sessions
+// and runs the queued actions that are due
+func (as *AutostartService) daemonTick(ctx context.Context) {
+	log := root.GetLogger()
+	catchUpWindow := as.catchUpWindow
+	if catchUpWindow == 0 {
+		catchUpWindow = state.DefaultCatchUpWindow
+	}
+
+	var sessions []state.Session
+	var due, missed, pruned []state.Action
+	err := as.state.Update(func(st *state.State) error {
+		now := time.Now()
+		st.Daemon = &state.Daemon{PID: os.Getpid(), Heartbeat: now}
+		sessions = append(sessions, st.Sessions...)
+		pruned = st.PruneFailed(now)
+		due, missed = st.StartDue(now, catchUpWindow)
+		return nil
+	})
+	if err != nil {
+		log.Warnf("Failed to update daemon state: %v", err)
+		return
+	}
+
+	for _, session := range sessions {
+		if session.Owner == state.OwnerDaemon {
+			as.superviseDelegated(ctx, session)
+		}
+	}
+	for _, action := range pruned {
+		log.Infof("Removed failed queued action %d from the queue: %s", action.ID, action)
+	}
+	for _, action := range missed {
+		log.Warnf("Skipping queued action %d, its time passed more than %s ago: %s", action.ID, catchUpWindow, action)
+	}
+	for _, action := range due {
+		as.supervisorWg.Add(1)
+		go func() {
+			defer as.supervisorWg.Done()
+			err := as.runAction(ctx, action)
+			if err != nil {
+				log.Errorf("Queued action %d failed: %v", action.ID, err)
+			}
+			if err := as.state.Update(func(st *state.State) error {
+				st.FinishAction(action.ID, err)
+				return nil
+			}); err != nil {
+				log.Warnf("Failed to update daemon state: %v", err)
+			}
+		}()
+	}
+}
+
+// runAction executes a queued action
+func (as *AutostartService) runAction(ctx context.Context, action state.Action) error {
+	log := root.GetLogger()
+	log.Infof("Running queued action %d: %s", action.ID, action)
+
+	switch action.Kind {
+	case state.ActionStart:
+		liveData, err := as.ekzClient.StartChargeContext(ctx, action.ChargeBoxID, action.ConnectorID, as.startOptions)
+		if err != nil {
+			return fmt.Errorf("failed to start charge: %w", err)
+		}
+		log.Infof("✅ Queued start: session %d is charging at %.2f kW", liveData.TransactionID, liveData.Power)
+		if action.Limits.IsZero() {
+			return nil
+		}
+
+		session := state.Session{
+			ChargeBoxID:   action.ChargeBoxID,
+			ConnectorID:   action.ConnectorID,
+			TransactionID: liveData.TransactionID,
+			Limits:        action.Limits,
+			Requested:     time.Now(),
+		}
+		if err := as.state.Update(func(st *state.State) error {
+			st.SetSession(session)
+			return nil
+		}); err != nil {
+			log.Warnf("Failed to update daemon state: %v", err)
+		}
+		as.superviseDelegated(ctx, session)
+		return nil
+	case state.ActionStop:
+		as.requestStop(action.ChargeBoxID, action.ConnectorID)
+		final, err := as.ekzClient.StopAndVerify(ctx, action.ChargeBoxID, action.ConnectorID, ekz.StopOptions{Retry: true})
+		if errors.Is(err, ekz.ErrNoActiveSession) {
+			log.Infof("Queued stop: no active session on box %s, connector %d", action.ChargeBoxID, action.ConnectorID)
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to stop charge: %w", err)
+		}
+		as.recordSession(action.ChargeBoxID, action.ConnectorID, &ekz.SessionResult{
+			Reason:   fmt.Sprintf("queued stop %d", action.ID),
+			Stopped:  true,
+			LiveData: final,
+		})
+		return nil
+	default:
+		return fmt.Errorf("unknown action %q", action.Kind)
+	}
+}
+
+func (as *AutostartService) unregisterDaemon() {
+	err := as.state.Update(func(st *state.State) error {
+		if st.Daemon != nil && st.Daemon.PID == os.Getpid() {
+			st.Daemon = nil
+		}
+		return nil
+	})
+	if err != nil {
+		root.GetLogger().Warnf("Failed to update daemon state: %v", err)
+	}
+}
+
+// superviseDelegated supervises a handed over session with its limits, unless it is already supervised
+func (as *AutostartService) superviseDelegated(ctx context.Context, session state.Session) {
+	key := fmt.Sprintf("%s/%d/%d", session.ChargeBoxID, session.ConnectorID, session.TransactionID)
+	as.mu.Lock()
+	if as.delegated == nil {
+		as.delegated = make(map[string]bool)
+	}
+	if as.delegated[key] {
+		as.mu.Unlock()
+		return
+	}
+	as.delegated[key] = true
+	as.mu.Unlock()
+
+	log := root.GetLogger()
+	log.Infof("Supervising %s", session)
+
+	as.supervisorWg.Add(1)
+	go func() {
+		defer as.supervisorWg.Done()
+		defer func() {
+			as.mu.Lock()
+			delete(as.delegated, key)
+			as.mu.Unlock()
+		}()
+
+		supervisor := ekz.NewSessionSupervisor(as.ekzClient, session.ChargeBoxID, session.ConnectorID)
+		for _, condition := range session.Limits.Conditions(as.tariffs) {
+			supervisor.AddStopCondition(condition)
+		}
+
+		result, err := supervisor.Run(ctx)
+		if errors.Is(err, context.Canceled) {
+			// Keep the session in the state, the next daemon picks it up again
+			return
+		}
+		if err != nil {
+			log.Errorf("Supervision of session %d failed: %v", session.TransactionID, err)
+		} else {
+			as.recordSession(session.ChargeBoxID, session.ConnectorID, result)
+		}
+
+		as.forgetSession(session)
+	}()
+}
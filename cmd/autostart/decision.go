@@ -0,0 +1,295 @@
+package autostart
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/denysvitali/ekz-tesla/ekz"
+	"github.com/denysvitali/ekz-tesla/state"
+	"github.com/denysvitali/ekz-tesla/vehicle"
+)
+
+// DecisionAction is what autostart does after evaluating its conditions
+type DecisionAction string
+
+const (
+	// DecisionStart starts a session
+	DecisionStart DecisionAction = "start"
+	// DecisionStop stops the running emergency session
+	DecisionStop DecisionAction = "stop"
+	// DecisionContinue keeps the running session
+	DecisionContinue DecisionAction = "continue"
+	// DecisionNone doesn't charge now
+	DecisionNone DecisionAction = "none"
+)
+
+// Check is the outcome of a single autostart condition
+type Check struct {
+	Name    string `json:"name"`
+	Passed  bool   `json:"passed"`
+	Value   string `json:"value"`
+	Want    string `json:"want,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// Decision explains what autostart does and why
+type Decision struct {
+	Time       time.Time      `json:"time"`
+	CarID      int            `json:"car_id"`
+	CarName    string         `json:"car_name,omitempty"`
+	HighTariff bool           `json:"high_tariff"`
+	Action     DecisionAction `json:"action"`
+	Reason     string         `json:"reason"`
+	// Target is the charge level the session charges to
+	Target    int     `json:"target,omitempty"`
+	Emergency bool    `json:"emergency,omitempty"`
+	Checks    []Check `json:"checks"`
+
+	// car is the car the decision is about
+	car *Car
+	// preflight is the station state, nil if it wasn't checked
+	preflight *ekz.PreflightResult
+	// station is the start attempt state of the station in the current tariff window
+	station      *state.StationState
+	batteryLevel int
+	// planned is set when the turns of the cars were planned on the decision
+	planned bool
+}
+
+func (d *Decision) add(check Check) {
+	d.Checks = append(d.Checks, check)
+}
+
+// failed returns the first failed check
+func (d *Decision) failed() (Check, bool) {
+	for _, check := range d.Checks {
+		if !check.Passed {
+			return check, true
+		}
+	}
+	return Check{}, false
+}
+
+// evaluate checks the autostart conditions against the current car status. The station state is
+// only queried when all other conditions pass, unless full is set. It has no side effects.
+func (as *AutostartService) evaluate(highTariff bool, full bool) (*Decision, error) {
+	now := time.Now()
+	car, status, carCheck, err := as.selectCar(as.context(), now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get car status: %w", err)
+	}
+
+	d := &Decision{Time: now, CarID: car.ID, CarName: car.Name, HighTariff: highTariff, car: car}
+	station := as.stationState(as.tariffWindow(now))
+	// The hysteresis of the previous car doesn't apply to another car
+	station.SwitchCar(car.ID)
+	d.station = &station
+	if carCheck != nil {
+		d.add(*carCheck)
+	}
+
+	// Data freshness
+	age := status.DataAge(now)
+	fresh := Check{Name: "data_fresh", Passed: true, Value: age.Truncate(time.Second).String()}
+	if as.maxDataAge > 0 {
+		fresh.Want = fmt.Sprintf("<= %s", as.maxDataAge)
+		if age > as.maxDataAge {
+			fresh.Passed = false
+			fresh.Message = fmt.Sprintf("car data is %s old (car %s since %s)",
+				age.Truncate(time.Second), status.State, status.StateSince.Format(time.RFC3339))
+		}
+	}
+	d.add(fresh)
+
+	// Target
+	batteryLevel := status.SoC
+	d.batteryLevel = batteryLevel
+	target := as.carTarget(car, *status, now)
+
+	// Charging state
+	chargingState := status.CurrentChargingState()
+	charging := status.Charging()
+	stateCheck := Check{Name: "charging_state", Passed: true, Value: string(chargingState), Want: "not charging, complete or disconnected"}
+	switch {
+	case charging:
+		stateCheck.Passed = false
+		stateCheck.Message = "car is already charging"
+	case chargingState == vehicle.ChargingStateComplete:
+		stateCheck.Passed = false
+		stateCheck.Message = "car reports charging complete"
+	case chargingState == vehicle.ChargingStateDisconnected:
+		stateCheck.Passed = false
+		stateCheck.Message = "car is not plugged in"
+	}
+	d.add(stateCheck)
+
+	// Plugged in
+	pluggedIn := status.PluggedIn
+	plugged := Check{Name: "plugged_in", Passed: pluggedIn, Value: fmt.Sprintf("%v", pluggedIn), Want: "true"}
+	if !pluggedIn {
+		plugged.Message = "car is not plugged in"
+	}
+	d.add(plugged)
+
+	if as.rules != nil && as.rules.Start != nil {
+		// The start rule replaces the tariff and battery conditions
+		d.Target = target
+		d.add(as.evalStartRule(*status, target, now))
+	} else {
+		as.addPolicyChecks(d, highTariff, batteryLevel, target)
+	}
+
+	// Distance from charging station
+	location := as.locate(*status)
+	distance := Check{Name: "distance", Passed: location.Inside, Value: location.Reason, Want: as.geofence.Want()}
+	if !distance.Passed {
+		distance.Message = "car is not near the charging station"
+	}
+	d.add(distance)
+
+	// Turns of the cars sharing the connector, in the low tariff window
+	if as.fairnessActive() && !highTariff {
+		if turn, ok := as.turnCheck(d, car, pluggedIn, now); ok {
+			d.add(turn)
+		}
+	}
+
+	// Backoff after failed start attempts
+	d.add(as.backoffCheck(d.station, now))
+
+	// Station state
+	if _, failed := d.failed(); full || !failed {
+		station, err := as.checkStation(d)
+		if err != nil {
+			return nil, err
+		}
+		d.add(station)
+	}
+
+	as.decide(d, charging, batteryLevel)
+	return d, nil
+}
+
+// addPolicyChecks adds the built-in tariff and battery conditions
+func (as *AutostartService) addPolicyChecks(d *Decision, highTariff bool, batteryLevel int, target int) {
+	// Tariff: in high tariff, only an emergency charge is allowed
+	tariff := Check{Name: "tariff", Passed: true, Value: "low", Want: "low"}
+	if highTariff {
+		tariff.Value = "high"
+		if as.emergencySoC > 0 {
+			tariff.Want = fmt.Sprintf("low, or battery below the emergency floor of %d%%", as.emergencySoC)
+		}
+		if as.emergencySoC > 0 && batteryLevel < as.emergencySoC {
+			d.Emergency = true
+			target = as.emergencyTarget
+		} else {
+			tariff.Passed = false
+			tariff.Message = fmt.Sprintf("high tariff period and battery at %d%% (emergency floor: %d%%)", batteryLevel, as.emergencySoC)
+		}
+	}
+	d.add(tariff)
+	d.Target = target
+
+	// Battery level, with hysteresis once the target was reached
+	threshold := target
+	if !d.Emergency && d.station != nil {
+		threshold = d.station.StartThreshold(target, as.backoff)
+	}
+	battery := Check{
+		Name:   "battery",
+		Passed: batteryLevel < threshold,
+		Value:  fmt.Sprintf("%d%%", batteryLevel),
+		Want:   fmt.Sprintf("< %d%%", threshold),
+	}
+	switch {
+	case battery.Passed:
+	case threshold < target:
+		battery.Message = fmt.Sprintf("car battery at %d%%, target of %d%% reached, waiting for it to drop below %d%%",
+			batteryLevel, target, threshold)
+	default:
+		battery.Message = fmt.Sprintf("car battery at %d%% (target: %d%%)", batteryLevel, target)
+	}
+	d.add(battery)
+}
+
+// evalStartRule evaluates the user-defined start rule
+func (as *AutostartService) evalStartRule(status vehicle.Status, target int, now time.Time) Check {
+	check := Check{Name: "start_rule", Want: as.rules.Start.String()}
+	passed, err := as.rules.Start.Eval(as.ruleEnv(status, nil, target, now))
+	if err != nil {
+		check.Value = "error"
+		check.Message = err.Error()
+		return check
+	}
+	check.Passed = passed
+	check.Value = fmt.Sprintf("%v", passed)
+	if !passed {
+		check.Message = "start rule is false"
+	}
+	return check
+}
+
+// checkStation runs the pre-flight checks of the charging station
+func (as *AutostartService) checkStation(d *Decision) (Check, error) {
+	preflight, err := as.ekzClient.Preflight(as.chargingStation.BoxId, as.chargingStation.ConnectorId)
+	if err != nil {
+		return Check{}, fmt.Errorf("failed to check the charging station: %w", err)
+	}
+	d.preflight = preflight
+
+	check := Check{Name: "station", Passed: preflight.OK(), Value: "available", Want: "available"}
+	switch {
+	case preflight.AlreadyCharging():
+		check.Value = fmt.Sprintf("own session %d running", preflight.Session.TransactionID)
+	case len(preflight.Issues) > 0:
+		var codes, messages []string
+		for _, issue := range preflight.Issues {
+			codes = append(codes, string(issue.Code))
+			messages = append(messages, issue.Message)
+		}
+		check.Value = strings.Join(codes, ", ")
+		if !check.Passed {
+			check.Message = strings.Join(messages, "; ")
+		}
+	}
+	return check, nil
+}
+
+// decide sets the action and reason of the decision from its checks
+func (as *AutostartService) decide(d *Decision, charging bool, batteryLevel int) {
+	if check, failed := d.failed(); failed && check.Name == "data_fresh" {
+		d.Action, d.Reason = DecisionNone, check.Message
+		return
+	}
+
+	if charging {
+		as.mu.Lock()
+		emergencyActive := as.emergencyActive
+		as.mu.Unlock()
+
+		d.Action, d.Reason = DecisionContinue, "car is already charging"
+		switch {
+		case emergencyActive && !d.HighTariff:
+			d.Reason = fmt.Sprintf("low tariff period reached, emergency charge continues up to %d%%", d.Target)
+		case emergencyActive && batteryLevel >= as.emergencyTarget:
+			d.Action = DecisionStop
+			d.Reason = fmt.Sprintf("emergency charge reached %d%% (safe level: %d%%), stopping until the low tariff period",
+				batteryLevel, as.emergencyTarget)
+		}
+		return
+	}
+
+	if check, failed := d.failed(); failed {
+		d.Action, d.Reason = DecisionNone, check.Message
+		return
+	}
+
+	d.Action = DecisionStart
+	if d.Emergency {
+		d.Reason = fmt.Sprintf("battery below the emergency floor of %d%%, charging to %d%% regardless of tariff",
+			as.emergencySoC, as.emergencyTarget)
+	} else {
+		d.Reason = fmt.Sprintf("all conditions met, charging to %d%%", d.Target)
+	}
+}
@@ -0,0 +1,200 @@
+package autostart
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/h2non/gock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/ekz-tesla/rules"
+	"github.com/denysvitali/ekz-tesla/state"
+	"github.com/denysvitali/ekz-tesla/vehicle"
+)
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     func(*vehicle.Status)
+		setup      func(t *testing.T, as *AutostartService)
+		highTariff bool
+		// station mocks the backend, nil when the station must not be queried
+		station func()
+		// failed is the first failing check, empty if all pass
+		failed    string
+		action    DecisionAction
+		emergency bool
+	}{
+		{
+			name:    "all conditions met",
+			station: func() { mockStation(true, "Available") },
+			action:  DecisionStart,
+		},
+		{
+			name:   "stale data",
+			status: func(s *vehicle.Status) { s.Updated = time.Now().Add(-time.Hour) },
+			setup:  func(_ *testing.T, as *AutostartService) { as.maxDataAge = 10 * time.Minute },
+			failed: "data_fresh",
+			action: DecisionNone,
+		},
+		{
+			name:   "already charging",
+			status: func(s *vehicle.Status) { s.ChargingState = vehicle.ChargingStateCharging },
+			failed: "charging_state",
+			action: DecisionContinue,
+		},
+		{
+			name:   "charging complete",
+			status: func(s *vehicle.Status) { s.ChargingState = vehicle.ChargingStateComplete },
+			failed: "charging_state",
+			action: DecisionNone,
+		},
+		{
+			name:   "not plugged in",
+			status: func(s *vehicle.Status) { s.PluggedIn = false },
+			failed: "plugged_in",
+			action: DecisionNone,
+		},
+		{
+			name:       "high tariff",
+			highTariff: true,
+			failed:     "tariff",
+			action:     DecisionNone,
+		},
+		{
+			name:   "high tariff below the emergency floor",
+			status: func(s *vehicle.Status) { s.SoC = 10 },
+			setup: func(t *testing.T, as *AutostartService) {
+				require.NoError(t, as.SetEmergencyCharge(20, 40))
+			},
+			highTariff: true,
+			station:    func() { mockStation(true, "Available") },
+			action:     DecisionStart,
+			emergency:  true,
+		},
+		{
+			name:   "target reached",
+			status: func(s *vehicle.Status) { s.SoC = 80 },
+			failed: "battery",
+			action: DecisionNone,
+		},
+		{
+			name: "within the hysteresis",
+			status: func(s *vehicle.Status) {
+				s.SoC = 79
+			},
+			setup: func(_ *testing.T, as *AutostartService) {
+				as.updateStation(as.tariffWindow(time.Now()), func(s *state.StationState) {
+					s.SwitchCar(1)
+					s.TargetReached = true
+				})
+			},
+			failed: "battery",
+			action: DecisionNone,
+		},
+		{
+			name: "start rule false",
+			setup: func(t *testing.T, as *AutostartService) {
+				set, err := rules.CompileSet("car.soc < 20", "")
+				require.NoError(t, err)
+				as.SetRules(set)
+			},
+			failed: "start_rule",
+			action: DecisionNone,
+		},
+		{
+			name: "away from the station",
+			status: func(s *vehicle.Status) {
+				s.Location = &vehicle.Location{Latitude: testLatitude + 0.01, Longitude: testLongitude}
+			},
+			failed: "distance",
+			action: DecisionNone,
+		},
+		{
+			name: "backing off",
+			setup: func(_ *testing.T, as *AutostartService) {
+				as.recordStart(time.Now(), errors.New("car not drawing power"))
+			},
+			failed: "backoff",
+			action: DecisionNone,
+		},
+		{
+			name:    "station offline",
+			station: func() { mockStation(false, "Available") },
+			failed:  "station",
+			action:  DecisionNone,
+		},
+		{
+			name: "connector used by another session",
+			station: func() {
+				mockStation(true, "Charging")
+				mockNoLiveData()
+			},
+			failed: "station",
+			action: DecisionNone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := atStation()
+			if tt.status != nil {
+				tt.status(&status)
+			}
+			car, _ := newTestCar(1, status)
+			as := newTestService(t, car)
+			if tt.setup != nil {
+				tt.setup(t, as)
+			}
+			if tt.station != nil {
+				tt.station()
+			}
+
+			d, err := as.evaluate(tt.highTariff, false)
+			require.NoError(t, err)
+			check, failed := d.failed()
+			if tt.failed == "" {
+				assert.False(t, failed, "check %s failed: %s", check.Name, check.Message)
+			} else {
+				require.True(t, failed)
+				assert.Equal(t, tt.failed, check.Name)
+				if tt.action == DecisionNone {
+					assert.Equal(t, check.Message, d.Reason)
+				}
+			}
+			assert.Equal(t, tt.action, d.Action)
+			assert.Equal(t, tt.emergency, d.Emergency)
+			assert.False(t, gock.HasUnmatchedRequest(), "unexpected request to the backend")
+			assert.True(t, gock.IsDone(), "the station was not queried")
+		})
+	}
+}
+
+func TestDecide_EmergencyCharge(t *testing.T) {
+	tests := []struct {
+		name       string
+		highTariff bool
+		soc        int
+		action     DecisionAction
+		reason     string
+	}{
+		{"below the safe level", true, 30, DecisionContinue, "car is already charging"},
+		{"safe level reached", true, 40, DecisionStop, "emergency charge reached 40% (safe level: 40%), stopping until the low tariff period"},
+		{"low tariff reached", false, 40, DecisionContinue, "low tariff period reached, emergency charge continues up to 80%"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			car, _ := newTestCar(1, atStation())
+			as := newTestService(t, car)
+			require.NoError(t, as.SetEmergencyCharge(20, 40))
+			as.emergencyActive = true
+
+			d := &Decision{HighTariff: tt.highTariff, Target: 80}
+			as.decide(d, true, tt.soc)
+			assert.Equal(t, tt.action, d.Action)
+			assert.Equal(t, tt.reason, d.Reason)
+		})
+	}
+}
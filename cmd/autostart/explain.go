@@ -0,0 +1,85 @@
+package autostart
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/table"
+	"github.com/spf13/cobra"
+)
+
+func runAutostartExplain(cmd *cobra.Command, args []string) error {
+	service, err := createAutostartService()
+	if err != nil {
+		return err
+	}
+
+	scheduler, err := newTariffScheduler(nil, false)
+	if err != nil {
+		return err
+	}
+
+	decision, err := service.Explain(scheduler.IsHighTariffTime(time.Now()))
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		return printDecisionJSON(decision)
+	}
+	printDecision(decision)
+	return nil
+}
+
+func printDecisionJSON(decision *Decision) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(decision)
+}
+
+// printDecision prints the evaluated checks using lipgloss's table, followed by the decision
+func printDecision(decision *Decision) {
+	var rows [][]string
+	for _, check := range decision.Checks {
+		passed := "❌"
+		if check.Passed {
+			passed = "✅"
+		}
+		want := check.Want
+		if want == "" {
+			want = "-"
+		}
+		rows = append(rows, []string{check.Name, passed, check.Value, want})
+	}
+
+	t := table.New().
+		Border(lipgloss.NormalBorder()).
+		BorderStyle(lipgloss.NewStyle().Foreground(lipgloss.Color("99"))).
+		Headers("CHECK", "PASSED", "VALUE", "WANT").
+		StyleFunc(func(row, col int) lipgloss.Style {
+			if row == table.HeaderRow {
+				return lipgloss.NewStyle().Bold(true).PaddingLeft(1).PaddingRight(1)
+			}
+			baseStyle := lipgloss.NewStyle().PaddingLeft(1).PaddingRight(1)
+			if col == 1 {
+				return baseStyle.AlignHorizontal(lipgloss.Center)
+			}
+			return baseStyle
+		}).
+		Rows(rows...)
+
+	tariff := "low"
+	if decision.HighTariff {
+		tariff = "high"
+	}
+	car := fmt.Sprintf("Car %d", decision.CarID)
+	if decision.CarName != "" {
+		car = fmt.Sprintf("%s (%s)", car, decision.CarName)
+	}
+	fmt.Printf("%s at %s (%s tariff)\n", car, decision.Time.Format("2006-01-02 15:04:05 Mon"), tariff)
+	fmt.Println(t)
+	fmt.Printf("Decision: %s (%s)\n", decision.Action, decision.Reason)
+}
@@ -0,0 +1,182 @@
+package autostart
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/denysvitali/ekz-tesla/cmd/root"
+	"github.com/denysvitali/ekz-tesla/ekz"
+	"github.com/denysvitali/ekz-tesla/state"
+	"github.com/denysvitali/ekz-tesla/vehicle"
+)
+
+// SetFairness makes the cars sharing the connector take turns in the low tariff window,
+// instead of the car plugged in first charging up to its target
+func (as *AutostartService) SetFairness(config ekz.FairnessConfig) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	as.fairness = config
+	return nil
+}
+
+// fairnessActive tells whether the cars take turns
+func (as *AutostartService) fairnessActive() bool {
+	return as.fairness.Enabled() && len(as.cars) > 1 && as.tariffScheduler != nil
+}
+
+// carTarget returns the target of the car, its charge limit with --use-car-limit
+func (as *AutostartService) carTarget(car *Car, status vehicle.Status, now time.Time) int {
+	target := as.targetAt(car, now)
+	if as.useCarLimit && status.ChargeLimit > 0 {
+		target = status.ChargeLimit
+	}
+	return target
+}
+
+// needs returns the energy the cars near the station need to reach their target.
+// Cars at or above their target are left out.
+func (as *AutostartService) needs(now time.Time) []state.CarNeed {
+	var needs []state.CarNeed
+	for _, cs := range as.statuses(as.context()) {
+		if cs.err != nil {
+			root.GetLogger().Warnf("Failed to get the status of %s: %v", cs.car, cs.err)
+			continue
+		}
+		target := as.carTarget(cs.car, *cs.status, now)
+		if !as.nearStation(*cs.status) || cs.status.SoC >= target {
+			continue
+		}
+		needs = append(needs, state.CarNeed{
+			Car:      cs.car.ID,
+			Name:     cs.car.Name,
+			Need:     float64(target-cs.status.SoC) / 100 * cs.car.Capacity,
+			Priority: cs.car.Priority,
+		})
+	}
+	return needs
+}
+
+// needsCharge tells whether the car is near the station and below its target
+func needsCharge(needs []state.CarNeed, car int) bool {
+	for _, need := range needs {
+		if need.Car == car {
+			return true
+		}
+	}
+	return false
+}
+
+// turnCheck checks whether it is the turn of the car, planning the turns when a car needing
+// charge wasn't considered yet. The turns are only planned on the decision, apply persists them.
+// It returns false when a single car needs charge and no turns were planned.
+func (as *AutostartService) turnCheck(d *Decision, car *Car, pluggedIn bool, now time.Time) (Check, bool) {
+	needs := as.needs(now)
+	if len(needs) < 2 && d.station.Turns == nil {
+		return Check{}, false
+	}
+
+	ids := make([]int, len(needs))
+	for i, need := range needs {
+		ids[i] = need.Car
+	}
+	if !d.station.Turns.Covers(ids) {
+		first := 0
+		if pluggedIn {
+			first = car.ID
+		}
+		d.station.Turns = state.PlanTurns(as.fairness, needs, first, now, as.tariffScheduler.PeriodEnd(now))
+		d.planned = true
+	}
+
+	check := Check{Name: "turn", Passed: true, Value: "none", Want: "turn of " + car.String()}
+	turn, ok := d.station.Turns.At(now)
+	switch {
+	case !ok:
+	case turn.Car == car.ID:
+		check.Value = turn.String()
+	case !needsCharge(needs, turn.Car):
+		// Turns are a limit, not a reservation: a car that doesn't need charge leaves its turn to the others
+		check.Value = turn.String() + ", which needs no charge"
+	default:
+		check.Passed = false
+		check.Value = turn.String()
+		check.Message = fmt.Sprintf("it is the turn of %s until %s", turnCar(*turn), turn.End.Local().Format("15:04"))
+	}
+	return check, true
+}
+
+// updateTurns persists the turns planned by the decision, and reminds the owner of the car
+// whose turn it is to plug it in when another car is plugged in
+func (as *AutostartService) updateTurns(d *Decision) {
+	check, failed := d.failed()
+	remind := failed && check.Name == "turn"
+	if d.station == nil || !d.planned && !remind {
+		return
+	}
+
+	var turn state.Turn
+	var reminded bool
+	as.updateStation(d.station.Window, func(s *state.StationState) {
+		if d.planned {
+			s.Turns = d.station.Turns
+		}
+		if remind {
+			turn, reminded = s.Turns.Remind(d.Time)
+		}
+	})
+	if d.planned {
+		var turns []string
+		for _, turn := range d.station.Turns.Turns {
+			turns = append(turns, turn.String())
+		}
+		root.GetLogger().Infof("Cars take turns on the station: %s", strings.Join(turns, ", "))
+	}
+	if reminded {
+		as.remindSwap(d.car, turn)
+	}
+}
+
+// turnOver tells whether the session of the car must stop as the turn of another car needing
+// charge started
+func (as *AutostartService) turnOver(car *Car, now time.Time) (string, bool) {
+	as.mu.Lock()
+	emergency := as.emergencyActive
+	as.mu.Unlock()
+	if !as.fairnessActive() || emergency || as.tariffScheduler.IsHighTariffTime(now) {
+		return "", false
+	}
+
+	window := as.tariffWindow(now)
+	station := as.stationState(window)
+	turn, ok := station.Turns.At(now)
+	if !ok || turn.Car == car.ID || !needsCharge(as.needs(now), turn.Car) {
+		return "", false
+	}
+
+	var reminded bool
+	as.updateStation(window, func(s *state.StationState) {
+		_, reminded = s.Turns.Remind(now)
+	})
+	if reminded {
+		as.remindSwap(car, *turn)
+	}
+	return fmt.Sprintf("turn of %s ended, it is the turn of %s", car, turnCar(*turn)), true
+}
+
+// remindSwap asks the owner of the car whose turn it is to plug it in instead of the car plugged in
+func (as *AutostartService) remindSwap(pluggedIn *Car, turn state.Turn) {
+	message := fmt.Sprintf("it is the turn of %s until %s", turnCar(turn), turn.End.Local().Format("15:04"))
+	if pluggedIn != nil && pluggedIn.ID != turn.Car {
+		message += ", plug it in instead of " + pluggedIn.String()
+	}
+	root.GetLogger().Warnf("🔀 Swap the charging cable: %s", message)
+	as.sendAlert(turn.Car, "Swap the charging cable", message)
+}
+
+// turnCar returns the car of the turn as the service names it
+func turnCar(turn state.Turn) string {
+	car := &Car{ID: turn.Car, Name: turn.Name}
+	return car.String()
+}
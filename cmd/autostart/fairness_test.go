@@ -0,0 +1,185 @@
+package autostart
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/ekz-tesla/ekz"
+	"github.com/denysvitali/ekz-tesla/vehicle"
+)
+
+// Low tariff window of the fairness tests: from 20:00 to 07:00
+var (
+	turnsPlanned = time.Date(2025, 6, 2, 22, 0, 0, 0, time.Local)
+	// Both cars need as much, car 1 charges until 02:30 and car 2 until 07:00
+	firstTurn  = time.Date(2025, 6, 2, 23, 0, 0, 0, time.Local)
+	secondTurn = time.Date(2025, 6, 3, 3, 0, 0, 0, time.Local)
+	highTariff = time.Date(2025, 6, 3, 8, 0, 0, 0, time.Local)
+)
+
+// newFairService creates a two-car service whose cars take turns by need
+func newFairService(t *testing.T, status1, status2 func(*vehicle.Status)) (*AutostartService, [2]*Car, [2]*fakeVehicle) {
+	t.Helper()
+	as, cars, fakes := newTwoCarService(t, status1, status2)
+	require.NoError(t, as.SetFairness(ekz.FairnessConfig{Policy: ekz.FairnessNeed}))
+	scheduler, err := ekz.NewTariffScheduler(nil, []string{"07:00-20:00"}, nil)
+	require.NoError(t, err)
+	as.SetTariffs(scheduler, nil)
+	require.True(t, as.fairnessActive())
+	return as, cars, fakes
+}
+
+// decisionAt returns a decision with the station state of the tariff window at now
+func decisionAt(as *AutostartService, car *Car, now time.Time) *Decision {
+	station := as.stationState(as.tariffWindow(now))
+	return &Decision{Time: now, car: car, station: &station}
+}
+
+// planTurns plans and persists the turns, with car 1 plugged in
+func planTurns(t *testing.T, as *AutostartService, cars [2]*Car) {
+	t.Helper()
+	d := decisionAt(as, cars[0], turnsPlanned)
+	check, ok := as.turnCheck(d, cars[0], true, turnsPlanned)
+	require.True(t, ok)
+	require.True(t, check.Passed)
+	d.add(check)
+	as.updateTurns(d)
+}
+
+func TestTurnCheck(t *testing.T) {
+	as, cars, _ := newFairService(t, nil, nil)
+
+	d := decisionAt(as, cars[0], turnsPlanned)
+	check, ok := as.turnCheck(d, cars[0], true, turnsPlanned)
+	require.True(t, ok)
+	assert.True(t, d.planned)
+	assert.True(t, check.Passed)
+	require.Len(t, d.station.Turns.Turns, 2)
+	first, second := d.station.Turns.Turns[0], d.station.Turns.Turns[1]
+	assert.Equal(t, 1, first.Car, "the car plugged in charges first")
+	assert.Equal(t, turnsPlanned, first.Start)
+	assert.Equal(t, 2, second.Car)
+	assert.Equal(t, first.End, second.Start)
+	assert.Equal(t, time.Date(2025, 6, 3, 7, 0, 0, 0, time.Local), second.End)
+
+	// The plan covers both cars, it is kept for the other car
+	check, ok = as.turnCheck(d, cars[1], true, firstTurn)
+	require.True(t, ok)
+	assert.False(t, check.Passed)
+	assert.Equal(t, "it is the turn of car 1 (Model 3) until 02:30", check.Message)
+
+	check, ok = as.turnCheck(d, cars[1], true, secondTurn)
+	require.True(t, ok)
+	assert.True(t, check.Passed)
+}
+
+func TestTurnCheck_SingleCar(t *testing.T) {
+	as, cars, _ := newFairService(t, nil, func(s *vehicle.Status) { s.SoC = 80 })
+
+	d := decisionAt(as, cars[0], turnsPlanned)
+	_, ok := as.turnCheck(d, cars[0], true, turnsPlanned)
+	assert.False(t, ok, "no turns with a single car needing charge")
+	assert.False(t, d.planned)
+}
+
+func TestTurnCheck_TurnHolderNeedsNoCharge(t *testing.T) {
+	as, cars, fakes := newFairService(t, nil, nil)
+	planTurns(t, as, cars)
+	fakes[0].set(func(s *vehicle.Status) { s.SoC = 80 })
+
+	d := decisionAt(as, cars[1], firstTurn)
+	check, ok := as.turnCheck(d, cars[1], true, firstTurn)
+	require.True(t, ok)
+	assert.False(t, d.planned, "the turns are kept")
+	assert.True(t, check.Passed, "car 1 leaves its turn to car 2")
+	assert.Contains(t, check.Value, "which needs no charge")
+}
+
+func TestUpdateTurns(t *testing.T) {
+	as, cars, _ := newFairService(t, nil, nil)
+	planTurns(t, as, cars)
+
+	station := as.stationState(as.tariffWindow(firstTurn))
+	require.NotNil(t, station.Turns)
+	require.Len(t, station.Turns.Turns, 2)
+	assert.False(t, station.Turns.Turns[0].Reminded)
+
+	// Car 2 is plugged in during the turn of car 1: the owner of car 1 is reminded, once
+	for range 2 {
+		d := decisionAt(as, cars[1], firstTurn)
+		check, ok := as.turnCheck(d, cars[1], true, firstTurn)
+		require.True(t, ok)
+		d.add(check)
+		as.updateTurns(d)
+	}
+	station = as.stationState(as.tariffWindow(firstTurn))
+	assert.True(t, station.Turns.Turns[0].Reminded)
+	assert.False(t, station.Turns.Turns[1].Reminded)
+}
+
+func TestTurnOver(t *testing.T) {
+	tests := []struct {
+		name   string
+		now    time.Time
+		setup  func(as *AutostartService, fakes [2]*fakeVehicle)
+		over   bool
+		reason string
+	}{
+		{
+			name: "own turn",
+			now:  firstTurn,
+		},
+		{
+			name:   "turn of the other car",
+			now:    secondTurn,
+			over:   true,
+			reason: "turn of car 1 (Model 3) ended, it is the turn of car 2 (Model Y)",
+		},
+		{
+			name: "turn holder needs no charge",
+			now:  secondTurn,
+			setup: func(_ *AutostartService, fakes [2]*fakeVehicle) {
+				fakes[1].set(func(s *vehicle.Status) { s.SoC = 80 })
+			},
+		},
+		{
+			name: "turn holder away",
+			now:  secondTurn,
+			setup: func(_ *AutostartService, fakes [2]*fakeVehicle) {
+				fakes[1].set(func(s *vehicle.Status) {
+					s.Location = &vehicle.Location{Latitude: testLatitude + 0.01, Longitude: testLongitude}
+				})
+			},
+		},
+		{
+			name:  "emergency charge",
+			now:   secondTurn,
+			setup: func(as *AutostartService, _ [2]*fakeVehicle) { as.emergencyActive = true },
+		},
+		{
+			name: "high tariff",
+			now:  highTariff,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			as, cars, fakes := newFairService(t, nil, nil)
+			planTurns(t, as, cars)
+			if tt.setup != nil {
+				tt.setup(as, fakes)
+			}
+
+			reason, over := as.turnOver(cars[0], tt.now)
+			assert.Equal(t, tt.over, over)
+			assert.Equal(t, tt.reason, reason)
+
+			// The owner of the car whose turn it is was reminded to plug it in
+			station := as.stationState(as.tariffWindow(turnsPlanned))
+			assert.Equal(t, tt.over, station.Turns.Turns[1].Reminded)
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package autostart
+
+import (
+	"errors"
+	"time"
+
+	"github.com/denysvitali/ekz-tesla/cmd/root"
+	"github.com/denysvitali/ekz-tesla/ekz"
+	"github.com/denysvitali/ekz-tesla/history"
+	"github.com/denysvitali/ekz-tesla/state"
+)
+
+// ownSession persists the session autostart started, so that a restarted daemon resumes its supervision
+func (as *AutostartService) ownSession(transactionID int, target int, emergency bool, carID int) {
+	if as.state == nil {
+		return
+	}
+	err := as.state.Update(func(st *state.State) error {
+		st.SetSession(state.Session{
+			ChargeBoxID:   as.chargingStation.BoxId,
+			ConnectorID:   as.chargingStation.ConnectorId,
+			TransactionID: transactionID,
+			Owner:         state.OwnerAutostart,
+			Target:        target,
+			Emergency:     emergency,
+			CarID:         carID,
+			Requested:     time.Now(),
+		})
+		return nil
+	})
+	if err != nil {
+		root.GetLogger().Warnf("Failed to update daemon state: %v", err)
+	}
+}
+
+// updateOwnedSession persists a new target or car of the running autostart session
+func (as *AutostartService) updateOwnedSession(target int, emergency bool, carID int) {
+	if as.state == nil {
+		return
+	}
+	err := as.state.Update(func(st *state.State) error {
+		if session, ok := st.Session(as.chargingStation.BoxId, as.chargingStation.ConnectorId); ok && session.Owner == state.OwnerAutostart {
+			session.Target = target
+			session.Emergency = emergency
+			session.CarID = carID
+		}
+		return nil
+	})
+	if err != nil {
+		root.GetLogger().Warnf("Failed to update daemon state: %v", err)
+	}
+}
+
+// requestStop records that the session on the connector is stopped on purpose, so that its
+// supervisor doesn't take its end for an interruption
+func (as *AutostartService) requestStop(chargeBoxID string, connectorID int) {
+	if as.state == nil {
+		return
+	}
+	if err := as.state.RequestStop(chargeBoxID, connectorID); err != nil {
+		root.GetLogger().Warnf("Failed to update daemon state: %v", err)
+	}
+}
+
+// stopRequested tells whether a stop of the autostart session was requested, by autostart
+// itself, `stop` or a queued stop
+func (as *AutostartService) stopRequested() bool {
+	if as.state == nil {
+		return false
+	}
+	st, err := as.state.Load()
+	if err != nil {
+		root.GetLogger().Warnf("Failed to load daemon state: %v", err)
+		return false
+	}
+	session, ok := st.Session(as.chargingStation.BoxId, as.chargingStation.ConnectorId)
+	return ok && !session.StopRequested.IsZero()
+}
+
+// releaseSession forgets the autostart session once its supervision ended
+func (as *AutostartService) releaseSession() {
+	as.mu.Lock()
+	as.sessionCar = nil
+	as.baseline = nil
+	as.mu.Unlock()
+	if as.state == nil {
+		return
+	}
+	err := as.state.Update(func(st *state.State) error {
+		if session, ok := st.Session(as.chargingStation.BoxId, as.chargingStation.ConnectorId); ok && session.Owner == state.OwnerAutostart {
+			st.RemoveSession(as.chargingStation.BoxId, as.chargingStation.ConnectorId)
+		}
+		return nil
+	})
+	if err != nil {
+		root.GetLogger().Warnf("Failed to update daemon state: %v", err)
+	}
+}
+
+// recoverSessions reconciles the persisted sessions with the live data after a restart:
+// sessions that ended while the daemon was down are recorded and forgotten, the supervision
+// of running autostart sessions resumes with their target. Handed over sessions are
+// picked up by the daemon ticks.
+func (as *AutostartService) recoverSessions() {
+	log := root.GetLogger()
+	st, err := as.state.Load()
+	if err != nil {
+		log.Warnf("Failed to load daemon state: %v", err)
+		return
+	}
+
+	for _, session := range st.Sessions {
+		liveData, err := as.ekzClient.GetLiveData(session.ChargeBoxID, session.ConnectorID, ekz.ConnectorStatusCharging)
+		switch {
+		case errors.Is(err, ekz.ErrTransactionNotFoundInTable),
+			err == nil && (liveData.TransactionID != session.TransactionID || liveData.IsFinished()):
+			log.Infof("%s ended while the daemon was down", session)
+			if err == nil && liveData.TransactionID == session.TransactionID {
+				as.recordSession(session.ChargeBoxID, session.ConnectorID, &ekz.SessionResult{
+					Reason:   "session ended while the daemon was down",
+					LiveData: liveData,
+				})
+			} else {
+				as.recordLostSession(session)
+			}
+			as.forgetSession(session)
+		case err != nil:
+			// Keep the session, its supervisor finds out whether it still runs
+			log.Warnf("Failed to check %s, resuming supervision: %v", session, err)
+			as.resumeSession(session)
+		default:
+			log.Infof("Resuming supervision of %s: %.2f kWh charged so far", session, liveData.ChargedEnergy)
+			as.resumeSession(session)
+		}
+	}
+}
+
+// recordLostSession records a session that ended while the daemon was down and whose live
+// data is gone. Only what the state knows about it is recorded: its energy and cost are unknown,
+// and it started at the latest when it was requested.
+func (as *AutostartService) recordLostSession(session state.Session) {
+	if as.history == nil {
+		return
+	}
+	err := as.history.Append(history.Session{
+		ChargeBoxID:   session.ChargeBoxID,
+		ConnectorID:   session.ConnectorID,
+		TransactionID: session.TransactionID,
+		CarID:         session.CarID,
+		Start:         session.Requested,
+		Stop:          time.Now(),
+		StopReason:    "session ended while the daemon was down, its live data is no longer available",
+	})
+	if err != nil {
+		root.GetLogger().Warnf("Failed to record session: %v", err)
+	}
+}
+
+// resumeSession restores the supervision of an autostart session
+func (as *AutostartService) resumeSession(session state.Session) {
+	if session.Owner != state.OwnerAutostart {
+		return
+	}
+	if session.ChargeBoxID != as.chargingStation.BoxId || session.ConnectorID != as.chargingStation.ConnectorId {
+		root.GetLogger().Warnf("%s is not on the configured charging station, no longer supervising it", session)
+		as.forgetSession(session)
+		return
+	}
+
+	// Without a recorded car, the car charging is the one found at the station
+	car := as.carByID(session.CarID)
+	if car == nil && len(as.cars) == 1 {
+		car = as.cars[0]
+	}
+
+	as.mu.Lock()
+	as.sessionTarget = session.Target
+	as.emergencyActive = session.Emergency
+	as.sessionCar = car
+	as.mu.Unlock()
+	as.superviseSession()
+}
+
+// forgetSession removes the session from the state, unless it was replaced in the meantime
+func (as *AutostartService) forgetSession(session state.Session) {
+	err := as.state.Update(func(st *state.State) error {
+		if current, ok := st.Session(session.ChargeBoxID, session.ConnectorID); ok && current.TransactionID == session.TransactionID {
+			st.RemoveSession(session.ChargeBoxID, session.ConnectorID)
+		}
+		return nil
+	})
+	if err != nil {
+		root.GetLogger().Warnf("Failed to update daemon state: %v", err)
+	}
+}
@@ -0,0 +1,112 @@
+package autostart
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/h2non/gock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/ekz-tesla/ekz"
+	"github.com/denysvitali/ekz-tesla/state"
+)
+
+func TestRecoverSessions(t *testing.T) {
+	requested := time.Now().Add(-2 * time.Hour).Truncate(time.Second)
+	tests := []struct {
+		name     string
+		liveData func()
+		// kept is whether the session stays in the state
+		kept bool
+		// reason is the stop reason recorded in the history, empty if nothing is recorded
+		reason string
+		energy float64
+	}{
+		{
+			name:     "session ended",
+			liveData: func() { mockLiveData(endedSession(1)) },
+			reason:   "session ended while the daemon was down",
+			energy:   5,
+		},
+		{
+			name:     "live data gone",
+			liveData: mockNoLiveData,
+			reason:   "session ended while the daemon was down, its live data is no longer available",
+		},
+		{
+			name: "another session on the connector",
+			liveData: func() {
+				mockLiveData(map[string]any{"transaction_id": 2, "status": "ONGOING", "power": 11.0})
+			},
+			reason: "session ended while the daemon was down, its live data is no longer available",
+		},
+		{
+			name: "live data unavailable",
+			liveData: func() {
+				gock.New(ekz.Backend).Post("/charging-stations/charging-live-data").Reply(http.StatusInternalServerError)
+			},
+			kept: true,
+		},
+		{
+			name: "session running",
+			liveData: func() {
+				mockLiveData(map[string]any{"transaction_id": 1, "status": "ONGOING", "power": 11.0, "charged_energy": 3.0})
+			},
+			kept: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			car, _ := newTestCar(7, atStation())
+			other, _ := newTestCar(8, atStation())
+			as := newTestService(t, car, other)
+			require.NoError(t, as.state.Update(func(st *state.State) error {
+				st.SetSession(state.Session{
+					ChargeBoxID:   "1234",
+					ConnectorID:   1,
+					TransactionID: 1,
+					Owner:         state.OwnerAutostart,
+					Target:        70,
+					CarID:         car.ID,
+					Requested:     requested,
+				})
+				return nil
+			}))
+			tt.liveData()
+
+			as.recoverSessions()
+			assert.True(t, gock.IsDone())
+
+			st, err := as.state.Load()
+			require.NoError(t, err)
+			_, ok := st.Session("1234", 1)
+			assert.Equal(t, tt.kept, ok)
+			if tt.kept {
+				// The supervision resumes with the persisted target and car
+				assert.Equal(t, 70, as.sessionTarget)
+				assert.Equal(t, car, as.sessionCar)
+			}
+
+			sessions, err := as.history.List()
+			require.NoError(t, err)
+			if tt.reason == "" {
+				assert.Empty(t, sessions)
+				return
+			}
+			require.Len(t, sessions, 1)
+			session := sessions[0]
+			assert.Equal(t, tt.reason, session.StopReason)
+			assert.Equal(t, 1, session.TransactionID)
+			assert.Equal(t, "1234", session.ChargeBoxID)
+			assert.Equal(t, 1, session.ConnectorID)
+			assert.Equal(t, tt.energy, session.Energy)
+			if tt.energy == 0 {
+				assert.Equal(t, car.ID, session.CarID)
+				assert.True(t, requested.Equal(session.Start), "start %s", session.Start)
+			}
+		})
+	}
+}
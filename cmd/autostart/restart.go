@@ -0,0 +1,142 @@
+package autostart
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/denysvitali/ekz-tesla/cmd/root"
+	"github.com/denysvitali/ekz-tesla/ekz"
+	"github.com/denysvitali/ekz-tesla/notify"
+	"github.com/denysvitali/ekz-tesla/vehicle"
+)
+
+const (
+	defaultRestartAttempts = 3
+	defaultRestartDelay    = 2 * time.Minute
+)
+
+// SetRestart configures how often a supervised session that ended without a stop command
+// (e.g. after a power cut) is restarted, and how long to wait before each attempt.
+// 0 attempts disables restarts.
+func (as *AutostartService) SetRestart(attempts int, delay time.Duration) error {
+	if attempts < 0 || delay < 0 {
+		return fmt.Errorf("restart attempts and delay can't be negative")
+	}
+	as.restartAttempts = attempts
+	as.restartDelay = delay
+	return nil
+}
+
+// SetAlerts sends alerts to the webhook in addition to the log, an empty URL disables the webhook
+func (as *AutostartService) SetAlerts(webhookURL string) {
+	as.alerts = nil
+	if webhookURL != "" {
+		as.alerts = notify.NewWebhook(webhookURL)
+	}
+}
+
+// alert logs a problem that needs the attention of the user and sends it to the webhook
+func (as *AutostartService) alert(title string, message string) {
+	root.GetLogger().Errorf("❌ %s: %s", title, message)
+	as.sendAlert(as.currentCar().ID, title, message)
+}
+
+// sendAlert sends an alert about the car to the webhook
+func (as *AutostartService) sendAlert(carID int, title string, message string) {
+	if as.alerts == nil {
+		return
+	}
+
+	err := as.alerts.Send(context.Background(), notify.Alert{
+		Time:        time.Now(),
+		Title:       title,
+		Message:     message,
+		ChargeBoxID: as.chargingStation.BoxId,
+		ConnectorID: as.chargingStation.ConnectorId,
+		CarID:       carID,
+	})
+	if err != nil {
+		root.GetLogger().Warnf("Failed to send alert: %v", err)
+	}
+}
+
+// interrupted checks whether the car still needs the session that just ended:
+// it is plugged in, not complete and below the session target
+func (as *AutostartService) interrupted() (string, bool) {
+	car := as.currentCar()
+	status, err := car.Vehicle.Status(as.context())
+	if err != nil {
+		root.GetLogger().Warnf("Failed to get the status of %s after the session ended: %v", car, err)
+		return "", false
+	}
+
+	as.mu.Lock()
+	target := as.sessionTarget
+	as.mu.Unlock()
+
+	batteryLevel := status.SoC
+	switch status.CurrentChargingState() {
+	case vehicle.ChargingStateComplete, vehicle.ChargingStateDisconnected:
+		return "", false
+	}
+	if !status.PluggedIn || target == 0 || batteryLevel >= target {
+		return "", false
+	}
+	return fmt.Sprintf("car is plugged in at %d%%, below the target of %d%%", batteryLevel, target), true
+}
+
+// restartSession restarts a session that ended unexpectedly. restarts counts the attempts of
+// the supervised session, it returns true once a new session draws power.
+func (as *AutostartService) restartSession(ctx context.Context, restarts *int) bool {
+	log := root.GetLogger()
+
+	var lastErr error
+	for *restarts < as.restartAttempts {
+		*restarts++
+		log.Infof("Restarting the session in %s (attempt %d/%d)", as.restartDelay, *restarts, as.restartAttempts)
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(as.restartDelay):
+		}
+
+		// The car may have been unplugged or reached its target in the meantime
+		if _, interrupted := as.interrupted(); !interrupted {
+			log.Info("Car no longer needs charging, not restarting the session")
+			return false
+		}
+		as.mu.Lock()
+		emergency := as.emergencyActive
+		as.mu.Unlock()
+		if !emergency && as.tariffScheduler != nil && as.tariffScheduler.IsHighTariffTime(time.Now()) {
+			log.Info("High tariff period started, not restarting the session")
+			return false
+		}
+
+		var liveData *ekz.LiveDataResponse
+		liveData, lastErr = as.ekzClient.StartAndVerify(ctx, as.chargingStation.BoxId, as.chargingStation.ConnectorId, as.startOptionsForCar(as.currentCar()))
+		as.recordStart(time.Now(), lastErr)
+		if lastErr == nil {
+			log.Infof("✅ Session restarted as session %d", liveData.TransactionID)
+			as.mu.Lock()
+			target, emergency, car := as.sessionTarget, as.emergencyActive, as.currentCarLocked()
+			as.mu.Unlock()
+			as.ownSession(liveData.TransactionID, target, emergency, car.ID)
+			return true
+		}
+		log.Warnf("Failed to restart the session: %v", lastErr)
+	}
+
+	reason := "restarts are disabled"
+	switch {
+	case lastErr != nil:
+		reason = lastErr.Error()
+	case as.restartAttempts > 0:
+		// The attempts were used up by earlier interruptions of the same session
+		reason = "no restart attempts left"
+	}
+	as.alert("Charging interrupted", fmt.Sprintf("the session on station %s/%d ended unexpectedly and was not restarted after %d attempt(s): %s",
+		as.chargingStation.BoxId, as.chargingStation.ConnectorId, *restarts, reason))
+	return false
+}
@@ -0,0 +1,53 @@
+package autostart
+
+import (
+	"time"
+
+	"github.com/denysvitali/ekz-tesla/ekz"
+	"github.com/denysvitali/ekz-tesla/rules"
+	"github.com/denysvitali/ekz-tesla/vehicle"
+)
+
+// SetRules sets the user-defined start and stop rules
+func (as *AutostartService) SetRules(set *rules.Set) {
+	as.rules = set
+}
+
+// SetTariffs sets how the tariff in force is determined for the rules
+func (as *AutostartService) SetTariffs(scheduler *ekz.ScheduleScheduler, tariffs ekz.Tariffs) {
+	as.tariffScheduler = scheduler
+	as.tariffs = tariffs
+}
+
+// SetGeofence sets the area in which a car is at the station, instead of the default radius
+// around the station coordinates
+func (as *AutostartService) SetGeofence(geofence *ekz.Geofence) {
+	as.geofence = geofence
+}
+
+// locate tells whether the car is at the charging station, by its location or TeslaMate geofence
+func (as *AutostartService) locate(status vehicle.Status) ekz.GeofenceMatch {
+	if status.Location == nil {
+		return as.geofence.Match(0, 0, false, status.Geofence)
+	}
+	return as.geofence.Match(status.Location.Latitude, status.Location.Longitude, true, status.Geofence)
+}
+
+// nearStation tells whether the car is at the charging station, which it is assumed to be
+// when its location is unknown
+func (as *AutostartService) nearStation(status vehicle.Status) bool {
+	return as.locate(status).Inside
+}
+
+// ruleEnv returns the variables of the rule expressions
+func (as *AutostartService) ruleEnv(status vehicle.Status, liveData *ekz.LiveDataResponse, target int, now time.Time) rules.Env {
+	return rules.Build(rules.Input{
+		Now:        now,
+		Vehicle:    status,
+		LiveData:   liveData,
+		Station:    *as.chargingStation,
+		HighTariff: as.tariffScheduler != nil && as.tariffScheduler.IsHighTariffTime(now),
+		Tariffs:    as.tariffs,
+		Target:     target,
+	})
+}
@@ -0,0 +1,276 @@
+package autostart
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/denysvitali/ekz-tesla/cmd/root"
+	"github.com/denysvitali/ekz-tesla/ekz"
+	"github.com/denysvitali/ekz-tesla/history"
+	"github.com/denysvitali/ekz-tesla/notify"
+	"github.com/denysvitali/ekz-tesla/rules"
+	"github.com/denysvitali/ekz-tesla/state"
+)
+
+// AutostartService handles the logic for automatically starting charging
+type AutostartService struct {
+	ekzClient *ekz.Client
+	// cars are the cars charged on the station, at least one
+	cars            []*Car
+	maxCharge       int
+	chargingStation *ekz.ChargingStationConfig
+	geofence        *ekz.Geofence
+	chargeTargets   *ekz.ChargeTargetConfig
+	useCarLimit     bool
+	maxDataAge      time.Duration
+	startOptions    ekz.StartOptions
+
+	// Emergency charging: below emergencySoC, charge up to emergencyTarget regardless of tariff
+	emergencySoC    int
+	emergencyTarget int
+
+	// evaluating serializes the evaluations of the conditions
+	evaluating sync.Mutex
+	// mu protects the state shared with the session supervisor
+	mu              sync.Mutex
+	emergencyActive bool
+	sessionTarget   int
+	// sessionCar is the car charged by the running autostart session, nil if none or unknown
+	sessionCar *Car
+	// selected is the car selected by the last evaluation
+	selected *Car
+	// baseline is the energy added to the batteries of the cars when the session started,
+	// nil once the car charging is identified
+	baseline map[int]float64
+	// delegated holds the handed over sessions being supervised
+	delegated map[string]bool
+	// lastRecorded is the last transaction added to the history
+	lastRecorded int
+
+	dryRun bool
+	rules  *rules.Set
+	// tariffScheduler and tariffs determine the tariff in force for the rules
+	tariffScheduler *ekz.ScheduleScheduler
+	tariffs         ekz.Tariffs
+	history         *history.Store
+	state           *state.Store
+	catchUpWindow   time.Duration
+	backoff         state.BackoffPolicy
+	// restartAttempts limits the restarts of a supervised session that ended unexpectedly
+	restartAttempts int
+	restartDelay    time.Duration
+	alerts          *notify.Webhook
+	carCommands     CarCommands
+	fairness        ekz.FairnessConfig
+	supervisorCtx   context.Context
+	supervising     atomic.Bool
+	supervisorWg    sync.WaitGroup
+}
+
+// NewAutostartService creates a new autostart service charging the cars on the station
+func NewAutostartService(ekzClient *ekz.Client, cars []*Car, maxCharge int, chargingStation *ekz.ChargingStationConfig) *AutostartService {
+	return &AutostartService{
+		ekzClient:       ekzClient,
+		cars:            cars,
+		maxCharge:       maxCharge,
+		chargingStation: chargingStation,
+		geofence:        ekz.NewCircleGeofence(chargingStation.Latitude, chargingStation.Longitude, 0),
+		backoff:         state.DefaultBackoffPolicy,
+	}
+}
+
+// SetEmergencyCharge configures the minimum charge percentage below which charging starts
+// regardless of tariff, and the percentage at which such an emergency charge stops.
+// A floor of 0 disables emergency charging.
+func (as *AutostartService) SetEmergencyCharge(floor int, target int) error {
+	if floor < 0 || floor > 100 || target < 0 || target > 100 {
+		return fmt.Errorf("emergency charge percentages must be between 0 and 100")
+	}
+	if floor > 0 && target <= floor {
+		return fmt.Errorf("emergency target (%d%%) must be above the emergency floor (%d%%)", target, floor)
+	}
+	as.emergencySoC = floor
+	as.emergencyTarget = target
+	return nil
+}
+
+// SetChargeTargets configures per-day and calendar-driven targets,
+// falling back to the maximum charge when no target applies
+func (as *AutostartService) SetChargeTargets(targets *ekz.ChargeTargetConfig) {
+	as.chargeTargets = targets
+}
+
+// targetAt returns the target charge level of the car for a session started at t. The
+// maximum charge and targets of the car replace the ones of the service.
+func (as *AutostartService) targetAt(car *Car, t time.Time) int {
+	log := root.GetLogger()
+	maxCharge, targets := as.maxCharge, as.chargeTargets
+	if car.MaxCharge > 0 {
+		maxCharge = car.MaxCharge
+	}
+	if car.ChargeTargets != nil {
+		targets = car.ChargeTargets
+	}
+	if targets == nil {
+		return maxCharge
+	}
+
+	target, err := targets.TargetAt(t)
+	if err != nil {
+		log.Warnf("Failed to determine charge target of %s, using maximum charge %d%%: %v", car, maxCharge, err)
+		return maxCharge
+	}
+	if target.Percent == 0 {
+		return maxCharge
+	}
+
+	log.Debugf("Charge target of %s %d%% (%s)", car, target.Percent, target.Source)
+	return target.Percent
+}
+
+// context returns the context of the supervision, which is cancelled on shutdown
+func (as *AutostartService) context() context.Context {
+	if as.supervisorCtx == nil {
+		return context.Background()
+	}
+	return as.supervisorCtx
+}
+
+// TryAutostart attempts to start charging if conditions are met
+func (as *AutostartService) TryAutostart() error {
+	_, err := as.tryAutostart(false, true)
+	return err
+}
+
+// TryHighTariffAutostart is called during high tariff periods: it only starts charging
+// when the battery is below the emergency floor, and stops an emergency charge once
+// the safe level is reached
+func (as *AutostartService) TryHighTariffAutostart() error {
+	_, err := as.tryAutostart(true, true)
+	return err
+}
+
+// Explain evaluates all autostart conditions, including the station state, without acting on them
+func (as *AutostartService) Explain(highTariff bool) (*Decision, error) {
+	return as.evaluate(highTariff, true)
+}
+
+// SetDryRun makes the service evaluate its conditions without starting or stopping sessions
+func (as *AutostartService) SetDryRun(dryRun bool) {
+	as.dryRun = dryRun
+}
+
+// tryAutostart evaluates the conditions and acts on the decision. wake allows waking the car
+// up first if configured. Concurrent calls, e.g. from the schedule and from car data changes,
+// run one after the other.
+func (as *AutostartService) tryAutostart(highTariff bool, wake bool) (*Decision, error) {
+	as.evaluating.Lock()
+	defer as.evaluating.Unlock()
+
+	log := root.GetLogger()
+	log.Debugf("Checking autostart conditions for %s (max charge: %d%%, high tariff: %v)", as.carsString(), as.maxCharge, highTariff)
+
+	if wake && as.carCommands.Wake && !as.dryRun {
+		as.wakeCars(as.context())
+	}
+
+	d, err := as.evaluate(highTariff, as.dryRun)
+	if err != nil {
+		return nil, err
+	}
+	for _, check := range d.Checks {
+		log.Debugf("Check %s: passed=%v value=%s want=%s", check.Name, check.Passed, check.Value, check.Want)
+	}
+
+	if as.dryRun {
+		log.Infof("Dry run: would %s (%s)", d.Action, d.Reason)
+		return d, nil
+	}
+	return d, as.apply(d)
+}
+
+// apply acts on the decision
+func (as *AutostartService) apply(d *Decision) error {
+	log := root.GetLogger()
+	as.observe(d)
+	as.updateTurns(d)
+
+	if d.Action == DecisionContinue || d.Action == DecisionStop {
+		as.mu.Lock()
+		defer as.mu.Unlock()
+		if d.Action == DecisionStop {
+			log.Info(d.Reason)
+			as.requestStop(as.chargingStation.BoxId, as.chargingStation.ConnectorId)
+			if _, err := as.ekzClient.RemoteStop(as.chargingStation.BoxId, as.chargingStation.ConnectorId); err != nil {
+				return fmt.Errorf("failed to stop emergency charge: %w", err)
+			}
+			as.emergencyActive = false
+			return nil
+		}
+		if as.emergencyActive && !d.HighTariff {
+			log.Info(d.Reason)
+			as.emergencyActive = false
+			as.sessionTarget = d.Target
+			as.updateOwnedSession(d.Target, false, d.CarID)
+		}
+		log.Info("Car is already charging")
+		return nil
+	}
+
+	// The car is not charging, an emergency session is no longer running
+	as.mu.Lock()
+	as.emergencyActive = false
+	as.mu.Unlock()
+
+	if d.Action != DecisionStart {
+		log.Infof("Not charging: %s", d.Reason)
+		return nil
+	}
+
+	// All conditions met, start charging
+	if d.Emergency {
+		log.Warnf("⚠️ %s", d.Reason)
+	} else {
+		log.Info("All conditions met, starting charge...")
+	}
+	ctx := as.context()
+	var transactionID int
+	if d.preflight.AlreadyCharging() {
+		transactionID = d.preflight.Session.TransactionID
+		log.Infof("Session %d is already running", transactionID)
+	} else {
+		for _, warning := range d.preflight.Warnings() {
+			log.Warnf("Pre-flight: %s", warning.Message)
+		}
+		as.prepareCar(ctx, d.car, d.Target)
+		opts := as.startOptionsForCar(d.car)
+		opts.Progress = func(elapsed time.Duration, liveData *ekz.LiveDataResponse) {
+			if liveData != nil {
+				log.Debugf("Waiting for power (%s): %s, %.2f kW", elapsed.Truncate(time.Second), liveData.Status, liveData.Power)
+			}
+		}
+		liveData, err := as.ekzClient.StartAndVerify(ctx, as.chargingStation.BoxId, as.chargingStation.ConnectorId, opts)
+		as.recordStart(d.Time, err)
+		if err != nil {
+			return fmt.Errorf("failed to start charge: %w", err)
+		}
+		transactionID = liveData.TransactionID
+	}
+	baseline := as.readBaseline(ctx)
+	as.mu.Lock()
+	as.emergencyActive = d.Emergency
+	as.sessionTarget = d.Target
+	as.sessionCar = d.car
+	as.baseline = baseline
+	as.mu.Unlock()
+	if as.supervisorCtx != nil {
+		as.ownSession(transactionID, d.Target, d.Emergency, d.CarID)
+	}
+
+	log.Info("✅ Successfully started charging")
+	as.superviseSession()
+	return nil
+}
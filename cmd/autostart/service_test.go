@@ -0,0 +1,143 @@
+package autostart
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/h2non/gock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/ekz-tesla/ekz"
+	"github.com/denysvitali/ekz-tesla/history"
+	"github.com/denysvitali/ekz-tesla/state"
+	"github.com/denysvitali/ekz-tesla/vehicle"
+)
+
+// Coordinates of the test charging station
+const (
+	testLatitude  = 47.123
+	testLongitude = 8.456
+)
+
+// fakeVehicle returns the status set by the test
+type fakeVehicle struct {
+	mu     sync.Mutex
+	status vehicle.Status
+	err    error
+}
+
+func (f *fakeVehicle) Status(_ context.Context) (*vehicle.Status, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	status := f.status
+	return &status, nil
+}
+
+func (f *fakeVehicle) set(fn func(*vehicle.Status)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fn(&f.status)
+}
+
+// atStation is a car plugged in at the station, not charging, at 50%
+func atStation() vehicle.Status {
+	return vehicle.Status{
+		SoC:           50,
+		PluggedIn:     true,
+		ChargingState: vehicle.ChargingStateStopped,
+		State:         "online",
+		Location:      &vehicle.Location{Latitude: testLatitude, Longitude: testLongitude},
+	}
+}
+
+// newTestCar creates a car with a fake vehicle reporting status
+func newTestCar(id int, status vehicle.Status) (*Car, *fakeVehicle) {
+	fake := &fakeVehicle{status: status}
+	return &Car{ID: id, Name: "car", Vehicle: fake, Capacity: ekz.DefaultBatteryCapacity}, fake
+}
+
+// newTestService creates a service charging the cars on box 1234, connector 1, with the
+// EKZ backend mocked by gock and the state and history in a temporary directory
+func newTestService(t *testing.T, cars ...*Car) *AutostartService {
+	t.Helper()
+	gock.Intercept()
+	t.Cleanup(gock.Off)
+
+	client, err := ekz.New(&ekz.Config{})
+	require.NoError(t, err)
+	station := &ekz.ChargingStationConfig{BoxId: "1234", ConnectorId: 1, Latitude: testLatitude, Longitude: testLongitude}
+	as := NewAutostartService(client, cars, 80, station)
+	dir := t.TempDir()
+	as.state = state.New(filepath.Join(dir, "state.json"))
+	as.history = history.New(filepath.Join(dir, "sessions.jsonl"))
+	return as
+}
+
+// mockStation mocks the charging stations of the account with connector 1 of box 1234
+// in the given state
+func mockStation(online bool, connectorStatus string) {
+	gock.New(ekz.Backend).
+		Post("/charging-stations/user-charging-stations").
+		Reply(http.StatusOK).
+		JSON(map[string]any{
+			"status_code": 200,
+			"data": map[string]any{
+				"charging_stations": []any{map[string]any{
+					"chargeBoxes": []any{map[string]any{
+						"chargeBoxId": "1234",
+						"online":      online,
+						"connectors": []any{map[string]any{
+							"connectorId":           1,
+							"status":                connectorStatus,
+							"hasPermission":         true,
+							"chargingProcessStatus": "inactive",
+						}},
+					}},
+				}},
+			},
+		})
+}
+
+// mockNoLiveData mocks the live data of a connector without our session
+func mockNoLiveData() {
+	gock.New(ekz.Backend).
+		Post("/charging-stations/charging-live-data").
+		Reply(http.StatusNotFound).
+		File("../../resources/live-data-fail.json")
+}
+
+// mockLiveData mocks the live data of our session on the connector
+func mockLiveData(data map[string]any) *gock.Request {
+	request := gock.New(ekz.Backend).
+		Post("/charging-stations/charging-live-data")
+	request.Reply(http.StatusOK).JSON(data)
+	return request
+}
+
+func TestTryAutostart_DryRun(t *testing.T) {
+	car, _ := newTestCar(1, atStation())
+	as := newTestService(t, car)
+	as.SetDryRun(true)
+	mockStation(true, "Available")
+	remoteStart := gock.New(ekz.Backend).Post("/saascharge/remote-start")
+	remoteStart.Reply(http.StatusOK).File("../../resources/remote-start.json")
+
+	d, err := as.tryAutostart(false, true)
+	require.NoError(t, err)
+	assert.Equal(t, DecisionStart, d.Action)
+	assert.False(t, remoteStart.Mock.Done(), "a dry run must not start a session")
+	assert.False(t, gock.HasUnmatchedRequest())
+
+	// Nothing was recorded either
+	st, err := as.state.Load()
+	require.NoError(t, err)
+	assert.Empty(t, st.Sessions)
+	assert.Nil(t, as.sessionCar)
+}
@@ -0,0 +1,189 @@
+package autostart
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/denysvitali/ekz-tesla/cmd/root"
+	"github.com/denysvitali/ekz-tesla/ekz"
+	"github.com/denysvitali/ekz-tesla/history"
+	"github.com/denysvitali/ekz-tesla/vehicle"
+)
+
+// EnableSupervision makes the service supervise the sessions it starts until ctx is cancelled:
+// a session is stopped when the target is reached, the car completes charging or is unplugged
+func (as *AutostartService) EnableSupervision(ctx context.Context) {
+	as.supervisorCtx = ctx
+}
+
+// WaitForSupervisor blocks until the running session supervisor, if any, returns
+func (as *AutostartService) WaitForSupervisor() {
+	as.supervisorWg.Wait()
+}
+
+// superviseSession starts a supervisor for the session that was just started
+func (as *AutostartService) superviseSession() {
+	if as.supervisorCtx == nil {
+		return
+	}
+	if !as.supervising.CompareAndSwap(false, true) {
+		root.GetLogger().Debug("Session is already supervised")
+		return
+	}
+
+	as.supervisorWg.Add(1)
+	go func() {
+		defer as.supervisorWg.Done()
+		defer as.supervising.Store(false)
+		err := as.runSupervisor(as.supervisorCtx)
+		if errors.Is(err, context.Canceled) {
+			// Keep the session in the state, the next daemon resumes its supervision
+			return
+		}
+		if err != nil {
+			root.GetLogger().Errorf("Session supervision failed: %v", err)
+		}
+		as.releaseSession()
+	}()
+}
+
+// runSupervisor supervises the session until it is stopped or ends. A session that ends
+// without a stop command while the car still needs charge is restarted. Stop commands not
+// sent by the supervisor are recorded in the state by requestStop.
+func (as *AutostartService) runSupervisor(ctx context.Context) error {
+	restarts := 0
+	for {
+		supervisor := ekz.NewSessionSupervisor(as.ekzClient, as.chargingStation.BoxId, as.chargingStation.ConnectorId)
+		supervisor.AddStopCondition(as.carStopCondition)
+
+		result, err := supervisor.Run(ctx)
+		if err != nil {
+			return err
+		}
+
+		interruption, interrupted := "", false
+		switch {
+		case result.Stopped:
+		case as.stopRequested():
+			result.Reason = "stopped by a stop command"
+		default:
+			interruption, interrupted = as.interrupted()
+		}
+		if interrupted {
+			result.Reason = "session ended unexpectedly"
+		}
+		as.recordSession(as.chargingStation.BoxId, as.chargingStation.ConnectorId, result)
+		if !interrupted {
+			return nil
+		}
+
+		root.GetLogger().Warnf("⚠️ Session ended without a stop command while the %s", interruption)
+		if !as.restartSession(ctx, &restarts) {
+			return nil
+		}
+	}
+}
+
+// carStopCondition stops the session when the car reached the session target,
+// reports charging as complete, was unplugged, or the stop rule is true
+func (as *AutostartService) carStopCondition(liveData *ekz.LiveDataResponse) (string, bool) {
+	as.identifyCar(liveData)
+	car := as.currentCar()
+	status, err := car.Vehicle.Status(as.context())
+	if err != nil {
+		root.GetLogger().Warnf("Failed to get the status of %s while supervising: %v", car, err)
+		return "", false
+	}
+
+	as.mu.Lock()
+	target := as.sessionTarget
+	as.mu.Unlock()
+
+	batteryLevel := status.SoC
+	switch status.CurrentChargingState() {
+	case vehicle.ChargingStateComplete:
+		return "car reports charging complete", true
+	case vehicle.ChargingStateDisconnected:
+		return "car was unplugged", true
+	}
+	if !status.PluggedIn {
+		return "car was unplugged", true
+	}
+	if target > 0 && batteryLevel >= target {
+		return fmt.Sprintf("target of %d%% reached (battery at %d%%)", target, batteryLevel), true
+	}
+	if reason, stop := as.turnOver(car, time.Now()); stop {
+		return reason, true
+	}
+	if as.rules != nil && as.rules.Stop != nil {
+		stop, err := as.rules.Stop.Eval(as.ruleEnv(*status, liveData, target, time.Now()))
+		if err != nil {
+			root.GetLogger().Warnf("Stop rule: %v", err)
+		} else if stop {
+			return fmt.Sprintf("stop rule %q is true", as.rules.Stop), true
+		}
+	}
+	return "", false
+}
+
+// recordSession logs the final energy and cost of a session and appends it to the history
+func (as *AutostartService) recordSession(chargeBoxID string, connectorID int, result *ekz.SessionResult) {
+	log := root.GetLogger()
+	if result.LiveData == nil {
+		log.Infof("Session ended (%s)", result.Reason)
+		return
+	}
+
+	liveData := result.LiveData
+	stop, ok := liveData.StopTime()
+	if !ok {
+		stop = time.Now()
+	}
+
+	// A session can be supervised twice when it was handed over while autostart supervised it
+	as.mu.Lock()
+	duplicate := as.lastRecorded == liveData.TransactionID
+	as.lastRecorded = liveData.TransactionID
+	as.mu.Unlock()
+	if duplicate {
+		return
+	}
+
+	// With several cars, only the car of an autostart session is known
+	carID := 0
+	if chargeBoxID == as.chargingStation.BoxId && connectorID == as.chargingStation.ConnectorId {
+		as.mu.Lock()
+		switch {
+		case as.sessionCar != nil:
+			carID = as.sessionCar.ID
+		case len(as.cars) == 1:
+			carID = as.cars[0].ID
+		}
+		as.mu.Unlock()
+	}
+	summary := liveData.Summary(as.tariffs)
+	session := history.Session{
+		ChargeBoxID:      chargeBoxID,
+		ConnectorID:      connectorID,
+		TransactionID:    liveData.TransactionID,
+		CarID:            carID,
+		Start:            liveData.StartTime(),
+		Stop:             stop,
+		Energy:           liveData.ChargedEnergy,
+		HighTariffEnergy: summary.HighTariffEnergy,
+		LowTariffEnergy:  summary.LowTariffEnergy,
+		Cost:             summary.TotalCost,
+		CostEstimated:    summary.CostEstimated,
+		StopReason:       result.Reason,
+	}
+	log.Infof("Session %d ended (%s): %.2f kWh, CHF %.2f", session.TransactionID, session.StopReason, session.Energy, session.Cost)
+
+	if as.history == nil {
+		return
+	}
+	if err := as.history.Append(session); err != nil {
+		log.Warnf("Failed to record session: %v", err)
+	}
+}
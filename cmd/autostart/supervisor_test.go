@@ -0,0 +1,108 @@
+package autostart
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/h2non/gock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/ekz-tesla/ekz"
+	"github.com/denysvitali/ekz-tesla/vehicle"
+)
+
+// endedSession is the live data of a session that ended
+func endedSession(transactionID int) map[string]any {
+	start := time.Now().Add(-time.Hour)
+	return map[string]any{
+		"transaction_id": transactionID,
+		"status":         "FINISHED",
+		"starttimestamp": start.Unix(),
+		"stoptimestamp":  time.Now().Unix(),
+		"charged_energy": 5.0,
+		"totalcost":      1.0,
+	}
+}
+
+// newSupervisedService creates a service supervising the autostart session 1 of a car at 50%
+// with a target of 80%
+func newSupervisedService(t *testing.T) (*AutostartService, *fakeVehicle) {
+	t.Helper()
+	car, fake := newTestCar(1, atStation())
+	as := newTestService(t, car)
+	as.EnableSupervision(context.Background())
+	require.NoError(t, as.SetRestart(1, time.Millisecond))
+	as.sessionTarget = 80
+	as.sessionCar = car
+	as.ownSession(1, 80, false, car.ID)
+	return as, fake
+}
+
+func TestRunSupervisor_EndedByItself(t *testing.T) {
+	as, _ := newSupervisedService(t)
+	mockLiveData(endedSession(1))
+	remoteStart := gock.New(ekz.Backend).Post("/saascharge/remote-start")
+	remoteStart.Reply(http.StatusOK).File("../../resources/remote-start.json")
+	mockLiveData(map[string]any{"transaction_id": 2, "status": "ONGOING", "power": 11.0})
+	// The restarted session ends as well, no restart attempt is left
+	mockLiveData(endedSession(2))
+
+	require.NoError(t, as.runSupervisor(context.Background()))
+	assert.True(t, remoteStart.Mock.Done(), "the interrupted session must be restarted")
+	assert.True(t, gock.IsDone())
+
+	sessions, err := as.history.List()
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	assert.Equal(t, "session ended unexpectedly", sessions[0].StopReason)
+	assert.Equal(t, "session ended unexpectedly", sessions[1].StopReason)
+
+	st, err := as.state.Load()
+	require.NoError(t, err)
+	session, ok := st.Session("1234", 1)
+	require.True(t, ok)
+	assert.Equal(t, 2, session.TransactionID, "the restarted session is owned")
+}
+
+func TestRunSupervisor_StoppedByUs(t *testing.T) {
+	as, _ := newSupervisedService(t)
+	as.requestStop("1234", 1)
+	mockLiveData(endedSession(1))
+	remoteStart := gock.New(ekz.Backend).Post("/saascharge/remote-start")
+	remoteStart.Reply(http.StatusOK).File("../../resources/remote-start.json")
+
+	require.NoError(t, as.runSupervisor(context.Background()))
+	assert.False(t, remoteStart.Mock.Done(), "a session stopped on purpose must not be restarted")
+
+	sessions, err := as.history.List()
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, "stopped by a stop command", sessions[0].StopReason)
+}
+
+func TestRunSupervisor_TargetReached(t *testing.T) {
+	as, fake := newSupervisedService(t)
+	fake.set(func(s *vehicle.Status) { s.SoC = 80 })
+	mockLiveData(endedSession(1))
+
+	require.NoError(t, as.runSupervisor(context.Background()))
+	sessions, err := as.history.List()
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, "session ended", sessions[0].StopReason)
+}
+
+func TestRestartSession_NoLongerNeeded(t *testing.T) {
+	as, fake := newSupervisedService(t)
+	fake.set(func(s *vehicle.Status) { s.PluggedIn = false })
+	remoteStart := gock.New(ekz.Backend).Post("/saascharge/remote-start")
+	remoteStart.Reply(http.StatusOK).File("../../resources/remote-start.json")
+
+	restarts := 0
+	assert.False(t, as.restartSession(context.Background(), &restarts))
+	assert.Equal(t, 1, restarts)
+	assert.False(t, remoteStart.Mock.Done())
+}
@@ -0,0 +1,213 @@
+package car
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/table"
+	"github.com/spf13/cobra"
+
+	"github.com/denysvitali/ekz-tesla/cmd/root"
+	"github.com/denysvitali/ekz-tesla/teslamateapi"
+)
+
+var (
+	carID           int
+	teslaMateAPIURL string
+	teslaMateToken  string
+)
+
+var CarCmd = &cobra.Command{
+	Use:   "car",
+	Short: "Send commands to the car through TeslaMateApi",
+	Long: `List the cars known to TeslaMate and send commands to a car through TeslaMateApi.
+
+Commands require ENABLE_COMMANDS and an API_TOKEN to be configured in TeslaMateApi,
+pass the token with --teslamate-api-token or $TESLAMATE_API_TOKEN.`,
+	Example: `  # Wake the car up
+  ekz-tesla car wake --teslamate-api-url http://teslamate-api:8080
+
+  # Set the charge limit of car 2 to 80%
+  ekz-tesla car charge-limit 80 --car-id 2 --teslamate-api-url http://teslamate-api:8080`,
+}
+
+var carListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the cars known to TeslaMate",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+		cars, err := client.ListCars(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("failed to list cars: %w", err)
+		}
+
+		var rows [][]string
+		for _, car := range cars {
+			rows = append(rows, []string{
+				strconv.Itoa(car.CarID),
+				car.Name,
+				strings.TrimSpace(car.CarDetails.Model + " " + car.CarDetails.TrimBadging),
+				car.CarDetails.VIN,
+			})
+		}
+		t := table.New().
+			Border(lipgloss.NormalBorder()).
+			BorderStyle(lipgloss.NewStyle().Foreground(lipgloss.Color("99"))).
+			Headers("ID", "NAME", "MODEL", "VIN").
+			StyleFunc(func(row, col int) lipgloss.Style {
+				if row == table.HeaderRow {
+					return lipgloss.NewStyle().Bold(true).PaddingLeft(1).PaddingRight(1)
+				}
+				return lipgloss.NewStyle().PaddingLeft(1).PaddingRight(1)
+			}).
+			Rows(rows...)
+		fmt.Println(t)
+		return nil
+	},
+}
+
+var carWakeCmd = &cobra.Command{
+	Use:   "wake",
+	Short: "Wake the car up",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, id, err := newCarClient(cmd)
+		if err != nil {
+			return err
+		}
+		state, err := client.WakeUp(cmd.Context(), id)
+		if err != nil {
+			return fmt.Errorf("failed to wake the car up: %w", err)
+		}
+		fmt.Printf("✅ Wake-up sent, car is %s\n", state)
+		return nil
+	},
+}
+
+var carChargeLimitCmd = &cobra.Command{
+	Use:   "charge-limit <percent>",
+	Short: "Set the charge limit of the car",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		percent, err := strconv.Atoi(args[0])
+		if err != nil || percent < 50 || percent > 100 {
+			return fmt.Errorf("invalid charge limit %q, expected a percentage between 50 and 100", args[0])
+		}
+		return runCommand(cmd, fmt.Sprintf("Charge limit set to %d%%", percent), func(client *teslamateapi.Client, id int) error {
+			return client.SetChargeLimit(cmd.Context(), id, percent)
+		})
+	},
+}
+
+var carAmpsCmd = &cobra.Command{
+	Use:   "amps <amps>",
+	Short: "Set the charging current of the car",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		amps, err := strconv.Atoi(args[0])
+		if err != nil || amps < 1 || amps > 48 {
+			return fmt.Errorf("invalid charging current %q, expected amps between 1 and 48", args[0])
+		}
+		return runCommand(cmd, fmt.Sprintf("Charging current set to %d A", amps), func(client *teslamateapi.Client, id int) error {
+			return client.SetChargingAmps(cmd.Context(), id, amps)
+		})
+	},
+}
+
+var carChargeCmd = &cobra.Command{
+	Use:       "charge <start|stop>",
+	Short:     "Start or stop charging on the car side",
+	Long:      `Start or stop charging on the car side. The EKZ session is not affected, use 'start' and 'stop' for it.`,
+	Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	ValidArgs: []string{"start", "stop"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if args[0] == "start" {
+			return runCommand(cmd, "Car started charging", func(client *teslamateapi.Client, id int) error {
+				return client.ChargeStart(cmd.Context(), id)
+			})
+		}
+		return runCommand(cmd, "Car stopped charging", func(client *teslamateapi.Client, id int) error {
+			return client.ChargeStop(cmd.Context(), id)
+		})
+	},
+}
+
+var carClimateCmd = &cobra.Command{
+	Use:       "climate <on|off>",
+	Short:     "Turn the climate control on or off",
+	Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	ValidArgs: []string{"on", "off"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if args[0] == "on" {
+			return runCommand(cmd, "Climate control turned on", func(client *teslamateapi.Client, id int) error {
+				return client.ClimateStart(cmd.Context(), id)
+			})
+		}
+		return runCommand(cmd, "Climate control turned off", func(client *teslamateapi.Client, id int) error {
+			return client.ClimateStop(cmd.Context(), id)
+		})
+	},
+}
+
+func newClient() (*teslamateapi.Client, error) {
+	client, err := teslamateapi.New(strings.TrimSuffix(teslaMateAPIURL, "/"), teslamateapi.WithToken(teslaMateToken))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create TeslaMate API client: %w", err)
+	}
+	return client, nil
+}
+
+// newCarClient returns the client and the car ID, discovering it if not given
+func newCarClient(cmd *cobra.Command) (*teslamateapi.Client, int, error) {
+	client, err := newClient()
+	if err != nil {
+		return nil, 0, err
+	}
+	if carID != 0 {
+		return client, carID, nil
+	}
+	id, err := client.DiscoverCarID(cmd.Context())
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to discover the car, use --car-id: %w", err)
+	}
+	return client, id, nil
+}
+
+// runCommand sends a command to the car and prints done on success
+func runCommand(cmd *cobra.Command, done string, send func(client *teslamateapi.Client, id int) error) error {
+	client, id, err := newCarClient(cmd)
+	if err != nil {
+		return err
+	}
+	if err := send(client, id); err != nil {
+		return fmt.Errorf("car %d: %w", id, err)
+	}
+	fmt.Printf("✅ %s\n", done)
+	return nil
+}
+
+func init() {
+	CarCmd.PersistentFlags().IntVar(&carID, "car-id", 0, "TeslaMate car ID (default: the only car known to TeslaMate)")
+	CarCmd.PersistentFlags().StringVar(&teslaMateAPIURL, "teslamate-api-url", "", "TeslaMate API URL (required)")
+	CarCmd.PersistentFlags().StringVar(&teslaMateToken, "teslamate-api-token", os.Getenv("TESLAMATE_API_TOKEN"),
+		"TeslaMate API token, as configured in API_TOKEN (default: $TESLAMATE_API_TOKEN)")
+	if err := CarCmd.MarkPersistentFlagRequired("teslamate-api-url"); err != nil {
+		panic(fmt.Sprintf("Failed to mark teslamate-api-url flag as required: %v", err))
+	}
+
+	CarCmd.AddCommand(carListCmd)
+	CarCmd.AddCommand(carWakeCmd)
+	CarCmd.AddCommand(carChargeLimitCmd)
+	CarCmd.AddCommand(carAmpsCmd)
+	CarCmd.AddCommand(carChargeCmd)
+	CarCmd.AddCommand(carClimateCmd)
+
+	root.RootCmd.AddCommand(CarCmd)
+}
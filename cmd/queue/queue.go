@@ -0,0 +1,141 @@
+package queue
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/table"
+	"github.com/spf13/cobra"
+
+	"github.com/denysvitali/ekz-tesla/cmd/root"
+	"github.com/denysvitali/ekz-tesla/state"
+)
+
+var QueueCmd = &cobra.Command{
+	Use:   "queue",
+	Short: "Manage one-off actions queued for the daemon",
+	Long: `Manage the start and stop actions queued with 'start --at' and 'stop --at'.
+
+Queued actions are kept in the state file and executed by the running autostart
+daemon ('autostart scheduled' or 'autostart smart'). Actions whose time passed
+while no daemon was running are skipped, unless they are late by less than the
+catch-up window (queue.catch_up_window in the config, default: 10m).
+
+An action stays queued while it runs. Skipped and failed actions stay listed with
+the error until they are cancelled, or removed by the daemon a week after their time.`,
+}
+
+var queueListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the queued actions",
+	Example: `  # List the queued actions
+  ekz-tesla queue list`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		st, err := state.New(state.DefaultPath()).Load()
+		if err != nil {
+			return fmt.Errorf("failed to load state: %w", err)
+		}
+
+		if !st.Daemon.Alive(time.Now()) {
+			fmt.Println("⚠️  No autostart daemon is running, queued actions only run once one is started")
+		}
+		if len(st.Queue) == 0 {
+			fmt.Println("No queued actions.")
+			return nil
+		}
+
+		printQueue(st.Queue)
+		if len(st.FailedActions()) > 0 {
+			fmt.Println("Failed actions are removed a week after their time, or now with 'queue cancel <id>'")
+		}
+		return nil
+	},
+}
+
+var queueCancelCmd = &cobra.Command{
+	Use:   "cancel <id>...",
+	Short: "Cancel queued actions, or clear failed ones",
+	Example: `  # Cancel the queued action 3
+  ekz-tesla queue cancel 3`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var ids []int
+		for _, arg := range args {
+			id, err := strconv.Atoi(arg)
+			if err != nil {
+				return fmt.Errorf("invalid action ID %q", arg)
+			}
+			ids = append(ids, id)
+		}
+
+		var cancelled []state.Action
+		err := state.New(state.DefaultPath()).Update(func(st *state.State) error {
+			for _, id := range ids {
+				action, ok := st.Cancel(id)
+				if !ok {
+					return fmt.Errorf("no queued action with ID %d", id)
+				}
+				cancelled = append(cancelled, action)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, action := range cancelled {
+			fmt.Printf("🗑️  Cancelled action %d: %s\n", action.ID, action)
+		}
+		return nil
+	},
+}
+
+func init() {
+	QueueCmd.AddCommand(queueListCmd)
+	QueueCmd.AddCommand(queueCancelCmd)
+
+	root.RootCmd.AddCommand(QueueCmd)
+}
+
+// printQueue prints the queued actions using lipgloss's table
+func printQueue(actions []state.Action) {
+	var rows [][]string
+	for _, action := range actions {
+		limits := "-"
+		if !action.Limits.IsZero() {
+			limits = action.Limits.String()
+		}
+		status := "pending"
+		if action.Status != state.ActionPending {
+			status = string(action.Status)
+		}
+		if action.Error != "" {
+			status += ": " + action.Error
+		}
+		rows = append(rows, []string{
+			strconv.Itoa(action.ID),
+			string(action.Kind),
+			action.At.Local().Format("2006-01-02 15:04 Mon"),
+			action.ChargeBoxID,
+			strconv.Itoa(action.ConnectorID),
+			limits,
+			status,
+		})
+	}
+
+	t := table.New().
+		Border(lipgloss.NormalBorder()).
+		BorderStyle(lipgloss.NewStyle().Foreground(lipgloss.Color("99"))).
+		Headers("ID", "ACTION", "AT", "BOX ID", "CONN", "LIMITS", "STATUS").
+		StyleFunc(func(row, col int) lipgloss.Style {
+			if row == table.HeaderRow {
+				return lipgloss.NewStyle().Bold(true).PaddingLeft(1).PaddingRight(1)
+			}
+			return lipgloss.NewStyle().PaddingLeft(1).PaddingRight(1)
+		}).
+		Rows(rows...)
+
+	fmt.Println(t)
+}
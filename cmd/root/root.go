@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/adrg/xdg"
 	"github.com/sirupsen/logrus"
@@ -45,10 +46,16 @@ You can list available stations, start/stop charging, view live data, and set up
 			return fmt.Errorf("failed to initialize config: %w", err)
 		}
 
+		// Actions queued with --at run in the daemon, which has its own client
+		if at := cmd.Flags().Lookup("at"); at != nil && at.Changed {
+			return nil
+		}
+
 		// Initialize EKZ client for commands that need it
-		needsClient := []string{"list", "start", "stop", "live-data"}
+		needsClient := []string{"list", "start", "stop", "live-data", "rules snapshot", "sync"}
+		path := strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()+" ")
 		for _, cmdName := range needsClient {
-			if cmd.Name() == cmdName || cmd.Parent().Name() == cmdName {
+			if path == cmdName || strings.HasPrefix(path, cmdName+" ") {
 				if err := initClient(); err != nil {
 					return fmt.Errorf("unable to initialize client: %w", err)
 				}
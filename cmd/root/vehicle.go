@@ -0,0 +1,107 @@
+package root
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/denysvitali/ekz-tesla/teslamateapi"
+	"github.com/denysvitali/ekz-tesla/teslamatedb"
+	"github.com/denysvitali/ekz-tesla/vehicle"
+)
+
+// vehicleConnectTimeout bounds the connection of the vehicle provider, e.g. to the MQTT broker
+const vehicleConnectTimeout = 30 * time.Second
+
+// Vehicle is the car data provider selected in the configuration
+type Vehicle struct {
+	Provider vehicle.Provider
+	// Commander sends commands through TeslaMateApi, nil without its URL
+	Commander vehicle.Commander
+	// DB reads the TeslaMate database, nil without its DSN
+	DB *teslamatedb.DB
+	// CarID is the TeslaMate ID of the car, discovered if not given
+	CarID int
+}
+
+// NewVehicle creates the vehicle provider selected in the configuration. The TeslaMate API URL
+// is only required by the teslamateapi provider and by car commands, the teslamate_db DSN by
+// the postgres provider. When either is given and carID is 0, the only car known to TeslaMate
+// is used.
+func NewVehicle(ctx context.Context, carID int, teslaMateAPIURL string, opts ...teslamateapi.Option) (*Vehicle, error) {
+	vehicles, err := NewVehicles(ctx, []int{carID}, teslaMateAPIURL, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return vehicles[0], nil
+}
+
+// NewVehicles creates the vehicle providers of several cars, sharing the TeslaMate clients.
+// Without car IDs, or with a single ID of 0, the car is discovered as with NewVehicle.
+func NewVehicles(ctx context.Context, carIDs []int, teslaMateAPIURL string, opts ...teslamateapi.Option) ([]*Vehicle, error) {
+	cfg := GetConfig()
+	if cfg == nil {
+		return nil, fmt.Errorf("configuration not loaded")
+	}
+
+	var db *teslamatedb.DB
+	if cfg.TeslaMateDB.DSN != "" {
+		var err error
+		if db, err = teslamatedb.Open(cfg.TeslaMateDB.DSN); err != nil {
+			return nil, fmt.Errorf("failed to open TeslaMate database: %w", err)
+		}
+	}
+	var carAPI *teslamateapi.Client
+	if teslaMateAPIURL != "" {
+		var err error
+		carAPI, err = teslamateapi.New(strings.TrimSuffix(teslaMateAPIURL, "/"), opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create TeslaMate API client: %w", err)
+		}
+	}
+
+	if len(carIDs) == 0 || (len(carIDs) == 1 && carIDs[0] == 0) {
+		carID := 0
+		var err error
+		switch {
+		case carAPI != nil:
+			carID, err = carAPI.DiscoverCarID(ctx)
+		case db != nil:
+			carID, err = db.DiscoverCarID(ctx)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to discover the car, use --car-id: %w", err)
+		}
+		if carID != 0 {
+			GetLogger().Infof("Using car %d, the only car known to TeslaMate", carID)
+		}
+		carIDs = []int{carID}
+	}
+
+	vehicles := make([]*Vehicle, 0, len(carIDs))
+	for _, carID := range carIDs {
+		v := Vehicle{CarID: carID, DB: db}
+		if carAPI != nil {
+			v.Commander = vehicle.NewTeslaMateAPI(carAPI, carID)
+		}
+
+		config := cfg.Vehicle
+		if len(carIDs) > 1 && config.MQTT.ClientID != "" {
+			// Every car has its own connection, which the broker tells apart by the client ID
+			config.MQTT.ClientID = fmt.Sprintf("%s-%d", config.MQTT.ClientID, carID)
+		}
+		connectCtx, cancel := context.WithTimeout(ctx, vehicleConnectTimeout)
+		provider, err := vehicle.New(connectCtx, config, carID, vehicle.Sources{API: carAPI, DB: db})
+		cancel()
+		if err != nil && len(carIDs) > 1 {
+			return nil, fmt.Errorf("failed to create %s vehicle provider for car %d: %w", cfg.Vehicle.ProviderName(), carID, err)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s vehicle provider: %w", cfg.Vehicle.ProviderName(), err)
+		}
+		v.Provider = provider
+		vehicles = append(vehicles, &v)
+	}
+	return vehicles, nil
+}
@@ -0,0 +1,218 @@
+package rules
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/table"
+	"github.com/spf13/cobra"
+
+	"github.com/denysvitali/ekz-tesla/cmd/root"
+	"github.com/denysvitali/ekz-tesla/ekz"
+	"github.com/denysvitali/ekz-tesla/rules"
+	"github.com/denysvitali/ekz-tesla/teslamateapi"
+)
+
+var (
+	snapshotPath    string
+	startRule       string
+	stopRule        string
+	carID           int
+	teslaMateAPIURL string
+	teslaMateToken  string
+	maximumCharge   int
+	highTariffTimes []string
+)
+
+var RulesCmd = &cobra.Command{
+	Use:   "rules",
+	Short: "Inspect and test the autostart rule expressions",
+	Long: `Autostart start and stop policies can be expressed as rules in the config:
+
+  rules:
+    start: car.soc < 60 && tariff.low && !car.sentry_mode && weekday != "Sun"
+    stop: session.energy >= 20 || tariff.high
+
+The start rule replaces the tariff and battery conditions of autostart; the car
+must still be plugged in and near the station. The stop rule stops a supervised
+session as soon as it is true. Use 'rules variables' to list the variables.`,
+}
+
+var rulesVariablesCmd = &cobra.Command{
+	Use:   "variables",
+	Short: "List the variables available to rule expressions",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var rows [][]string
+		for _, v := range rules.Variables {
+			rows = append(rows, []string{v.Name, v.Type, v.Description})
+		}
+
+		t := table.New().
+			Border(lipgloss.NormalBorder()).
+			BorderStyle(lipgloss.NewStyle().Foreground(lipgloss.Color("99"))).
+			Headers("VARIABLE", "TYPE", "DESCRIPTION").
+			StyleFunc(func(row, col int) lipgloss.Style {
+				if row == table.HeaderRow {
+					return lipgloss.NewStyle().Bold(true).PaddingLeft(1).PaddingRight(1)
+				}
+				return lipgloss.NewStyle().PaddingLeft(1).PaddingRight(1)
+			}).
+			Rows(rows...)
+
+		fmt.Println(t)
+		return nil
+	},
+}
+
+var rulesTestCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Evaluate the rules against a recorded snapshot",
+	Long: `Evaluate the start and stop rules against a snapshot of the variables,
+recorded with 'rules snapshot' or written by hand. The rules default to the
+ones in the config.`,
+	Example: `  # Record a snapshot and test the configured rules against it
+  ekz-tesla rules snapshot --car-id 1 --teslamate-api-url http://teslamate-api:8080 > snapshot.json
+  ekz-tesla rules test --snapshot snapshot.json
+
+  # Try a new start rule
+  ekz-tesla rules test --snapshot snapshot.json --start 'car.soc < 50 && hour >= 22'`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := root.GetConfig()
+		if cfg == nil {
+			return fmt.Errorf("configuration not loaded")
+		}
+		if !cmd.Flags().Changed("start") {
+			startRule = cfg.Rules.Start
+		}
+		if !cmd.Flags().Changed("stop") {
+			stopRule = cfg.Rules.Stop
+		}
+		if startRule == "" && stopRule == "" {
+			return fmt.Errorf("no rules configured (set rules.start or rules.stop in the config, or use --start/--stop)")
+		}
+
+		env, err := rules.LoadSnapshot(snapshotPath)
+		if err != nil {
+			return err
+		}
+		set, err := rules.CompileSet(startRule, stopRule)
+		if err != nil {
+			return err
+		}
+
+		var failed bool
+		for _, r := range []struct {
+			name string
+			rule *rules.Rule
+		}{{"start", set.Start}, {"stop", set.Stop}} {
+			if r.rule == nil {
+				continue
+			}
+			result, err := r.rule.Eval(env)
+			if err != nil {
+				fmt.Printf("❌ %s: %v\n", r.name, err)
+				failed = true
+				continue
+			}
+			icon := "⚪"
+			if result {
+				icon = "🟢"
+			}
+			fmt.Printf("%s %s = %v\n   %s\n", icon, r.name, result, r.rule)
+		}
+		if failed {
+			return fmt.Errorf("failed to evaluate the rules")
+		}
+		return nil
+	},
+}
+
+var rulesSnapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Print the current values of the rule variables as JSON",
+	Example: `  # Record a snapshot to test rules against
+  ekz-tesla rules snapshot --car-id 1 --teslamate-api-url http://teslamate-api:8080 > snapshot.json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client := root.GetClient()
+		if client == nil {
+			return fmt.Errorf("EKZ client not initialized")
+		}
+		cfg := root.GetConfig()
+		if cfg == nil {
+			return fmt.Errorf("configuration not loaded")
+		}
+		if err := root.ValidateChargingStationConfig(); err != nil {
+			return fmt.Errorf("invalid charging station config: %w", err)
+		}
+		// The tariff in force as autostart determines it
+		scheduler, err := ekz.NewTariffScheduler(nil, highTariffTimes, cfg.Tariffs)
+		if err != nil {
+			return err
+		}
+
+		car, err := root.NewVehicle(cmd.Context(), carID, teslaMateAPIURL, teslamateapi.WithToken(teslaMateToken))
+		if err != nil {
+			return err
+		}
+		status, err := car.Provider.Status(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("failed to get car status: %w", err)
+		}
+
+		station := cfg.ChargingStation
+		liveData, err := client.GetLiveData(station.BoxId, station.ConnectorId, ekz.ConnectorStatusCharging)
+		if errors.Is(err, ekz.ErrTransactionNotFoundInTable) {
+			liveData = nil
+		} else if err != nil {
+			return fmt.Errorf("failed to get live data: %w", err)
+		}
+
+		now := time.Now()
+		target := maximumCharge
+		if t, err := cfg.ChargeTargets.TargetAt(now); err == nil && t.Percent > 0 {
+			target = t.Percent
+		}
+
+		env := rules.Build(rules.Input{
+			Now:        now,
+			Vehicle:    *status,
+			LiveData:   liveData,
+			Station:    station,
+			HighTariff: scheduler.IsHighTariffTime(now),
+			Tariffs:    cfg.Tariffs,
+			Target:     target,
+		})
+
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(env)
+	},
+}
+
+func init() {
+	rulesTestCmd.Flags().StringVar(&snapshotPath, "snapshot", "", "Snapshot of the variables (JSON)")
+	rulesTestCmd.Flags().StringVar(&startRule, "start", "", "Start rule to test instead of the configured one")
+	rulesTestCmd.Flags().StringVar(&stopRule, "stop", "", "Stop rule to test instead of the configured one")
+	if err := rulesTestCmd.MarkFlagRequired("snapshot"); err != nil {
+		panic(fmt.Sprintf("Failed to mark snapshot flag as required: %v", err))
+	}
+
+	rulesSnapshotCmd.Flags().IntVar(&carID, "car-id", 0, "TeslaMate car ID (default: the only car known to TeslaMateApi)")
+	rulesSnapshotCmd.Flags().StringVar(&teslaMateAPIURL, "teslamate-api-url", "",
+		"TeslaMate API URL (required by the teslamateapi vehicle provider)")
+	rulesSnapshotCmd.Flags().StringVar(&teslaMateToken, "teslamate-api-token", os.Getenv("TESLAMATE_API_TOKEN"),
+		"TeslaMate API token, as configured in API_TOKEN (default: $TESLAMATE_API_TOKEN)")
+	rulesSnapshotCmd.Flags().IntVar(&maximumCharge, "maximum-charge", 90, "Target when no charge target applies")
+	rulesSnapshotCmd.Flags().StringSliceVar(&highTariffTimes, "high-tariff-times", []string{},
+		"High tariff time ranges, as given to autostart (format: 'HH:MM-HH:MM:Mon,Tue,Wed,Thu,Fri')")
+
+	RulesCmd.AddCommand(rulesVariablesCmd)
+	RulesCmd.AddCommand(rulesTestCmd)
+	RulesCmd.AddCommand(rulesSnapshotCmd)
+
+	root.RootCmd.AddCommand(RulesCmd)
+}
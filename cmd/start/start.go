@@ -1,34 +1,73 @@
 package start
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/denysvitali/ekz-tesla/cmd/root"
+	"github.com/denysvitali/ekz-tesla/ekz"
+	"github.com/denysvitali/ekz-tesla/state"
 )
 
 var (
 	boxID       string
 	connectorID int
+	force       bool
+	wait        bool
+	timeout     time.Duration
+	retry       bool
+	kWh         float64
+	forDuration time.Duration
+	maxCost     float64
+	detach      bool
+	at          string
+	dryRun      bool
 )
 
 var StartCmd = &cobra.Command{
 	Use:   "start",
 	Short: "Start charging at a charging station",
 	Long: `Start a charging session at the specified charging station.
-If no box ID or connector ID is provided, uses values from configuration.`,
+If no box ID or connector ID is provided, uses values from configuration.
+
+Before starting, the station state is checked: the command refuses to start
+when the box is offline, you have no permission to use the connector, or the
+connector is in use by another session. Use --force to start anyway.
+
+With --kwh, --for or --max-cost the session is supervised and stopped as soon
+as the charged energy, the elapsed time or the cost reaches the bound. The
+supervision runs in the foreground, or in the running autostart daemon with
+--detach. If our own session is already running, the bounds apply to it.
+
+With --at, the start is queued and executed by the running autostart daemon
+at the given time (see 'queue list').
+
+With --dry-run, the pre-flight checks run and the command prints what it
+would do, without starting a session or queueing anything.`,
 	Example: `  # Start charging using config values
   ekz-tesla start
 
   # Start charging at a specific box and connector
-  ekz-tesla start --box-id CH-EKZ-E001234 --connector-id 1`,
-	RunE: func(cmd *cobra.Command, args []string) error {
-		client := root.GetClient()
-		if client == nil {
-			return fmt.Errorf("EKZ client not initialized")
-		}
+  ekz-tesla start --box-id CH-EKZ-E001234 --connector-id 1
+
+  # Start charging and wait up to 2 minutes until the car draws power, retrying once
+  ekz-tesla start --wait --timeout 2m --retry
+
+  # Give the car 15 kWh, but spend at most CHF 5
+  ekz-tesla start --kwh 15 --max-cost 5
 
+  # Charge for 2 hours, supervised by the running autostart daemon
+  ekz-tesla start --for 2h --detach
+
+  # Start at 22:15 and stop after 15 kWh
+  ekz-tesla start --at 22:15 --kwh 15`,
+	RunE: func(cmd *cobra.Command, args []string) error {
 		cfg := root.GetConfig()
 		if cfg == nil {
 			return fmt.Errorf("configuration not loaded")
@@ -50,28 +89,229 @@ If no box ID or connector ID is provided, uses values from configuration.`,
 			return fmt.Errorf("connector ID is required (use --connector-id or set in config)")
 		}
 
+		limits := ekz.SessionLimits{Energy: kWh, Duration: forDuration, Cost: maxCost}
+		if err := limits.Validate(); err != nil {
+			return err
+		}
+		bounded := !limits.IsZero()
+
+		if at != "" {
+			return queueStart(limits)
+		}
+
+		client := root.GetClient()
+		if client == nil {
+			return fmt.Errorf("EKZ client not initialized")
+		}
+
+		var store *state.Store
+		if detach {
+			if !bounded {
+				return fmt.Errorf("--detach requires --kwh, --for or --max-cost")
+			}
+			store = state.New(state.DefaultPath())
+			st, err := store.Load()
+			if err != nil {
+				return fmt.Errorf("failed to load state: %w", err)
+			}
+			if !st.Daemon.Alive(time.Now()) {
+				return fmt.Errorf("no autostart daemon is running (start 'autostart scheduled' or 'autostart smart', or drop --detach)")
+			}
+		}
+
+		ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer cancel()
+
 		log := root.GetLogger()
+		log.Debugf("Running pre-flight checks for box %s, connector %d", boxID, connectorID)
+
+		preflight, err := client.Preflight(boxID, connectorID)
+		if err != nil {
+			return fmt.Errorf("failed to run pre-flight checks: %w", err)
+		}
+		for _, issue := range preflight.Issues {
+			icon := "⚠️ "
+			if issue.Severity == ekz.PreflightError {
+				icon = "❌"
+			}
+			fmt.Printf("%s %s\n", icon, issue.Message)
+		}
+		if preflight.AlreadyCharging() {
+			fmt.Printf("⚡ Already charging (session %d, %.2f kWh)\n", preflight.Session.TransactionID, preflight.Session.ChargedEnergy)
+			if !bounded {
+				return nil
+			}
+			if dryRun {
+				fmt.Printf("🔍 Dry run: would supervise session %d, stopping at %s\n", preflight.Session.TransactionID, limits)
+				return nil
+			}
+			return superviseBounded(ctx, client, store, preflight.Session, limits)
+		}
+		if err := preflight.Err(); err != nil {
+			if !force {
+				return err
+			}
+			log.Warnf("Ignoring failed pre-flight checks: %v", err)
+		}
+
+		if dryRun {
+			fmt.Printf("🔍 Dry run: would start charging at box %s, connector %d", boxID, connectorID)
+			if bounded {
+				fmt.Printf(", stopping at %s", limits)
+			}
+			fmt.Println()
+			return nil
+		}
+
 		log.Debugf("Starting charge at box %s, connector %d", boxID, connectorID)
 
+		if wait || bounded {
+			liveData, err := startAndWait(ctx, client, boxID, connectorID)
+			if err != nil || !bounded {
+				return err
+			}
+			return superviseBounded(ctx, client, store, liveData, limits)
+		}
+
 		remoteStart, err := client.RemoteStart(boxID, connectorID)
 		if err != nil {
 			return fmt.Errorf("failed to start charging: %w", err)
 		}
 
-		if remoteStart != nil {
-			fmt.Printf("✅ Charging started successfully\n")
-			log.Debugf("Remote start response: %+v", remoteStart)
-		} else {
-			fmt.Printf("✅ Charging command sent\n")
-		}
+		fmt.Printf("✅ Start command accepted (use --wait to verify that the car draws power)\n")
+		log.Debugf("Remote start response: %+v", remoteStart)
 
 		return nil
 	},
 }
 
+// startAndWait starts a session and polls the live data until the car draws power
+func startAndWait(ctx context.Context, client *ekz.Client, boxID string, connectorID int) (*ekz.LiveDataResponse, error) {
+	liveData, err := client.StartAndVerify(ctx, boxID, connectorID, ekz.StartOptions{
+		Timeout: timeout,
+		Retry:   retry,
+		Progress: func(elapsed time.Duration, liveData *ekz.LiveDataResponse) {
+			if liveData == nil {
+				fmt.Printf("⏳ Waiting for the session to appear (%s)\n", elapsed.Truncate(time.Second))
+				return
+			}
+			fmt.Printf("⏳ %s, %.2f kW (%s)\n", liveData.Status, liveData.Power, elapsed.Truncate(time.Second))
+		},
+	})
+	switch {
+	case errors.Is(err, ekz.ErrStartRejected):
+		return nil, fmt.Errorf("the station rejected the start: %w", err)
+	case errors.Is(err, ekz.ErrNotDrawing):
+		return nil, fmt.Errorf("the station accepted the start, but the car is not charging: %w", err)
+	case err != nil:
+		return nil, fmt.Errorf("failed to start charging: %w", err)
+	}
+
+	fmt.Printf("✅ Charging started, the car draws %.2f kW\n", liveData.Power)
+	return liveData, nil
+}
+
+// queueStart queues the start for the daemon
+func queueStart(limits ekz.SessionLimits) error {
+	when, err := state.ParseAt(at, time.Now())
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		fmt.Printf("🔍 Dry run: would queue %s\n", state.Action{
+			Kind: state.ActionStart, At: when, ChargeBoxID: boxID, ConnectorID: connectorID, Limits: limits,
+		})
+		return nil
+	}
+
+	action, alive, err := state.New(state.DefaultPath()).Enqueue(state.Action{
+		Kind:        state.ActionStart,
+		At:          when,
+		ChargeBoxID: boxID,
+		ConnectorID: connectorID,
+		Limits:      limits,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to queue the start: %w", err)
+	}
+
+	fmt.Printf("📋 Queued action %d: %s\n", action.ID, action)
+	if !alive {
+		fmt.Printf("⚠️  No autostart daemon is running, the action only runs once one is started\n")
+	}
+	return nil
+}
+
+// superviseBounded stops the session once one of the limits is reached. With a
+// store, the session is handed over to the running daemon instead.
+func superviseBounded(ctx context.Context, client *ekz.Client, store *state.Store, liveData *ekz.LiveDataResponse, limits ekz.SessionLimits) error {
+	if store != nil {
+		err := store.Update(func(st *state.State) error {
+			st.SetSession(state.Session{
+				ChargeBoxID:   boxID,
+				ConnectorID:   connectorID,
+				TransactionID: liveData.TransactionID,
+				Limits:        limits,
+				Requested:     time.Now(),
+			})
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to hand the session over to the daemon: %w", err)
+		}
+		fmt.Printf("📋 Session %d handed over to the daemon, stopping at %s\n", liveData.TransactionID, limits)
+		return nil
+	}
+
+	fmt.Printf("⏱️  Supervising session %d, stopping at %s (Ctrl+C to leave it running)\n", liveData.TransactionID, limits)
+	tariffs := root.GetConfig().Tariffs
+	supervisor := ekz.NewSessionSupervisor(client, boxID, connectorID)
+	for _, condition := range limits.Conditions(tariffs) {
+		supervisor.AddStopCondition(condition)
+	}
+	supervisor.SetProgress(func(liveData *ekz.LiveDataResponse) {
+		fmt.Printf("⚡ %.2f kW, %.2f kWh, CHF %.2f (%s)\n", liveData.Power, liveData.ChargedEnergy, liveData.Cost(tariffs),
+			time.Since(liveData.StartTime()).Truncate(time.Second))
+	})
+
+	result, err := supervisor.Run(ctx)
+	if errors.Is(err, context.Canceled) {
+		fmt.Printf("⚠️  Supervision interrupted, the session keeps running\n")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to supervise the session: %w", err)
+	}
+
+	if result.Stopped {
+		fmt.Printf("✅ Charging stopped: %s\n", result.Reason)
+	} else {
+		fmt.Printf("🔌 Session ended before reaching the limits\n")
+	}
+	if result.LiveData != nil {
+		summary := result.LiveData.Summary(tariffs)
+		cost := fmt.Sprintf("CHF %.2f", summary.TotalCost)
+		if summary.CostEstimated {
+			cost += " (estimate)"
+		}
+		fmt.Printf("   %.2f kWh in %s, %s\n", summary.Energy, summary.Duration.Truncate(time.Second), cost)
+	}
+	return nil
+}
+
 func init() {
 	StartCmd.Flags().StringVar(&boxID, "box-id", "", "Charging station box ID")
 	StartCmd.Flags().IntVar(&connectorID, "connector-id", 0, "Connector ID")
+	StartCmd.Flags().BoolVar(&force, "force", false, "Start even if the pre-flight checks fail")
+	StartCmd.Flags().BoolVar(&wait, "wait", false, "Wait until the car draws power")
+	StartCmd.Flags().DurationVar(&timeout, "timeout", ekz.DefaultStartTimeout, "How long to wait for power with --wait")
+	StartCmd.Flags().BoolVar(&retry, "retry", false, "Retry the remote start once if it fails (with --wait)")
+	StartCmd.Flags().Float64Var(&kWh, "kwh", 0, "Stop the session once this much energy (kWh) is charged")
+	StartCmd.Flags().DurationVar(&forDuration, "for", 0, "Stop the session after this duration")
+	StartCmd.Flags().Float64Var(&maxCost, "max-cost", 0, "Stop the session once it costs this much (CHF)")
+	StartCmd.Flags().BoolVar(&detach, "detach", false, "Hand the bounded session over to the running autostart daemon")
+	StartCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Run the pre-flight checks and print what would be done, without starting")
+	StartCmd.Flags().StringVar(&at, "at", "", "Queue the start for the daemon at this time (HH:MM, YYYY-MM-DD HH:MM or RFC 3339)")
 
 	root.RootCmd.AddCommand(StartCmd)
 }
\ No newline at end of file
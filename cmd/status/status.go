@@ -0,0 +1,194 @@
+package status
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/table"
+	"github.com/spf13/cobra"
+
+	"github.com/denysvitali/ekz-tesla/cmd/root"
+	"github.com/denysvitali/ekz-tesla/state"
+)
+
+var jsonOutput bool
+
+var StatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the state of the autostart daemon",
+	Long: `Show whether the autostart daemon is running, the start attempts per station
+(backoff after failures, and whether autostart gave up for the current tariff
+window), the turns of the cars sharing a station, the sessions supervised by the
+daemon and the queued actions.`,
+	Example: `  # Show the daemon state
+  ekz-tesla status
+
+  # Print the state as JSON
+  ekz-tesla status --json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		st, err := state.New(state.DefaultPath()).Load()
+		if err != nil {
+			return fmt.Errorf("failed to load state: %w", err)
+		}
+
+		now := time.Now()
+		if jsonOutput {
+			return printJSON(st, now)
+		}
+
+		if st.Daemon.Alive(now) {
+			fmt.Printf("🟢 Daemon running (PID %d, last heartbeat %s ago)\n",
+				st.Daemon.PID, now.Sub(st.Daemon.Heartbeat).Truncate(time.Second))
+		} else {
+			fmt.Println("⚪ No autostart daemon is running")
+		}
+
+		if len(st.Stations) > 0 {
+			fmt.Println()
+			printStations(st.Stations, now)
+		}
+		for _, key := range sortedKeys(st.Stations) {
+			if turns := st.Stations[key].Turns; turns != nil && len(turns.Turns) > 0 && now.Before(turns.Turns[len(turns.Turns)-1].End) {
+				fmt.Printf("🔀 Cars take turns on %s:\n", key)
+				printTurns(turns, now)
+			}
+		}
+		for _, session := range st.Sessions {
+			fmt.Printf("🔌 Supervising %s\n", session)
+		}
+		if next, ok := st.NextAction(); ok {
+			fmt.Printf("📋 %d queued action(s), next: %s (see 'queue list')\n", len(st.Queue), next)
+		}
+		if failed := st.FailedActions(); len(failed) > 0 {
+			fmt.Printf("⚠️  %d queued action(s) failed (see 'queue list')\n", len(failed))
+		}
+		return nil
+	},
+}
+
+// stationStatus is the JSON representation of a station state
+type stationStatus struct {
+	Station string             `json:"station"`
+	Phase   state.StationPhase `json:"phase"`
+	state.StationState
+}
+
+func printJSON(st *state.State, now time.Time) error {
+	output := struct {
+		DaemonAlive bool            `json:"daemon_alive"`
+		Daemon      *state.Daemon   `json:"daemon,omitempty"`
+		Stations    []stationStatus `json:"stations"`
+		Sessions    []state.Session `json:"sessions"`
+		Queue       []state.Action  `json:"queue"`
+	}{
+		DaemonAlive: st.Daemon.Alive(now),
+		Daemon:      st.Daemon,
+		Stations:    []stationStatus{},
+		Sessions:    st.Sessions,
+		Queue:       st.Queue,
+	}
+	for _, key := range sortedKeys(st.Stations) {
+		station := st.Stations[key]
+		output.Stations = append(output.Stations, stationStatus{Station: key, Phase: station.Phase(now), StationState: *station})
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(output)
+}
+
+// printStations prints the station states using lipgloss's table
+func printStations(stations map[string]*state.StationState, now time.Time) {
+	var rows [][]string
+	for _, key := range sortedKeys(stations) {
+		station := stations[key]
+		next, lastError := "-", "-"
+		if station.Phase(now) == state.StationBackoff {
+			next = station.NextAttempt.Local().Format("2006-01-02 15:04:05")
+		}
+		if station.LastError != "" {
+			lastError = station.LastError
+		}
+		rows = append(rows, []string{
+			key,
+			string(station.Phase(now)),
+			strconv.Itoa(station.WindowAttempts),
+			strconv.Itoa(station.Failures),
+			next,
+			lastError,
+		})
+	}
+
+	t := table.New().
+		Border(lipgloss.NormalBorder()).
+		BorderStyle(lipgloss.NewStyle().Foreground(lipgloss.Color("99"))).
+		Headers("STATION", "PHASE", "ATTEMPTS", "FAILURES", "NEXT ATTEMPT", "LAST ERROR").
+		StyleFunc(func(row, col int) lipgloss.Style {
+			if row == table.HeaderRow {
+				return lipgloss.NewStyle().Bold(true).PaddingLeft(1).PaddingRight(1)
+			}
+			return lipgloss.NewStyle().PaddingLeft(1).PaddingRight(1)
+		}).
+		Rows(rows...)
+
+	fmt.Println(t)
+}
+
+// printTurns prints the turns of the cars sharing a station using lipgloss's table,
+// marking the current one
+func printTurns(turns *state.TurnPlan, now time.Time) {
+	current, _ := turns.At(now)
+	var rows [][]string
+	for i := range turns.Turns {
+		turn := &turns.Turns[i]
+		car := strconv.Itoa(turn.Car)
+		if turn.Name != "" {
+			car = fmt.Sprintf("%d (%s)", turn.Car, turn.Name)
+		}
+		marker := ""
+		if turn == current {
+			marker = "▶"
+		}
+		rows = append(rows, []string{
+			marker,
+			car,
+			turn.Start.Local().Format("2006-01-02 15:04"),
+			turn.End.Local().Format("2006-01-02 15:04"),
+			fmt.Sprintf("%.1f kWh", turn.Need),
+		})
+	}
+
+	t := table.New().
+		Border(lipgloss.NormalBorder()).
+		BorderStyle(lipgloss.NewStyle().Foreground(lipgloss.Color("99"))).
+		Headers("", "CAR", "FROM", "TO", "NEED").
+		StyleFunc(func(row, col int) lipgloss.Style {
+			if row == table.HeaderRow {
+				return lipgloss.NewStyle().Bold(true).PaddingLeft(1).PaddingRight(1)
+			}
+			return lipgloss.NewStyle().PaddingLeft(1).PaddingRight(1)
+		}).
+		Rows(rows...)
+
+	fmt.Println(t)
+}
+
+func sortedKeys(stations map[string]*state.StationState) []string {
+	keys := make([]string, 0, len(stations))
+	for key := range stations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func init() {
+	StatusCmd.Flags().BoolVar(&jsonOutput, "json", false, "Print the state as JSON")
+
+	root.RootCmd.AddCommand(StatusCmd)
+}
@@ -1,34 +1,61 @@
 package stop
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/table"
 	"github.com/spf13/cobra"
 
 	"github.com/denysvitali/ekz-tesla/cmd/root"
+	"github.com/denysvitali/ekz-tesla/ekz"
+	"github.com/denysvitali/ekz-tesla/state"
 )
 
 var (
 	boxID       string
 	connectorID int
+	noWait      bool
+	timeout     time.Duration
+	retry       bool
+	jsonOutput  bool
+	at          string
+	dryRun      bool
 )
 
 var StopCmd = &cobra.Command{
 	Use:   "stop",
 	Short: "Stop charging at a charging station",
 	Long: `Stop an active charging session at the specified charging station.
-If no box ID or connector ID is provided, uses values from configuration.`,
+If no box ID or connector ID is provided, uses values from configuration.
+
+The command waits until the session is closed, then prints a summary with
+the duration, energy and cost of the session.
+
+With --at, the stop is queued and executed by the running autostart daemon
+at the given time (see 'queue list').
+
+With --dry-run, the command prints the session it would stop, without
+stopping it or queueing anything.`,
 	Example: `  # Stop charging using config values
   ekz-tesla stop
 
   # Stop charging at a specific box and connector
-  ekz-tesla stop --box-id CH-EKZ-E001234 --connector-id 1`,
-	RunE: func(cmd *cobra.Command, args []string) error {
-		client := root.GetClient()
-		if client == nil {
-			return fmt.Errorf("EKZ client not initialized")
-		}
+  ekz-tesla stop --box-id CH-EKZ-E001234 --connector-id 1
 
+  # Stop charging and print the session summary as JSON
+  ekz-tesla stop --json
+
+  # Stop charging tomorrow at 05:30
+  ekz-tesla stop --at 05:30`,
+	RunE: func(cmd *cobra.Command, args []string) error {
 		cfg := root.GetConfig()
 		if cfg == nil {
 			return fmt.Errorf("configuration not loaded")
@@ -50,28 +77,151 @@ If no box ID or connector ID is provided, uses values from configuration.`,
 			return fmt.Errorf("connector ID is required (use --connector-id or set in config)")
 		}
 
+		if at != "" {
+			return queueStop()
+		}
+
+		client := root.GetClient()
+		if client == nil {
+			return fmt.Errorf("EKZ client not initialized")
+		}
+
+		if dryRun {
+			liveData, err := client.GetLiveData(boxID, connectorID, ekz.ConnectorStatusCharging)
+			if errors.Is(err, ekz.ErrTransactionNotFoundInTable) {
+				fmt.Printf("🔍 Dry run: no active session on box %s, connector %d, nothing to stop\n", boxID, connectorID)
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("failed to get live data: %w", err)
+			}
+			fmt.Printf("🔍 Dry run: would stop session %d (%s, %.2f kWh, CHF %.2f)\n",
+				liveData.TransactionID, liveData.Status, liveData.ChargedEnergy, liveData.Cost(cfg.Tariffs))
+			return nil
+		}
+
 		log := root.GetLogger()
 		log.Debugf("Stopping charge at box %s, connector %d", boxID, connectorID)
 
-		remoteStop, err := client.RemoteStop(boxID, connectorID)
-		if err != nil {
-			return fmt.Errorf("failed to stop charging: %w", err)
+		// A daemon supervising the session must not restart it once it ended
+		if err := state.New(state.DefaultPath()).RequestStop(boxID, connectorID); err != nil {
+			log.Warnf("Failed to record the stop in the state: %v", err)
 		}
 
-		if remoteStop != nil {
-			fmt.Printf("✅ Charging stopped successfully\n")
-			log.Debugf("Remote stop response: %+v", remoteStop)
-		} else {
+		if noWait {
+			remoteStop, err := client.RemoteStop(boxID, connectorID)
+			if err != nil {
+				return fmt.Errorf("failed to stop charging: %w", err)
+			}
 			fmt.Printf("✅ Stop command sent\n")
+			log.Debugf("Remote stop response: %+v", remoteStop)
+			return nil
+		}
+
+		ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer cancel()
+
+		final, err := client.StopAndVerify(ctx, boxID, connectorID, ekz.StopOptions{
+			Timeout: timeout,
+			Retry:   retry,
+		})
+		switch {
+		case errors.Is(err, ekz.ErrNoActiveSession):
+			return fmt.Errorf("no active session on box %s, connector %d", boxID, connectorID)
+		case errors.Is(err, ekz.ErrStopNotConfirmed):
+			return fmt.Errorf("the station accepted the stop, but the session keeps running: %w", err)
+		case err != nil:
+			return fmt.Errorf("failed to stop charging: %w", err)
+		}
+
+		summary := final.Summary(cfg.Tariffs)
+		if jsonOutput {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(summary)
 		}
 
+		fmt.Printf("✅ Charging stopped\n")
+		printSummary(summary)
 		return nil
 	},
 }
 
+// queueStop queues the stop for the daemon
+func queueStop() error {
+	when, err := state.ParseAt(at, time.Now())
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		fmt.Printf("🔍 Dry run: would queue %s\n", state.Action{
+			Kind: state.ActionStop, At: when, ChargeBoxID: boxID, ConnectorID: connectorID,
+		})
+		return nil
+	}
+
+	action, alive, err := state.New(state.DefaultPath()).Enqueue(state.Action{
+		Kind:        state.ActionStop,
+		At:          when,
+		ChargeBoxID: boxID,
+		ConnectorID: connectorID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to queue the stop: %w", err)
+	}
+
+	fmt.Printf("📋 Queued action %d: %s\n", action.ID, action)
+	if !alive {
+		fmt.Printf("⚠️  No autostart daemon is running, the action only runs once one is started\n")
+	}
+	return nil
+}
+
+// totalCost formats the total cost of the session, marking an estimate
+func totalCost(summary ekz.SessionSummary) string {
+	if summary.CostEstimated {
+		return fmt.Sprintf("CHF %.2f (estimate)", summary.TotalCost)
+	}
+	return fmt.Sprintf("CHF %.2f", summary.TotalCost)
+}
+
+// printSummary prints the session summary using lipgloss's table
+func printSummary(summary ekz.SessionSummary) {
+	rows := [][]string{
+		{"Session", fmt.Sprintf("%d", summary.TransactionID)},
+		{"Start", summary.Start.Local().Format("2006-01-02 15:04:05")},
+		{"Stop", summary.Stop.Local().Format("2006-01-02 15:04:05")},
+		{"Duration", summary.Duration.Truncate(time.Second).String()},
+		{"Energy", fmt.Sprintf("%.2f kWh", summary.Energy)},
+		{"High tariff", fmt.Sprintf("%.2f kWh, CHF %.2f", summary.HighTariffEnergy, summary.HighTariffCost)},
+		{"Low tariff", fmt.Sprintf("%.2f kWh, CHF %.2f", summary.LowTariffEnergy, summary.LowTariffCost)},
+		{"Total cost", totalCost(summary)},
+	}
+
+	t := table.New().
+		Border(lipgloss.RoundedBorder()).
+		BorderStyle(lipgloss.NewStyle().Foreground(lipgloss.Color("99"))).
+		StyleFunc(func(row, col int) lipgloss.Style {
+			baseStyle := lipgloss.NewStyle().PaddingLeft(1).PaddingRight(1)
+			if col == 0 {
+				return baseStyle.Foreground(lipgloss.Color("241"))
+			}
+			return baseStyle.Bold(true)
+		}).
+		Rows(rows...)
+
+	fmt.Println(t)
+}
+
 func init() {
 	StopCmd.Flags().StringVar(&boxID, "box-id", "", "Charging station box ID")
 	StopCmd.Flags().IntVar(&connectorID, "connector-id", 0, "Connector ID")
+	StopCmd.Flags().BoolVar(&noWait, "no-wait", false, "Send the stop command without waiting for the session to close")
+	StopCmd.Flags().DurationVar(&timeout, "timeout", ekz.DefaultStopTimeout, "How long to wait for the session to close")
+	StopCmd.Flags().BoolVar(&retry, "retry", false, "Send the stop command once more if the session keeps running")
+	StopCmd.Flags().BoolVar(&jsonOutput, "json", false, "Print the session summary as JSON")
+	StopCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the session that would be stopped, without stopping it")
+	StopCmd.Flags().StringVar(&at, "at", "", "Queue the stop for the daemon at this time (HH:MM, YYYY-MM-DD HH:MM or RFC 3339)")
 
 	root.RootCmd.AddCommand(StopCmd)
 }
\ No newline at end of file
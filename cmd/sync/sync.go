@@ -0,0 +1,336 @@
+package sync
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/table"
+	"github.com/spf13/cobra"
+
+	"github.com/denysvitali/ekz-tesla/cmd/root"
+	"github.com/denysvitali/ekz-tesla/ekz"
+	"github.com/denysvitali/ekz-tesla/history"
+	"github.com/denysvitali/ekz-tesla/teslamateapi"
+	"github.com/denysvitali/ekz-tesla/teslamatedb"
+)
+
+var (
+	dryRun          bool
+	days            int
+	carID           int
+	teslaMateAPIURL string
+	teslaMateToken  string
+	timeTolerance   time.Duration
+	energyTolerance float64
+)
+
+var SyncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Synchronize EKZ data with other services",
+}
+
+var syncTeslaMateCostsCmd = &cobra.Command{
+	Use:   "teslamate-costs",
+	Short: "Write the cost of EKZ sessions into TeslaMate charging processes",
+	Long: `Match the EKZ sessions to the charging processes recorded by TeslaMate, by time
+and energy, and write the actual EKZ cost into TeslaMate.
+
+The sessions are read from the history recorded by autostart, merged with the last
+session of each connector reported by the EKZ backend. Sessions the backend hasn't
+priced yet are shown with a cost estimated from the configured tariffs, but not
+written until the backend reports the actual cost.
+
+When the car paused charging, TeslaMate records several processes for one session;
+the cost of the session is then split between them by energy. Processes whose cost
+is already right are left alone, so the command can be run repeatedly, e.g. daily.
+
+The charging processes are read from the TeslaMate database (teslamate_db in the
+config) or, without it, from TeslaMateApi. Costs can only be written to the
+database, as TeslaMateApi can't update them. Use --dry-run to review the changes.`,
+	Example: `  # Review the changes of the last 30 days
+  ekz-tesla sync teslamate-costs --dry-run
+
+  # Write the costs of the last 90 days
+  ekz-tesla sync teslamate-costs --days 90`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := root.GetConfig()
+		if cfg == nil {
+			return fmt.Errorf("configuration not loaded")
+		}
+		if cfg.TeslaMateDB.DSN == "" && !dryRun {
+			return fmt.Errorf("writing costs requires teslamate_db in the config, TeslaMateApi can't update them (use --dry-run to preview)")
+		}
+		if cfg.TeslaMateDB.DSN == "" && teslaMateAPIURL == "" {
+			return fmt.Errorf("either teslamate_db in the config or --teslamate-api-url is required")
+		}
+
+		sessions, err := history.New(history.DefaultPath()).List()
+		if err != nil {
+			return fmt.Errorf("failed to read the session history: %w", err)
+		}
+		sessions = mergeSessions(sessions, backendSessions(cfg.Tariffs))
+		sessions = filterSessions(sessions, time.Now())
+		if len(sessions) == 0 {
+			fmt.Println("No EKZ sessions to synchronize.")
+			return nil
+		}
+		from, to := sessions[0].Start, sessions[0].Stop
+		for _, session := range sessions {
+			if session.Start.Before(from) {
+				from = session.Start
+			}
+			if session.Stop.After(to) {
+				to = session.Stop
+			}
+		}
+		from, to = from.Add(-timeTolerance), to.Add(timeTolerance)
+
+		var db *teslamatedb.DB
+		if cfg.TeslaMateDB.DSN != "" {
+			if dryRun {
+				db, err = teslamatedb.Open(cfg.TeslaMateDB.DSN)
+			} else {
+				db, err = teslamatedb.OpenWritable(cfg.TeslaMateDB.DSN)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to open TeslaMate database: %w", err)
+			}
+			defer func() { _ = db.Close() }()
+		}
+
+		var charges []teslamatedb.ChargingProcess
+		if db != nil {
+			charges, err = db.ChargingProcesses(cmd.Context(), carID, from, to)
+		} else {
+			charges, err = apiChargingProcesses(cmd, from, to)
+		}
+		if err != nil {
+			return err
+		}
+
+		matches := teslamatedb.MatchSessions(sessions, charges, teslamatedb.MatchOptions{
+			TimeTolerance:   timeTolerance,
+			EnergyTolerance: energyTolerance,
+		})
+		var updates []teslamatedb.CostUpdate
+		for _, match := range matches {
+			// An estimate isn't written, the session is synchronized once the backend priced it
+			if !match.Session.CostEstimated {
+				updates = append(updates, match.Updates()...)
+			}
+		}
+		printMatches(matches)
+
+		if len(updates) == 0 {
+			fmt.Println("✅ TeslaMate costs are up to date")
+			return nil
+		}
+		if dryRun {
+			fmt.Printf("🔍 Dry run: %d charging processes would be updated\n", len(updates))
+			return nil
+		}
+		for _, update := range updates {
+			if err := db.SetChargingProcessCost(cmd.Context(), update.Charge.ID, update.Cost); err != nil {
+				return err
+			}
+		}
+		fmt.Printf("✅ Updated the cost of %d charging processes\n", len(updates))
+		return nil
+	},
+}
+
+// backendSessions returns the last finished session of each connector of the account, as
+// reported by the EKZ backend. Sessions without a total cost yet are estimated from the tariffs.
+func backendSessions(tariffs ekz.Tariffs) []history.Session {
+	log := root.GetLogger()
+	client := root.GetClient()
+	if client == nil {
+		return nil
+	}
+	stations, err := client.GetUserChargingStations()
+	if err != nil {
+		log.Warnf("Failed to get the charging stations, using the local history only: %v", err)
+		return nil
+	}
+
+	var sessions []history.Session
+	for _, station := range stations {
+		for _, box := range station.ChargeBoxes {
+			for _, conn := range box.Connectors {
+				liveData, err := client.GetLiveData(box.ChargeBoxID, conn.ConnectorID, ekz.ConnectorStatusAvailable)
+				if errors.Is(err, ekz.ErrTransactionNotFoundInTable) {
+					continue
+				}
+				if err != nil {
+					log.Warnf("Failed to get the live data of box %s, connector %d: %v", box.ChargeBoxID, conn.ConnectorID, err)
+					continue
+				}
+				if !liveData.IsFinished() {
+					continue
+				}
+				summary := liveData.Summary(tariffs)
+				sessions = append(sessions, history.Session{
+					ChargeBoxID:      box.ChargeBoxID,
+					ConnectorID:      conn.ConnectorID,
+					TransactionID:    liveData.TransactionID,
+					Start:            summary.Start,
+					Stop:             summary.Stop,
+					Energy:           summary.Energy,
+					HighTariffEnergy: summary.HighTariffEnergy,
+					LowTariffEnergy:  summary.LowTariffEnergy,
+					Cost:             summary.TotalCost,
+					CostEstimated:    summary.CostEstimated,
+				})
+			}
+		}
+	}
+	return sessions
+}
+
+// mergeSessions adds the backend sessions missing from the local history, oldest first.
+// The local history wins, it knows the car of autostart sessions.
+func mergeSessions(local, backend []history.Session) []history.Session {
+	known := map[int]bool{}
+	for _, session := range local {
+		known[session.TransactionID] = true
+	}
+	sessions := append([]history.Session(nil), local...)
+	for _, session := range backend {
+		if !known[session.TransactionID] {
+			sessions = append(sessions, session)
+		}
+	}
+	sort.SliceStable(sessions, func(i, j int) bool { return sessions[i].Start.Before(sessions[j].Start) })
+	return sessions
+}
+
+// filterSessions returns the sessions of the selected car that stopped within --days
+func filterSessions(sessions []history.Session, now time.Time) []history.Session {
+	var filtered []history.Session
+	for _, session := range sessions {
+		if days > 0 && session.Stop.Before(now.AddDate(0, 0, -days)) {
+			continue
+		}
+		if carID != 0 && session.CarID != 0 && session.CarID != carID {
+			continue
+		}
+		filtered = append(filtered, session)
+	}
+	return filtered
+}
+
+// apiChargingProcesses reads the charges of the car from TeslaMateApi
+func apiChargingProcesses(cmd *cobra.Command, from, to time.Time) ([]teslamatedb.ChargingProcess, error) {
+	client, err := teslamateapi.New(strings.TrimSuffix(teslaMateAPIURL, "/"), teslamateapi.WithToken(teslaMateToken))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create TeslaMate API client: %w", err)
+	}
+	id := carID
+	if id == 0 {
+		if id, err = client.DiscoverCarID(cmd.Context()); err != nil {
+			return nil, fmt.Errorf("failed to discover the car, use --car-id: %w", err)
+		}
+	}
+	charges, err := client.ListCharges(cmd.Context(), id, &teslamateapi.ListOptions{StartDate: from, EndDate: to})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list TeslaMate charges: %w", err)
+	}
+
+	processes := make([]teslamatedb.ChargingProcess, 0, len(charges))
+	for _, charge := range charges {
+		process := teslamatedb.ChargingProcess{
+			ID:                charge.ChargeID,
+			CarID:             id,
+			Start:             charge.StartDate,
+			End:               charge.EndDate,
+			ChargeEnergyAdded: charge.ChargeEnergyAdded,
+			ChargeEnergyUsed:  charge.ChargeEnergyUsed,
+			StartBatteryLevel: charge.BatteryDetails.StartBatteryLevel,
+			EndBatteryLevel:   charge.BatteryDetails.EndBatteryLevel,
+		}
+		// TeslaMateApi reports a missing cost as 0
+		if charge.Cost != 0 {
+			cost := charge.Cost
+			process.Cost = &cost
+		}
+		processes = append(processes, process)
+	}
+	return processes, nil
+}
+
+// printMatches prints the sessions with their charging processes and the cost changes
+func printMatches(matches []teslamatedb.CostMatch) {
+	var rows [][]string
+	for _, match := range matches {
+		session := match.Session
+		cost := fmt.Sprintf("%.2f CHF", session.Cost)
+		if session.CostEstimated {
+			cost = fmt.Sprintf("~%.2f CHF (estimate)", session.Cost)
+		}
+		sessionCols := []string{
+			session.Start.Local().Format("2006-01-02 15:04"),
+			fmt.Sprintf("%.2f kWh", session.Energy),
+			cost,
+		}
+		if !match.Matched() {
+			rows = append(rows, append(sessionCols, "-", "-", match.Reason))
+			continue
+		}
+		changes := map[int]float64{}
+		for _, update := range match.Updates() {
+			changes[update.Charge.ID] = update.Cost
+		}
+		for _, charge := range match.Charges {
+			change := "unchanged"
+			if cost, ok := changes[charge.ID]; ok {
+				current := "-"
+				if charge.Cost != nil {
+					current = fmt.Sprintf("%.2f", *charge.Cost)
+				}
+				change = fmt.Sprintf("%s → %.2f CHF", current, cost)
+				if session.CostEstimated {
+					change = "not written, the cost is an estimate"
+				}
+			}
+			rows = append(rows, append(sessionCols, strconv.Itoa(charge.ID), fmt.Sprintf("%.2f kWh", charge.ChargeEnergyAdded), change))
+		}
+	}
+
+	t := table.New().
+		Border(lipgloss.NormalBorder()).
+		BorderStyle(lipgloss.NewStyle().Foreground(lipgloss.Color("99"))).
+		Headers("EKZ SESSION", "ENERGY", "COST", "TESLAMATE", "ADDED", "COST CHANGE").
+		StyleFunc(func(row, col int) lipgloss.Style {
+			if row == table.HeaderRow {
+				return lipgloss.NewStyle().Bold(true).PaddingLeft(1).PaddingRight(1)
+			}
+			return lipgloss.NewStyle().PaddingLeft(1).PaddingRight(1)
+		}).
+		Rows(rows...)
+
+	fmt.Println(t)
+}
+
+func init() {
+	syncTeslaMateCostsCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show the changes without writing them")
+	syncTeslaMateCostsCmd.Flags().IntVar(&days, "days", 30, "Synchronize the sessions of the last days (0: all)")
+	syncTeslaMateCostsCmd.Flags().IntVar(&carID, "car-id", 0, "TeslaMate car ID (default: all cars, or the only car known to TeslaMateApi)")
+	syncTeslaMateCostsCmd.Flags().StringVar(&teslaMateAPIURL, "teslamate-api-url", "",
+		"TeslaMate API URL, to read the charges without teslamate_db")
+	syncTeslaMateCostsCmd.Flags().StringVar(&teslaMateToken, "teslamate-api-token", os.Getenv("TESLAMATE_API_TOKEN"),
+		"TeslaMate API token, as configured in API_TOKEN (default: $TESLAMATE_API_TOKEN)")
+	syncTeslaMateCostsCmd.Flags().DurationVar(&timeTolerance, "time-tolerance", teslamatedb.DefaultMatchOptions.TimeTolerance,
+		"Time TeslaMate charges may start before or end after an EKZ session")
+	syncTeslaMateCostsCmd.Flags().Float64Var(&energyTolerance, "energy-tolerance", teslamatedb.DefaultMatchOptions.EnergyTolerance,
+		"Accepted relative difference between the EKZ and TeslaMate energy")
+
+	SyncCmd.AddCommand(syncTeslaMateCostsCmd)
+
+	root.RootCmd.AddCommand(SyncCmd)
+}
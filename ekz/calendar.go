@@ -0,0 +1,122 @@
+package ekz
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// CalendarEvent is an event read from an iCalendar (.ics) file
+type CalendarEvent struct {
+	Summary string
+	Start   time.Time
+}
+
+// LoadICS reads the events of a local iCalendar file
+func LoadICS(path string) ([]CalendarEvent, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	return ParseICS(f)
+}
+
+// ParseICS parses the VEVENT entries of an iCalendar stream.
+// Only SUMMARY and DTSTART are read, recurrence rules are not expanded.
+func ParseICS(r io.Reader) ([]CalendarEvent, error) {
+	lines, err := unfoldICSLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []CalendarEvent
+	var current *CalendarEvent
+	for _, line := range lines {
+		name, params, value, ok := splitICSLine(line)
+		if !ok {
+			continue
+		}
+
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			current = &CalendarEvent{}
+		case name == "END" && value == "VEVENT":
+			if current != nil && !current.Start.IsZero() {
+				events = append(events, *current)
+			}
+			current = nil
+		case current == nil:
+			continue
+		case name == "SUMMARY":
+			current.Summary = unescapeICSText(value)
+		case name == "DTSTART":
+			start, err := parseICSTime(params, value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid DTSTART %q: %w", value, err)
+			}
+			current.Start = start
+		}
+	}
+
+	return events, nil
+}
+
+// unfoldICSLines joins continuation lines (starting with a space or tab) as per RFC 5545
+func unfoldICSLines(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// splitICSLine splits "NAME;PARAM=VALUE:value" into its parts
+func splitICSLine(line string) (name string, params map[string]string, value string, ok bool) {
+	colon := strings.Index(line, ":")
+	if colon < 0 {
+		return "", nil, "", false
+	}
+
+	parts := strings.Split(line[:colon], ";")
+	name = strings.ToUpper(parts[0])
+	params = make(map[string]string)
+	for _, p := range parts[1:] {
+		if k, v, found := strings.Cut(p, "="); found {
+			params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+		}
+	}
+	return name, params, line[colon+1:], true
+}
+
+func parseICSTime(params map[string]string, value string) (time.Time, error) {
+	if params["VALUE"] == "DATE" {
+		return time.ParseInLocation("20060102", value, time.Local)
+	}
+	if strings.HasSuffix(value, "Z") {
+		return time.Parse("20060102T150405Z", value)
+	}
+
+	loc := time.Local
+	if tzid, ok := params["TZID"]; ok {
+		var err error
+		loc, err = time.LoadLocation(tzid)
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+	return time.ParseInLocation("20060102T150405", value, loc)
+}
+
+func unescapeICSText(s string) string {
+	return strings.NewReplacer(`\n`, " ", `\N`, " ", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(s)
+}
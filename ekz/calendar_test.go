@@ -0,0 +1,49 @@
+package ekz
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testCalendar = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Road trip\\, Milan\r\n" +
+	"DTSTART:20251018T050000Z\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Dentist appointment with a very long summary that is folded\r\n" +
+	"  onto the next line\r\n" +
+	"DTSTART;TZID=Europe/Zurich:20251020T080000\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Holiday\r\n" +
+	"DTSTART;VALUE=DATE:20251224\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParseICS(t *testing.T) {
+	events, err := ParseICS(strings.NewReader(testCalendar))
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+
+	assert.Equal(t, "Road trip, Milan", events[0].Summary)
+	assert.True(t, events[0].Start.Equal(time.Date(2025, 10, 18, 5, 0, 0, 0, time.UTC)))
+
+	assert.Equal(t, "Dentist appointment with a very long summary that is folded onto the next line", events[1].Summary)
+	zurich, err := time.LoadLocation("Europe/Zurich")
+	require.NoError(t, err)
+	assert.True(t, events[1].Start.Equal(time.Date(2025, 10, 20, 8, 0, 0, 0, zurich)))
+
+	assert.Equal(t, "Holiday", events[2].Summary)
+	assert.Equal(t, time.Date(2025, 12, 24, 0, 0, 0, 0, time.Local), events[2].Start)
+}
+
+func TestParseICS_InvalidStart(t *testing.T) {
+	_, err := ParseICS(strings.NewReader("BEGIN:VEVENT\nDTSTART:tomorrow\nEND:VEVENT\n"))
+	assert.Error(t, err)
+}
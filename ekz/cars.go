@@ -0,0 +1,83 @@
+package ekz
+
+import "fmt"
+
+// CarConfig configures one of several cars sharing the charging station
+type CarConfig struct {
+	// ID is the TeslaMate ID of the car
+	ID   int    `yaml:"id"`
+	Name string `yaml:"name,omitempty"`
+	// MaximumCharge replaces --maximum-charge for the car, 0 keeps it
+	MaximumCharge int `yaml:"maximum_charge,omitempty"`
+	// ChargeTargets replaces charge_targets for the car, nil keeps them
+	ChargeTargets *ChargeTargetConfig `yaml:"charge_targets,omitempty"`
+	// Priority orders the cars with the priority fairness policy, highest first
+	Priority int `yaml:"priority,omitempty"`
+	// BatteryCapacity is the usable capacity in kWh, to estimate the energy the car needs
+	BatteryCapacity float64 `yaml:"battery_capacity,omitempty"`
+}
+
+// Capacity returns the usable battery capacity in kWh, DefaultBatteryCapacity if not configured
+func (c CarConfig) Capacity() float64 {
+	if c.BatteryCapacity > 0 {
+		return c.BatteryCapacity
+	}
+	return DefaultBatteryCapacity
+}
+
+// String returns the ID of the car, with its name if configured
+func (c CarConfig) String() string {
+	if c.Name == "" {
+		return fmt.Sprintf("car %d", c.ID)
+	}
+	return fmt.Sprintf("car %d (%s)", c.ID, c.Name)
+}
+
+// CarsConfig lists the cars sharing the charging station
+type CarsConfig []CarConfig
+
+// Validate checks the cars and their targets
+func (c CarsConfig) Validate() error {
+	seen := make(map[int]bool)
+	for _, car := range c {
+		if car.ID <= 0 {
+			return fmt.Errorf("cars: id must be a positive TeslaMate car ID")
+		}
+		if seen[car.ID] {
+			return fmt.Errorf("cars: car %d is configured twice", car.ID)
+		}
+		seen[car.ID] = true
+		if err := validatePercent(car.String()+": maximum_charge", car.MaximumCharge); err != nil {
+			return err
+		}
+		if car.BatteryCapacity < 0 {
+			return fmt.Errorf("%s: battery_capacity can't be negative", car)
+		}
+		if car.ChargeTargets != nil {
+			if err := car.ChargeTargets.Validate(); err != nil {
+				return fmt.Errorf("%s: invalid charge_targets: %w", car, err)
+			}
+		}
+	}
+	return nil
+}
+
+// IDs returns the IDs of the cars, in the configured order
+func (c CarsConfig) IDs() []int {
+	ids := make([]int, 0, len(c))
+	for _, car := range c {
+		ids = append(ids, car.ID)
+	}
+	return ids
+}
+
+// Car returns the configuration of the car with the given ID, a configuration without
+// overrides if it isn't configured
+func (c CarsConfig) Car(id int) CarConfig {
+	for _, car := range c {
+		if car.ID == id {
+			return car
+		}
+	}
+	return CarConfig{ID: id}
+}
@@ -0,0 +1,48 @@
+package ekz
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCarsConfig(t *testing.T) {
+	cars := CarsConfig{
+		{ID: 1, Name: "Blue", MaximumCharge: 80},
+		{ID: 2, ChargeTargets: &ChargeTargetConfig{Default: 70}},
+	}
+	require.NoError(t, cars.Validate())
+	assert.Equal(t, []int{1, 2}, cars.IDs())
+	assert.Equal(t, "car 1 (Blue)", cars.Car(1).String())
+	assert.Equal(t, 70, cars.Car(2).ChargeTargets.Default)
+	assert.Equal(t, CarConfig{ID: 3}, cars.Car(3))
+	assert.Equal(t, DefaultBatteryCapacity, cars.Car(1).Capacity())
+	assert.Equal(t, 60.0, CarConfig{ID: 4, BatteryCapacity: 60}.Capacity())
+
+	assert.ErrorContains(t, CarsConfig{{ID: 0}}.Validate(), "positive TeslaMate car ID")
+	assert.ErrorContains(t, CarsConfig{{ID: 1}, {ID: 1}}.Validate(), "car 1 is configured twice")
+	assert.ErrorContains(t, CarsConfig{{ID: 1, MaximumCharge: 120}}.Validate(), "between 0 and 100")
+	assert.ErrorContains(t, CarsConfig{{ID: 1, BatteryCapacity: -1}}.Validate(), "battery_capacity can't be negative")
+	assert.ErrorContains(t, CarsConfig{{ID: 2, Name: "White", ChargeTargets: &ChargeTargetConfig{Default: -1}}}.Validate(),
+		"car 2 (White): invalid charge_targets")
+}
+
+func TestFairnessConfig(t *testing.T) {
+	var off FairnessConfig
+	require.NoError(t, off.Validate())
+	assert.False(t, off.Enabled())
+	assert.Equal(t, DefaultChargePower, off.Power())
+	assert.Equal(t, DefaultMinTurn, off.MinimumTurn())
+
+	need := FairnessConfig{Policy: FairnessNeed, ChargePower: 7.4, MinTurn: time.Hour}
+	require.NoError(t, need.Validate())
+	assert.True(t, need.Enabled())
+	assert.Equal(t, 7.4, need.Power())
+	assert.Equal(t, time.Hour, need.MinimumTurn())
+
+	assert.ErrorContains(t, FairnessConfig{Policy: "first"}.Validate(), `unknown policy "first"`)
+	assert.ErrorContains(t, FairnessConfig{Policy: FairnessPriority, ChargePower: -1}.Validate(), "charge_power")
+	assert.ErrorContains(t, FairnessConfig{Policy: FairnessPriority, MinTurn: -time.Minute}.Validate(), "min_turn")
+}
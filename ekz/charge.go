@@ -1,62 +1,208 @@
 package ekz
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"time"
 )
 
-// StartCharge calls the remote start API of the backend and starts fetching some live data
+const (
+	DefaultStartTimeout      = 5 * time.Minute
+	DefaultStartPollInterval = 10 * time.Second
+	DefaultStopTimeout       = 2 * time.Minute
+	DefaultStopPollInterval  = 5 * time.Second
+)
+
+var (
+	// ErrStartRejected is returned when the backend refuses the remote start
+	ErrStartRejected = errors.New("remote start rejected")
+	// ErrNotDrawing is returned when the remote start was accepted but no power flows
+	ErrNotDrawing = errors.New("remote start accepted but the car is not drawing power")
+	// ErrNoActiveSession is returned when stopping a connector without a running session
+	ErrNoActiveSession = errors.New("no active session")
+	// ErrStopNotConfirmed is returned when the remote stop was accepted but the session keeps running
+	ErrStopNotConfirmed = errors.New("remote stop accepted but the session keeps running")
+)
+
+// StartOptions controls how a started session is verified
+type StartOptions struct {
+	// Timeout is how long to wait for power to flow (default: DefaultStartTimeout)
+	Timeout time.Duration
+	// PollInterval is how often the live data is polled (default: DefaultStartPollInterval)
+	PollInterval time.Duration
+	// Retry retries the remote start once before giving up
+	Retry bool
+	// Progress is called after every poll. liveData is nil while the session is not visible yet.
+	Progress func(elapsed time.Duration, liveData *LiveDataResponse)
+	// Started is called once the started session first appears in the live data, before
+	// waiting for power, e.g. to make the car start charging. It is not called if the session
+	// never appears. Errors are logged.
+	Started func(ctx context.Context) error
+}
+
+// StartCharge runs the pre-flight checks, calls the remote start API of the backend
+// and starts fetching some live data
 func (c *Client) StartCharge(chargeBoxID string, connectorID int) error {
-	// Check if we're already charging
-	livedata, err := c.GetLiveData(chargeBoxID, connectorID, ConnectorStatusCharging)
+	_, err := c.StartChargeContext(context.Background(), chargeBoxID, connectorID, StartOptions{
+		Progress: func(_ time.Duration, liveData *LiveDataResponse) {
+			if liveData != nil {
+				printLiveData(liveData)
+			}
+		},
+	})
+	return err
+}
+
+// StartChargeContext runs the pre-flight checks, then starts a session and waits until power flows.
+// If our own session is already running, its live data is returned.
+func (c *Client) StartChargeContext(ctx context.Context, chargeBoxID string, connectorID int, opts StartOptions) (*LiveDataResponse, error) {
+	preflight, err := c.Preflight(chargeBoxID, connectorID)
 	if err != nil {
-		if !errors.Is(err, ErrTransactionNotFoundInTable) {
-			return err
+		return nil, err
+	}
+
+	// Check if we're already charging
+	if preflight.AlreadyCharging() {
+		if opts.Progress != nil {
+			opts.Progress(0, preflight.Session)
+		}
+		return preflight.Session, nil
+	}
+
+	for _, warning := range preflight.Warnings() {
+		log.Warnf("Pre-flight: %s", warning.Message)
+	}
+	if err := preflight.Err(); err != nil {
+		return nil, err
+	}
+
+	return c.StartAndVerify(ctx, chargeBoxID, connectorID, opts)
+}
+
+// StartAndVerify calls the remote start API and polls the live data until power flows.
+// It returns ErrStartRejected if the backend refuses the start and ErrNotDrawing if
+// the car doesn't draw power within the timeout. With Retry, a rejected start or a session that never
+// drew power is retried once, the latter only after its session closed.
+func (c *Client) StartAndVerify(ctx context.Context, chargeBoxID string, connectorID int, opts StartOptions) (*LiveDataResponse, error) {
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultStartTimeout
+	}
+	if opts.PollInterval == 0 {
+		opts.PollInterval = DefaultStartPollInterval
+	}
+
+	attempts := 1
+	if opts.Retry {
+		attempts = 2
+	}
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		var liveData *LiveDataResponse
+		liveData, err = c.startOnce(ctx, chargeBoxID, connectorID, opts)
+		if err == nil {
+			return liveData, nil
+		}
+		if ctx.Err() != nil || attempt == attempts || !c.closeForRetry(ctx, chargeBoxID, connectorID, liveData, err, opts) {
+			break
 		}
+		log.Warnf("Start attempt %d/%d failed, retrying: %v", attempt, attempts, err)
+	}
+	return nil, err
+}
+
+// closeForRetry makes sure the failed start attempt left no session open before it is retried.
+// An idle session is stopped and waited for to close, within the start timeout. It returns
+// false when the attempt must not be retried, as the state of its session is unknown.
+func (c *Client) closeForRetry(ctx context.Context, chargeBoxID string, connectorID int, liveData *LiveDataResponse, err error, opts StartOptions) bool {
+	switch {
+	case errors.Is(err, ErrStartRejected):
+		return true
+	case !errors.Is(err, ErrNotDrawing):
+		return false
+	case liveData == nil:
+		// The session never appeared
+		return true
 	}
 
-	if livedata != nil {
-		printLiveData(livedata)
-		return nil
+	// The session exists but the car doesn't draw power: close it before retrying
+	if _, err := c.RemoteStop(chargeBoxID, connectorID); err != nil {
+		log.Warnf("Failed to stop the idle session, not retrying: %v", err)
+		return false
+	}
+	if _, err := c.waitForSessionEnd(ctx, chargeBoxID, connectorID, liveData, StopOptions{Timeout: opts.Timeout, PollInterval: opts.PollInterval}); err != nil {
+		log.Warnf("The idle session didn't close, not retrying: %v", err)
+		return false
 	}
+	return true
+}
 
+// startOnce performs a single remote start and waits for power. On ErrNotDrawing,
+// the last live data is returned if the session was visible.
+func (c *Client) startOnce(ctx context.Context, chargeBoxID string, connectorID int, opts StartOptions) (*LiveDataResponse, error) {
 	remoteStart, err := c.RemoteStart(chargeBoxID, connectorID)
+	if errors.Is(err, errRemoteOpRefused) {
+		return nil, fmt.Errorf("%w: %v", ErrStartRejected, err)
+	}
 	if err != nil {
-		return err
+		return nil, err
 	}
-
 	log.Debugf("remote start: %+v", remoteStart)
 
-	// We call live data until the ChargedEnergy is > 0
-	attempts := 0
-	// One attempt every 10 seconds, so we wait 5 minutes
-	maxAttempts := 6 * 5
+	waitCtx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(opts.PollInterval)
+	defer ticker.Stop()
+
+	started := time.Now()
+	var last *LiveDataResponse
+	// pollErr is the error of the last poll, the session may have appeared in the meantime
+	var pollErr error
 	for {
-		if attempts >= maxAttempts {
-			return fmt.Errorf("max attempts reached")
-		}
-		livedata, err := c.GetLiveData(chargeBoxID, connectorID, ConnectorStatusCharging)
-		if err != nil {
-			if errors.Is(err, ErrTransactionNotFoundInTable) {
-				attempts++
-				time.Sleep(5 * time.Second)
-				continue
+		liveData, err := c.GetLiveData(chargeBoxID, connectorID, ConnectorStatusCharging)
+		pollErr = nil
+		switch {
+		case errors.Is(err, ErrTransactionNotFoundInTable):
+			log.Debugf("Session not visible in live data yet")
+		case err != nil:
+			pollErr = err
+			log.Warnf("Failed to get live data, retrying: %v", err)
+		default:
+			if last == nil && opts.Started != nil {
+				if err := opts.Started(ctx); err != nil {
+					log.Warnf("After the session started: %v", err)
+				}
+			}
+			last = liveData
+			if liveData.Power > 0 {
+				if opts.Progress != nil {
+					opts.Progress(time.Since(started), liveData)
+				}
+				return liveData, nil
 			}
-			return err
+			log.Debugf("Power is %.2f, waiting %s", liveData.Power, opts.PollInterval)
+		}
+		if opts.Progress != nil {
+			opts.Progress(time.Since(started), last)
 		}
 
-		printLiveData(livedata)
-
-		if livedata.Power > 0 {
-			break
+		select {
+		case <-waitCtx.Done():
+			if ctx.Err() != nil {
+				return last, ctx.Err()
+			}
+			if last == nil && pollErr != nil {
+				return nil, fmt.Errorf("session state unknown after %s: %w", opts.Timeout, pollErr)
+			}
+			if last == nil {
+				return nil, fmt.Errorf("%w: no session appeared within %s", ErrNotDrawing, opts.Timeout)
+			}
+			return last, fmt.Errorf("%w within %s (status %s)", ErrNotDrawing, opts.Timeout, last.Status)
+		case <-ticker.C:
 		}
-		attempts++
-		log.Debugf("Power is %.2f, waiting 5 seconds", livedata.Power)
-		time.Sleep(10 * time.Second)
 	}
-
-	return nil
 }
 
 func printLiveData(livedata *LiveDataResponse) {
@@ -67,3 +213,101 @@ func printLiveData(livedata *LiveDataResponse) {
 	)
 	log.Debugf("live data: %+v", livedata)
 }
+
+// StopOptions controls how a stopped session is verified
+type StopOptions struct {
+	// Timeout is how long to wait for the session to close (default: DefaultStopTimeout)
+	Timeout time.Duration
+	// PollInterval is how often the live data is polled (default: DefaultStopPollInterval)
+	PollInterval time.Duration
+	// Retry sends the remote stop once more if the session keeps running
+	Retry bool
+}
+
+// StopAndVerify calls the remote stop API and polls the live data until the session is closed.
+// It returns the final live data of the session, ErrNoActiveSession if no session is running,
+// and ErrStopNotConfirmed if the session keeps running after the stop was accepted.
+func (c *Client) StopAndVerify(ctx context.Context, chargeBoxID string, connectorID int, opts StopOptions) (*LiveDataResponse, error) {
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultStopTimeout
+	}
+	if opts.PollInterval == 0 {
+		opts.PollInterval = DefaultStopPollInterval
+	}
+
+	last, err := c.GetLiveData(chargeBoxID, connectorID, ConnectorStatusCharging)
+	if errors.Is(err, ErrTransactionNotFoundInTable) {
+		return nil, ErrNoActiveSession
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	attempts := 1
+	if opts.Retry {
+		attempts = 2
+	}
+
+	for attempt := 1; attempt <= attempts; attempt++ {
+		remoteStop, err := c.RemoteStop(chargeBoxID, connectorID)
+		if err != nil {
+			return last, err
+		}
+		log.Debugf("remote stop: %+v", remoteStop)
+
+		final, err := c.waitForSessionEnd(ctx, chargeBoxID, connectorID, last, opts)
+		if err == nil {
+			return final, nil
+		}
+		if !errors.Is(err, ErrStopNotConfirmed) || attempt == attempts {
+			return final, err
+		}
+		last = final
+		log.Warnf("Stop attempt %d/%d not confirmed, retrying: %v", attempt, attempts, err)
+	}
+	return last, ErrStopNotConfirmed
+}
+
+// waitForSessionEnd polls the live data until the session is closed
+func (c *Client) waitForSessionEnd(ctx context.Context, chargeBoxID string, connectorID int, last *LiveDataResponse, opts StopOptions) (*LiveDataResponse, error) {
+	waitCtx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-waitCtx.Done():
+			if ctx.Err() != nil {
+				return last, ctx.Err()
+			}
+			return last, fmt.Errorf("%w: session %d still %s after %s", ErrStopNotConfirmed, last.TransactionID, last.Status, opts.Timeout)
+		case <-ticker.C:
+		}
+
+		liveData, err := c.GetLiveData(chargeBoxID, connectorID, ConnectorStatusCharging)
+		switch {
+		case errors.Is(err, ErrTransactionNotFoundInTable):
+			return c.finalLiveData(chargeBoxID, connectorID, last), nil
+		case err != nil:
+			log.Warnf("Failed to get live data, retrying: %v", err)
+		case liveData.IsFinished():
+			return liveData, nil
+		default:
+			last = liveData
+			log.Debugf("Session %d is still %s", liveData.TransactionID, liveData.Status)
+		}
+	}
+}
+
+// finalLiveData fetches the closed session, which carries the final costs,
+// falling back to the last live data seen while it was running
+func (c *Client) finalLiveData(chargeBoxID string, connectorID int, last *LiveDataResponse) *LiveDataResponse {
+	final, err := c.GetLiveData(chargeBoxID, connectorID, ConnectorStatusAvailable)
+	if err != nil || final.TransactionID != last.TransactionID {
+		log.Debugf("Final live data not available, using the last data seen")
+		return last
+	}
+	return final
+}
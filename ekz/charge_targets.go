@@ -0,0 +1,197 @@
+package ekz
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	defaultNightRolloverHour = 12
+	defaultCalendarTarget    = 100
+	defaultCalendarLookahead = 24 * time.Hour
+)
+
+// ChargeTargetConfig describes the target charge level depending on the day.
+// Weekdays and dates refer to the charging night: times before NightRolloverHour
+// belong to the night of the previous day, so "fri" covers Friday evening up to Saturday noon.
+type ChargeTargetConfig struct {
+	// Default target, 0 means the --maximum-charge value
+	Default int `yaml:"default"`
+	// Weekdays maps "mon".."sun" to a target
+	Weekdays map[string]int `yaml:"weekdays"`
+	// Dates maps "YYYY-MM-DD" to a target, taking precedence over everything else
+	Dates             map[string]int `yaml:"dates"`
+	NightRolloverHour *int           `yaml:"night_rollover_hour"`
+
+	// Calendar is the path of a local .ics file containing departures
+	Calendar string `yaml:"calendar"`
+	// CalendarFilter only considers events whose summary contains this text (case-insensitive)
+	CalendarFilter string `yaml:"calendar_filter"`
+	// CalendarTarget is used when a departure starts within CalendarLookahead (default: 100)
+	CalendarTarget    int    `yaml:"calendar_target"`
+	CalendarLookahead string `yaml:"calendar_lookahead"`
+}
+
+// ChargeTarget is the target charge level and where it comes from
+type ChargeTarget struct {
+	Percent int
+	Source  string
+}
+
+// Validate checks the charge target configuration
+func (c *ChargeTargetConfig) Validate() error {
+	if err := validatePercent("default", c.Default); err != nil {
+		return err
+	}
+	days := make(map[time.Weekday]string)
+	for day, target := range c.Weekdays {
+		wd, err := parseWeekday(day)
+		if err != nil {
+			return err
+		}
+		if other, ok := days[wd]; ok {
+			first, second := min(other, day), max(other, day)
+			return fmt.Errorf("weekdays %q and %q are both %s", first, second, wd)
+		}
+		days[wd] = day
+		if err := validatePercent("weekday "+day, target); err != nil {
+			return err
+		}
+	}
+	for date, target := range c.Dates {
+		if _, err := time.Parse(tariffDateLayout, date); err != nil {
+			return fmt.Errorf("invalid date %q", date)
+		}
+		if err := validatePercent("date "+date, target); err != nil {
+			return err
+		}
+	}
+	if c.NightRolloverHour != nil && (*c.NightRolloverHour < 0 || *c.NightRolloverHour > 23) {
+		return fmt.Errorf("night_rollover_hour must be between 0 and 23")
+	}
+	if err := validatePercent("calendar_target", c.CalendarTarget); err != nil {
+		return err
+	}
+	if c.CalendarLookahead != "" {
+		if _, err := time.ParseDuration(c.CalendarLookahead); err != nil {
+			return fmt.Errorf("invalid calendar_lookahead: %w", err)
+		}
+	}
+	return nil
+}
+
+// TargetAt returns the target charge level for a charging session at t.
+// Precedence: date override, calendar departure, weekday, default.
+// A Percent of 0 means no target is configured for t.
+func (c *ChargeTargetConfig) TargetAt(t time.Time) (ChargeTarget, error) {
+	night := c.chargingNight(t)
+
+	if target, ok := c.Dates[night.Format(tariffDateLayout)]; ok {
+		return ChargeTarget{Percent: target, Source: "date " + night.Format(tariffDateLayout)}, nil
+	}
+
+	if c.Calendar != "" {
+		event, err := c.nextDeparture(t)
+		if err != nil {
+			return ChargeTarget{}, err
+		}
+		if event != nil {
+			target := c.CalendarTarget
+			if target == 0 {
+				target = defaultCalendarTarget
+			}
+			return ChargeTarget{
+				Percent: target,
+				Source:  fmt.Sprintf("departure %q at %s", event.Summary, event.Start.Format("2006-01-02 15:04")),
+			}, nil
+		}
+	}
+
+	for day, target := range c.Weekdays {
+		wd, err := parseWeekday(day)
+		if err != nil {
+			return ChargeTarget{}, err
+		}
+		if wd == night.Weekday() {
+			return ChargeTarget{Percent: target, Source: "weekday " + night.Weekday().String()}, nil
+		}
+	}
+
+	if c.Default > 0 {
+		return ChargeTarget{Percent: c.Default, Source: "default"}, nil
+	}
+	return ChargeTarget{}, nil
+}
+
+// chargingNight returns the day whose night t belongs to
+func (c *ChargeTargetConfig) chargingNight(t time.Time) time.Time {
+	rollover := defaultNightRolloverHour
+	if c.NightRolloverHour != nil {
+		rollover = *c.NightRolloverHour
+	}
+	if t.Hour() < rollover {
+		return t.AddDate(0, 0, -1)
+	}
+	return t
+}
+
+// nextDeparture returns the first calendar event starting within the lookahead window
+func (c *ChargeTargetConfig) nextDeparture(t time.Time) (*CalendarEvent, error) {
+	lookahead := defaultCalendarLookahead
+	if c.CalendarLookahead != "" {
+		var err error
+		lookahead, err = time.ParseDuration(c.CalendarLookahead)
+		if err != nil {
+			return nil, fmt.Errorf("invalid calendar_lookahead: %w", err)
+		}
+	}
+
+	events, err := LoadICS(c.Calendar)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read calendar: %w", err)
+	}
+
+	var next *CalendarEvent
+	for i := range events {
+		e := &events[i]
+		if e.Start.Before(t) || e.Start.After(t.Add(lookahead)) {
+			continue
+		}
+		if c.CalendarFilter != "" && !strings.Contains(strings.ToLower(e.Summary), strings.ToLower(c.CalendarFilter)) {
+			continue
+		}
+		if next == nil || e.Start.Before(next.Start) {
+			next = e
+		}
+	}
+	return next, nil
+}
+
+func parseWeekday(s string) (time.Weekday, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "mon", "monday":
+		return time.Monday, nil
+	case "tue", "tuesday":
+		return time.Tuesday, nil
+	case "wed", "wednesday":
+		return time.Wednesday, nil
+	case "thu", "thursday":
+		return time.Thursday, nil
+	case "fri", "friday":
+		return time.Friday, nil
+	case "sat", "saturday":
+		return time.Saturday, nil
+	case "sun", "sunday":
+		return time.Sunday, nil
+	default:
+		return 0, fmt.Errorf("invalid weekday: %s", s)
+	}
+}
+
+func validatePercent(name string, value int) error {
+	if value < 0 || value > 100 {
+		return fmt.Errorf("%s: charge target must be between 0 and 100", name)
+	}
+	return nil
+}
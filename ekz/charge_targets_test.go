@@ -0,0 +1,89 @@
+package ekz
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChargeTargetConfig_TargetAt(t *testing.T) {
+	cfg := ChargeTargetConfig{
+		Default:  80,
+		Weekdays: map[string]int{"fri": 100},
+		Dates:    map[string]int{"2025-01-15": 60},
+	}
+	require.NoError(t, cfg.Validate())
+
+	tests := []struct {
+		name     string
+		time     time.Time
+		expected int
+		source   string
+	}{
+		{name: "weekday default", time: time.Date(2025, 1, 13, 22, 0, 0, 0, time.UTC), expected: 80, source: "default"},
+		{name: "friday night", time: time.Date(2025, 1, 17, 22, 0, 0, 0, time.UTC), expected: 100, source: "weekday Friday"},
+		{name: "saturday early morning belongs to friday night", time: time.Date(2025, 1, 18, 3, 0, 0, 0, time.UTC), expected: 100, source: "weekday Friday"},
+		{name: "saturday afternoon", time: time.Date(2025, 1, 18, 14, 0, 0, 0, time.UTC), expected: 80, source: "default"},
+		{name: "date override", time: time.Date(2025, 1, 16, 2, 0, 0, 0, time.UTC), expected: 60, source: "date 2025-01-15"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, err := cfg.TargetAt(tt.time)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, target.Percent)
+			assert.Equal(t, tt.source, target.Source)
+		})
+	}
+}
+
+func TestChargeTargetConfig_Calendar(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "departures.ics")
+	require.NoError(t, os.WriteFile(path, []byte(testCalendar), 0644))
+
+	cfg := ChargeTargetConfig{
+		Default:        80,
+		Calendar:       path,
+		CalendarFilter: "trip",
+		CalendarTarget: 95,
+	}
+	require.NoError(t, cfg.Validate())
+
+	// The road trip starts within the next 24 hours
+	target, err := cfg.TargetAt(time.Date(2025, 10, 17, 22, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, 95, target.Percent)
+	assert.Contains(t, target.Source, "Road trip")
+
+	// The dentist appointment does not match the filter
+	target, err = cfg.TargetAt(time.Date(2025, 10, 19, 22, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, 80, target.Percent)
+
+	// A missing calendar is reported
+	cfg.Calendar = filepath.Join(t.TempDir(), "missing.ics")
+	_, err = cfg.TargetAt(time.Date(2025, 10, 17, 22, 0, 0, 0, time.UTC))
+	assert.Error(t, err)
+}
+
+func TestChargeTargetConfig_Validate(t *testing.T) {
+	invalid := []ChargeTargetConfig{
+		{Default: 101},
+		{Weekdays: map[string]int{"someday": 80}},
+		{Weekdays: map[string]int{"fri": 60, "Friday": 80}},
+		{Dates: map[string]int{"2025-13-01": 80}},
+		{CalendarLookahead: "tomorrow"},
+	}
+	for _, cfg := range invalid {
+		assert.Error(t, cfg.Validate())
+	}
+
+	// No configuration means no target
+	target, err := (&ChargeTargetConfig{}).TargetAt(time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 0, target.Percent)
+}
@@ -0,0 +1,314 @@
+package ekz
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/h2non/gock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mockRemoteStart(status int) {
+	gock.New(Backend).
+		Post("/saascharge/remote-start").
+		Reply(status).
+		File("../resources/remote-start.json")
+}
+
+func mockLiveDataPower(power float64) *gock.Request {
+	request := gock.New(Backend).
+		Post("/charging-stations/charging-live-data")
+	request.Reply(http.StatusOK).
+		JSON(map[string]any{
+			"transaction_id": 1,
+			"status":         "ONGOING",
+			"power":          power,
+		})
+	return request
+}
+
+func testStartOptions() StartOptions {
+	return StartOptions{
+		Timeout:      50 * time.Millisecond,
+		PollInterval: 5 * time.Millisecond,
+	}
+}
+
+func TestClient_StartAndVerify(t *testing.T) {
+	defer gock.Off()
+	mockRemoteStart(http.StatusOK)
+	gock.New(Backend).
+		Post("/charging-stations/charging-live-data").
+		Reply(http.StatusNotFound).
+		File("../resources/live-data-fail.json")
+	mockLiveDataPower(0)
+	mockLiveDataPower(11)
+
+	var polls int
+	opts := testStartOptions()
+	opts.Progress = func(_ time.Duration, _ *LiveDataResponse) { polls++ }
+
+	liveData, err := newTestPreflightClient(t).StartAndVerify(context.Background(), "1234", 1, opts)
+	require.NoError(t, err)
+	assert.Equal(t, 11.0, liveData.Power)
+	assert.Equal(t, 3, polls)
+}
+
+func TestClient_StartAndVerify_Started(t *testing.T) {
+	defer gock.Off()
+	mockRemoteStart(http.StatusOK)
+	gock.New(Backend).
+		Post("/charging-stations/charging-live-data").
+		Reply(http.StatusNotFound).
+		File("../resources/live-data-fail.json")
+	mockLiveDataPower(0)
+	mockLiveDataPower(0)
+	mockLiveDataPower(11)
+
+	var polls, started, startedAt int
+	opts := testStartOptions()
+	opts.Progress = func(_ time.Duration, _ *LiveDataResponse) { polls++ }
+	opts.Started = func(ctx context.Context) error {
+		started++
+		startedAt = polls
+		return errors.New("car asleep")
+	}
+
+	_, err := newTestPreflightClient(t).StartAndVerify(context.Background(), "1234", 1, opts)
+	require.NoError(t, err, "errors of the hook are only logged")
+	assert.Equal(t, 1, started)
+	assert.Equal(t, 1, startedAt, "called once the session appeared in the second poll")
+}
+
+func TestClient_StartAndVerify_StartedNeverVisible(t *testing.T) {
+	defer gock.Off()
+	mockRemoteStart(http.StatusOK)
+	gock.New(Backend).
+		Post("/charging-stations/charging-live-data").
+		Persist().
+		Reply(http.StatusNotFound).
+		File("../resources/live-data-fail.json")
+
+	started := false
+	opts := testStartOptions()
+	opts.Started = func(ctx context.Context) error {
+		started = true
+		return nil
+	}
+
+	_, err := newTestPreflightClient(t).StartAndVerify(context.Background(), "1234", 1, opts)
+	assert.ErrorIs(t, err, ErrNotDrawing)
+	assert.False(t, started)
+}
+
+func TestClient_StartAndVerify_Rejected(t *testing.T) {
+	defer gock.Off()
+	mockRemoteStart(http.StatusBadRequest)
+
+	_, err := newTestPreflightClient(t).StartAndVerify(context.Background(), "1234", 1, testStartOptions())
+	assert.ErrorIs(t, err, ErrStartRejected)
+}
+
+func TestClient_StartAndVerify_RejectedInBody(t *testing.T) {
+	defer gock.Off()
+	gock.New(Backend).
+		Post("/saascharge/remote-start").
+		Reply(http.StatusOK).
+		JSON(map[string]any{"status_code": 409, "message": "connector occupied"})
+
+	_, err := newTestPreflightClient(t).StartAndVerify(context.Background(), "1234", 1, testStartOptions())
+	assert.ErrorIs(t, err, ErrStartRejected)
+}
+
+func TestClient_StartAndVerify_NetworkFailure(t *testing.T) {
+	defer gock.Off()
+	reset := errors.New("connection reset by peer")
+	gock.New(Backend).
+		Post("/saascharge/remote-start").
+		ReplyError(reset)
+
+	_, err := newTestPreflightClient(t).StartAndVerify(context.Background(), "1234", 1, testStartOptions())
+	assert.ErrorIs(t, err, reset)
+	assert.NotErrorIs(t, err, ErrStartRejected)
+}
+
+func TestClient_StartAndVerify_BackendFailure(t *testing.T) {
+	defer gock.Off()
+	mockRemoteStart(http.StatusServiceUnavailable)
+	gock.New(Backend).
+		Post("/saascharge/remote-start").
+		Reply(http.StatusOK).
+		File("../resources/remote-start.json")
+
+	opts := testStartOptions()
+	opts.Retry = true
+	_, err := newTestPreflightClient(t).StartAndVerify(context.Background(), "1234", 1, opts)
+	assert.ErrorContains(t, err, "503")
+	assert.NotErrorIs(t, err, ErrStartRejected)
+	assert.False(t, gock.IsDone(), "a backend failure is not retried")
+}
+
+func TestClient_StartAndVerify_LiveDataFailure(t *testing.T) {
+	defer gock.Off()
+	mockRemoteStart(http.StatusOK)
+	gock.New(Backend).
+		Post("/charging-stations/charging-live-data").
+		Reply(http.StatusBadGateway)
+	mockLiveDataPower(11)
+
+	liveData, err := newTestPreflightClient(t).StartAndVerify(context.Background(), "1234", 1, testStartOptions())
+	require.NoError(t, err, "a failed poll is retried")
+	assert.Equal(t, 11.0, liveData.Power)
+}
+
+func TestClient_StartAndVerify_SessionUnknown(t *testing.T) {
+	defer gock.Off()
+	mockRemoteStart(http.StatusOK)
+	gock.New(Backend).
+		Post("/charging-stations/charging-live-data").
+		Persist().
+		Reply(http.StatusBadGateway)
+	mockRemoteStart(http.StatusOK)
+
+	opts := testStartOptions()
+	opts.Retry = true
+	_, err := newTestPreflightClient(t).StartAndVerify(context.Background(), "1234", 1, opts)
+	assert.ErrorContains(t, err, "session state unknown")
+	assert.NotErrorIs(t, err, ErrNotDrawing)
+	assert.False(t, gock.IsDone(), "the start is not retried while a session may be open")
+}
+
+func TestClient_StartAndVerify_NotDrawing(t *testing.T) {
+	defer gock.Off()
+	mockRemoteStart(http.StatusOK)
+	mockLiveDataPower(0).Persist()
+
+	_, err := newTestPreflightClient(t).StartAndVerify(context.Background(), "1234", 1, testStartOptions())
+	assert.ErrorIs(t, err, ErrNotDrawing)
+}
+
+func TestClient_StartAndVerify_Retry(t *testing.T) {
+	defer gock.Off()
+	mockRemoteStart(http.StatusBadRequest)
+	mockRemoteStart(http.StatusOK)
+	mockLiveDataPower(11)
+
+	opts := testStartOptions()
+	opts.Retry = true
+	liveData, err := newTestPreflightClient(t).StartAndVerify(context.Background(), "1234", 1, opts)
+	require.NoError(t, err)
+	assert.Equal(t, 11.0, liveData.Power)
+	assert.True(t, gock.IsDone())
+}
+
+func TestClient_StartAndVerify_RetryIdleSession(t *testing.T) {
+	defer gock.Off()
+	var stopped atomic.Bool
+	mockRemoteStart(http.StatusOK)
+	// The session draws no power until it is stopped
+	mockLiveDataPower(0).Persist().AddMatcher(func(*http.Request, *gock.Request) (bool, error) {
+		return !stopped.Load(), nil
+	})
+	remoteStop := gock.New(Backend).Post("/saascharge/remote-stop")
+	remoteStop.Reply(http.StatusOK).File("../resources/remote-start.json")
+	remoteStop.AddMatcher(func(*http.Request, *gock.Request) (bool, error) {
+		stopped.Store(true)
+		return true, nil
+	})
+	// The session closed, the final live data isn't available
+	gock.New(Backend).
+		Post("/charging-stations/charging-live-data").
+		Times(2).
+		Reply(http.StatusNotFound).
+		File("../resources/live-data-fail.json")
+	mockRemoteStart(http.StatusOK)
+	mockLiveDataPower(11)
+
+	opts := testStartOptions()
+	opts.Retry = true
+	liveData, err := newTestPreflightClient(t).StartAndVerify(context.Background(), "1234", 1, opts)
+	require.NoError(t, err)
+	assert.Equal(t, 11.0, liveData.Power)
+	assert.True(t, stopped.Load())
+	assert.Len(t, gock.Pending(), 1, "only the live data of the idle session is left")
+}
+
+func TestClient_StartAndVerify_RetryIdleSessionNotClosed(t *testing.T) {
+	defer gock.Off()
+	mockRemoteStart(http.StatusOK)
+	mockLiveDataPower(0).Persist()
+	mockRemoteStop()
+	mockRemoteStart(http.StatusOK)
+
+	opts := testStartOptions()
+	opts.Retry = true
+	_, err := newTestPreflightClient(t).StartAndVerify(context.Background(), "1234", 1, opts)
+	assert.ErrorIs(t, err, ErrNotDrawing)
+	assert.Len(t, gock.Pending(), 2, "the start is not retried while the idle session is open")
+}
+
+func testStopOptions() StopOptions {
+	return StopOptions{
+		Timeout:      50 * time.Millisecond,
+		PollInterval: 5 * time.Millisecond,
+	}
+}
+
+func mockRemoteStop() {
+	gock.New(Backend).
+		Post("/saascharge/remote-stop").
+		Reply(http.StatusOK).
+		File("../resources/remote-start.json")
+}
+
+func TestClient_StopAndVerify(t *testing.T) {
+	defer gock.Off()
+	mockLiveDataPower(11)
+	mockRemoteStop()
+	mockLiveDataPower(0)
+	gock.New(Backend).
+		Post("/charging-stations/charging-live-data").
+		BodyString(`"connector_status":"Charging"`).
+		Reply(http.StatusNotFound).
+		File("../resources/live-data-fail.json")
+	gock.New(Backend).
+		Post("/charging-stations/charging-live-data").
+		BodyString(`"connector_status":"Available"`).
+		Reply(http.StatusOK).
+		JSON(map[string]any{"transaction_id": 1, "status": "FINISHED", "stoptimestamp": 1701216858, "totalcost": 3.5})
+
+	final, err := newTestPreflightClient(t).StopAndVerify(context.Background(), "1234", 1, testStopOptions())
+	require.NoError(t, err)
+	assert.True(t, final.IsFinished())
+	assert.Equal(t, 3.5, final.Cost(nil))
+	assert.True(t, gock.IsDone())
+}
+
+func TestClient_StopAndVerify_NoSession(t *testing.T) {
+	defer gock.Off()
+	gock.New(Backend).
+		Post("/charging-stations/charging-live-data").
+		Reply(http.StatusNotFound).
+		File("../resources/live-data-fail.json")
+
+	_, err := newTestPreflightClient(t).StopAndVerify(context.Background(), "1234", 1, testStopOptions())
+	assert.ErrorIs(t, err, ErrNoActiveSession)
+}
+
+func TestClient_StopAndVerify_NotConfirmed(t *testing.T) {
+	defer gock.Off()
+	mockLiveDataPower(11).Persist()
+	mockRemoteStop()
+	mockRemoteStop()
+
+	opts := testStopOptions()
+	opts.Retry = true
+	final, err := newTestPreflightClient(t).StopAndVerify(context.Background(), "1234", 1, opts)
+	assert.ErrorIs(t, err, ErrStopNotConfirmed)
+	assert.Equal(t, 1, final.TransactionID)
+}
@@ -207,9 +207,13 @@ func (c *Client) remoteOp(boxId string, connectorID int, op string) (*RemoteStar
 
 	res, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("remote "+op+" failed: %s", err)
+		return nil, fmt.Errorf("remote "+op+" failed: %w", err)
+	}
+	if res.StatusCode >= 400 && res.StatusCode < 500 {
+		return nil, fmt.Errorf("remote "+op+" failed: %w: %s", errRemoteOpRefused, res.Status)
 	}
 	if res.StatusCode != http.StatusOK {
+		// A backend or gateway failure, not a refusal
 		return nil, fmt.Errorf("remote "+op+" failed: %s", res.Status)
 	}
 
@@ -219,6 +223,9 @@ func (c *Client) remoteOp(boxId string, connectorID int, op string) (*RemoteStar
 		return nil, err
 	}
 	log.Debugf("remote op response: %+v", remoteOpResponse)
+	if code := remoteOpResponse.StatusCode; code != 0 && (code < 200 || code >= 300) {
+		return nil, fmt.Errorf("remote "+op+" failed: %w: %d %s", errRemoteOpRefused, code, remoteOpResponse.Message)
+	}
 	return &remoteOpResponse.Data, nil
 }
 
@@ -2,9 +2,13 @@ package ekz
 
 import (
 	"os"
+	"time"
 
 	"github.com/adrg/xdg"
 	"gopkg.in/yaml.v3"
+
+	"github.com/denysvitali/ekz-tesla/teslamatedb"
+	"github.com/denysvitali/ekz-tesla/vehicle"
 )
 
 type ChargingStationConfig struct {
@@ -12,6 +16,29 @@ type ChargingStationConfig struct {
 	Longitude   float64 `yaml:"longitude"`
 	BoxId       string  `yaml:"box_id"`
 	ConnectorId int     `yaml:"connector_id"`
+	// Geofence is the area in which a car is at the station, a circle around the coordinates by default
+	Geofence GeofenceConfig `yaml:"geofence,omitempty"`
+}
+
+// QueueConfig configures the queue of one-off actions executed by the daemon
+type QueueConfig struct {
+	// CatchUpWindow is how late a queued action may still run, e.g. after the daemon was down.
+	// Older actions are skipped.
+	CatchUpWindow time.Duration `yaml:"catch_up_window,omitempty"`
+}
+
+// RulesConfig holds user-defined rule expressions over the variables documented in the rules package
+type RulesConfig struct {
+	// Start replaces the tariff and battery conditions of autostart
+	Start string `yaml:"start,omitempty"`
+	// Stop stops a supervised session as soon as it is true
+	Stop string `yaml:"stop,omitempty"`
+}
+
+// AlertsConfig configures where alerts are sent in addition to the log
+type AlertsConfig struct {
+	// WebhookURL receives alerts as JSON POST requests
+	WebhookURL string `yaml:"webhook_url,omitempty"`
 }
 
 type Config struct {
@@ -20,6 +47,15 @@ type Config struct {
 	Token    string `yaml:"token"`
 
 	ChargingStation ChargingStationConfig `yaml:"charging_station"`
+	Tariffs         Tariffs               `yaml:"tariffs,omitempty"`
+	ChargeTargets   ChargeTargetConfig    `yaml:"charge_targets,omitempty"`
+	Cars            CarsConfig            `yaml:"cars,omitempty"`
+	Fairness        FairnessConfig        `yaml:"fairness,omitempty"`
+	Queue           QueueConfig           `yaml:"queue,omitempty"`
+	Rules           RulesConfig           `yaml:"rules,omitempty"`
+	Alerts          AlertsConfig          `yaml:"alerts,omitempty"`
+	Vehicle         vehicle.Config        `yaml:"vehicle,omitempty"`
+	TeslaMateDB     teslamatedb.Config    `yaml:"teslamate_db,omitempty"`
 }
 
 var defaultConfigFilePath = xdg.ConfigHome + "/ekz-tesla/config.yaml"
@@ -4,4 +4,7 @@ import "fmt"
 
 var (
 	ErrLoginFailed = fmt.Errorf("login failed")
+	// errRemoteOpRefused is wrapped when the backend refuses a remote start or stop, with a 4xx
+	// status or a failing status code in the body. Backend and gateway failures are not refusals.
+	errRemoteOpRefused = fmt.Errorf("refused by the backend")
 )
@@ -0,0 +1,75 @@
+package ekz
+
+import (
+	"fmt"
+	"time"
+)
+
+// FairnessPolicy selects how cars sharing the connector split the low tariff window
+type FairnessPolicy string
+
+const (
+	// FairnessOff lets the car plugged in first charge up to its target
+	FairnessOff FairnessPolicy = ""
+	// FairnessNeed splits the window in proportion to the energy each car needs to reach its target
+	FairnessNeed FairnessPolicy = "need"
+	// FairnessPriority charges the cars by priority, each for the time it needs to reach its target
+	FairnessPriority FairnessPolicy = "priority"
+)
+
+const (
+	// DefaultChargePower is the power in kW the station is assumed to charge with
+	DefaultChargePower = 11.0
+	// DefaultBatteryCapacity is the usable battery capacity in kWh of a car without battery_capacity
+	DefaultBatteryCapacity = 75.0
+	// DefaultMinTurn is the shortest turn worth swapping the cable for
+	DefaultMinTurn = 30 * time.Minute
+)
+
+// FairnessConfig configures how several cars share the connector
+type FairnessConfig struct {
+	Policy FairnessPolicy `yaml:"policy,omitempty"`
+	// ChargePower is the power in kW the station charges with, to estimate how long a car
+	// needs to reach its target. 0 uses DefaultChargePower.
+	ChargePower float64 `yaml:"charge_power,omitempty"`
+	// MinTurn is the shortest turn worth swapping the cable for, shorter turns are merged
+	// into the previous one. 0 uses DefaultMinTurn.
+	MinTurn time.Duration `yaml:"min_turn,omitempty"`
+}
+
+// Enabled tells whether the cars take turns
+func (c FairnessConfig) Enabled() bool {
+	return c.Policy != FairnessOff
+}
+
+// Power returns the charge power in kW
+func (c FairnessConfig) Power() float64 {
+	if c.ChargePower > 0 {
+		return c.ChargePower
+	}
+	return DefaultChargePower
+}
+
+// MinimumTurn returns the shortest turn
+func (c FairnessConfig) MinimumTurn() time.Duration {
+	if c.MinTurn > 0 {
+		return c.MinTurn
+	}
+	return DefaultMinTurn
+}
+
+// Validate checks the policy and its parameters
+func (c FairnessConfig) Validate() error {
+	switch c.Policy {
+	case FairnessOff, FairnessNeed, FairnessPriority:
+	default:
+		return fmt.Errorf("fairness: unknown policy %q, use %q or %q", c.Policy, FairnessNeed, FairnessPriority)
+	}
+	if c.ChargePower < 0 {
+		return fmt.Errorf("fairness: charge_power can't be negative")
+	}
+	if c.MinTurn < 0 {
+		return fmt.Errorf("fairness: min_turn can't be negative")
+	}
+	return nil
+}
@@ -0,0 +1,269 @@
+package ekz
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	geo "github.com/kellydunn/golang-geo"
+)
+
+// DefaultStationRadius is the distance in meters from the station within which a car is at the station
+const DefaultStationRadius = 100
+
+// GeofenceConfig configures the area in which a car counts as at the charging station. Without
+// a polygon, it is the circle of Radius around the station coordinates.
+type GeofenceConfig struct {
+	// Radius in meters, 0 uses DefaultStationRadius
+	Radius float64 `yaml:"radius,omitempty"`
+	// Polygon lists the vertices as [latitude, longitude] pairs and replaces the circle
+	Polygon [][2]float64 `yaml:"polygon,omitempty"`
+	// GeoJSON is a file with a Polygon or MultiPolygon, or features of them, replacing the circle
+	GeoJSON string `yaml:"geojson,omitempty"`
+	// TeslaMate is the name of a TeslaMate geofence. A car TeslaMate reports in it is at the
+	// station wherever its location is, e.g. when the GPS drifts in an underground garage.
+	TeslaMate string `yaml:"teslamate,omitempty"`
+}
+
+// Validate checks the geofence, without reading the GeoJSON file
+func (c GeofenceConfig) Validate() error {
+	if c.Radius < 0 {
+		return fmt.Errorf("geofence: radius can't be negative")
+	}
+	if len(c.Polygon) > 0 && len(c.Polygon) < 3 {
+		return fmt.Errorf("geofence: a polygon needs at least 3 vertices")
+	}
+	for _, vertex := range c.Polygon {
+		if err := validateCoordinates(vertex[0], vertex[1]); err != nil {
+			return fmt.Errorf("geofence: %w", err)
+		}
+	}
+	if len(c.Polygon) > 0 && c.GeoJSON != "" {
+		return fmt.Errorf("geofence: polygon and geojson are exclusive")
+	}
+	return nil
+}
+
+func validateCoordinates(latitude, longitude float64) error {
+	if latitude < -90 || latitude > 90 || longitude < -180 || longitude > 180 {
+		return fmt.Errorf("invalid latitude %v or longitude %v", latitude, longitude)
+	}
+	return nil
+}
+
+// Geofence is the area in which a car counts as at the charging station
+type Geofence struct {
+	center *geo.Point
+	radius float64
+	// areas replace the circle when set
+	areas     []area
+	teslaMate string
+}
+
+// area is a polygon without its holes
+type area struct {
+	outer *geo.Polygon
+	holes []*geo.Polygon
+}
+
+func (a area) contains(point *geo.Point) bool {
+	if !a.outer.Contains(point) {
+		return false
+	}
+	for _, hole := range a.holes {
+		if hole.Contains(point) {
+			return false
+		}
+	}
+	return true
+}
+
+// NewCircleGeofence creates the geofence of radius meters around the coordinates,
+// DefaultStationRadius if radius is 0
+func NewCircleGeofence(latitude, longitude float64, radius float64) *Geofence {
+	if radius == 0 {
+		radius = DefaultStationRadius
+	}
+	return &Geofence{center: geo.NewPoint(latitude, longitude), radius: radius}
+}
+
+// NewGeofence creates the geofence of the station, reading its GeoJSON file if configured
+func NewGeofence(station ChargingStationConfig) (*Geofence, error) {
+	config := station.Geofence
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	g := NewCircleGeofence(station.Latitude, station.Longitude, config.Radius)
+	g.teslaMate = config.TeslaMate
+
+	if len(config.Polygon) > 0 {
+		points := make([]*geo.Point, len(config.Polygon))
+		for i, vertex := range config.Polygon {
+			points[i] = geo.NewPoint(vertex[0], vertex[1])
+		}
+		g.areas = []area{{outer: geo.NewPolygon(points)}}
+	}
+	if config.GeoJSON != "" {
+		data, err := os.ReadFile(config.GeoJSON)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read geofence: %w", err)
+		}
+		if g.areas, err = parseGeoJSON(data); err != nil {
+			return nil, fmt.Errorf("invalid geofence %s: %w", config.GeoJSON, err)
+		}
+	}
+	return g, nil
+}
+
+// GeofenceMatch tells whether a car is at the station and why
+type GeofenceMatch struct {
+	Inside bool
+	// Known is false when neither the location nor the TeslaMate geofence of the car is known,
+	// the car is then assumed at the station
+	Known bool
+	// Distance is the distance in meters from the station coordinates, 0 if the location is unknown
+	Distance float64
+	// Reason describes how the car was located
+	Reason string
+}
+
+// Match locates the car by its TeslaMate geofence, or by its coordinates when the car is not in
+// the configured TeslaMate geofence. located is false when the coordinates are unknown.
+func (g *Geofence) Match(latitude, longitude float64, located bool, teslaMateGeofence string) GeofenceMatch {
+	if g.teslaMate != "" && strings.EqualFold(teslaMateGeofence, g.teslaMate) {
+		return GeofenceMatch{Inside: true, Known: true, Reason: fmt.Sprintf("in TeslaMate geofence %q", teslaMateGeofence)}
+	}
+	if !located {
+		if g.teslaMate != "" && teslaMateGeofence != "" {
+			return GeofenceMatch{Known: true, Reason: fmt.Sprintf("in TeslaMate geofence %q", teslaMateGeofence)}
+		}
+		return GeofenceMatch{Inside: true, Reason: "unknown, assumed at the station"}
+	}
+
+	point := geo.NewPoint(latitude, longitude)
+	match := GeofenceMatch{Known: true, Distance: g.center.GreatCircleDistance(point) * 1000}
+	if len(g.areas) == 0 {
+		match.Inside = match.Distance <= g.radius
+		match.Reason = fmt.Sprintf("%.0f m", match.Distance)
+		return match
+	}
+	match.Reason = fmt.Sprintf("outside the polygon, %.0f m", match.Distance)
+	for _, a := range g.areas {
+		if a.contains(point) {
+			match.Inside = true
+			match.Reason = fmt.Sprintf("inside the polygon, %.0f m", match.Distance)
+			break
+		}
+	}
+	return match
+}
+
+// Want describes the area for the explanation of the checks
+func (g *Geofence) Want() string {
+	want := fmt.Sprintf("<= %.0f m", g.radius)
+	if len(g.areas) > 0 {
+		want = "inside the polygon"
+	}
+	if g.teslaMate != "" {
+		want += fmt.Sprintf(" or in TeslaMate geofence %q", g.teslaMate)
+	}
+	return want
+}
+
+// geoJSON holds the parts of a GeoJSON object needed to read polygons
+type geoJSON struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+	Geometry    *geoJSON        `json:"geometry"`
+	Features    []geoJSON       `json:"features"`
+	Geometries  []geoJSON       `json:"geometries"`
+}
+
+// parseGeoJSON reads the polygons of a GeoJSON object. Other geometries are ignored.
+func parseGeoJSON(data []byte) ([]area, error) {
+	var object geoJSON
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, err
+	}
+	areas, err := object.areas()
+	if err != nil {
+		return nil, err
+	}
+	if len(areas) == 0 {
+		return nil, fmt.Errorf("no polygon found")
+	}
+	return areas, nil
+}
+
+func (o geoJSON) areas() ([]area, error) {
+	switch o.Type {
+	case "Feature":
+		if o.Geometry == nil {
+			return nil, nil
+		}
+		return o.Geometry.areas()
+	case "FeatureCollection", "GeometryCollection":
+		var areas []area
+		for _, child := range append(o.Features, o.Geometries...) {
+			childAreas, err := child.areas()
+			if err != nil {
+				return nil, err
+			}
+			areas = append(areas, childAreas...)
+		}
+		return areas, nil
+	case "Polygon":
+		var rings [][][]float64
+		if err := json.Unmarshal(o.Coordinates, &rings); err != nil {
+			return nil, fmt.Errorf("invalid polygon: %w", err)
+		}
+		a, err := newArea(rings)
+		if err != nil {
+			return nil, err
+		}
+		return []area{a}, nil
+	case "MultiPolygon":
+		var polygons [][][][]float64
+		if err := json.Unmarshal(o.Coordinates, &polygons); err != nil {
+			return nil, fmt.Errorf("invalid multipolygon: %w", err)
+		}
+		areas := make([]area, 0, len(polygons))
+		for _, rings := range polygons {
+			a, err := newArea(rings)
+			if err != nil {
+				return nil, err
+			}
+			areas = append(areas, a)
+		}
+		return areas, nil
+	default:
+		return nil, nil
+	}
+}
+
+// newArea creates an area from GeoJSON rings: the outer ring followed by the holes,
+// with positions as [longitude, latitude]
+func newArea(rings [][][]float64) (area, error) {
+	if len(rings) == 0 {
+		return area{}, fmt.Errorf("polygon without rings")
+	}
+	polygons := make([]*geo.Polygon, len(rings))
+	for i, ring := range rings {
+		if len(ring) < 4 {
+			return area{}, fmt.Errorf("polygon ring with %d positions, at least 4 are required", len(ring))
+		}
+		points := make([]*geo.Point, 0, len(ring))
+		for _, position := range ring {
+			if len(position) < 2 {
+				return area{}, fmt.Errorf("invalid position %v", position)
+			}
+			if err := validateCoordinates(position[1], position[0]); err != nil {
+				return area{}, err
+			}
+			points = append(points, geo.NewPoint(position[1], position[0]))
+		}
+		polygons[i] = geo.NewPolygon(points)
+	}
+	return area{outer: polygons[0], holes: polygons[1:]}, nil
+}
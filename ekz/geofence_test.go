@@ -0,0 +1,122 @@
+package ekz
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var geofenceStation = ChargingStationConfig{Latitude: 47.3769, Longitude: 8.5417}
+
+func TestGeofence_Radius(t *testing.T) {
+	g, err := NewGeofence(geofenceStation)
+	require.NoError(t, err)
+	assert.Equal(t, "<= 100 m", g.Want())
+
+	// About 150 m north of the station
+	match := g.Match(47.37825, 8.5417, true, "")
+	assert.False(t, match.Inside)
+	assert.True(t, match.Known)
+	assert.InDelta(t, 150, match.Distance, 2)
+	assert.Equal(t, "150 m", match.Reason)
+
+	station := geofenceStation
+	station.Geofence.Radius = 200
+	g, err = NewGeofence(station)
+	require.NoError(t, err)
+	assert.True(t, g.Match(47.37825, 8.5417, true, "").Inside)
+
+	match = g.Match(0, 0, false, "")
+	assert.True(t, match.Inside, "an unknown location is assumed at the station")
+	assert.False(t, match.Known)
+}
+
+func TestGeofence_Polygon(t *testing.T) {
+	station := geofenceStation
+	// A garage north of the station, its entrance included
+	station.Geofence.Polygon = [][2]float64{
+		{47.3768, 8.5410},
+		{47.3790, 8.5410},
+		{47.3790, 8.5425},
+		{47.3768, 8.5425},
+	}
+	g, err := NewGeofence(station)
+	require.NoError(t, err)
+	assert.Equal(t, "inside the polygon", g.Want())
+
+	match := g.Match(47.37825, 8.5417, true, "")
+	assert.True(t, match.Inside)
+	assert.Contains(t, match.Reason, "inside the polygon")
+	assert.False(t, g.Match(47.3769, 8.5440, true, "").Inside, "the circle no longer applies")
+}
+
+func TestGeofence_GeoJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "garage.geojson")
+	// Positions are [longitude, latitude]; the second ring is a hole
+	require.NoError(t, os.WriteFile(path, []byte(`{
+  "type": "FeatureCollection",
+  "features": [
+    {"type": "Feature", "properties": {}, "geometry": {"type": "Point", "coordinates": [8.5417, 47.3769]}},
+    {"type": "Feature", "properties": {"name": "garage"}, "geometry": {
+      "type": "Polygon",
+      "coordinates": [
+        [[8.5410, 47.3768], [8.5425, 47.3768], [8.5425, 47.3790], [8.5410, 47.3790], [8.5410, 47.3768]],
+        [[8.5412, 47.3785], [8.5414, 47.3785], [8.5414, 47.3787], [8.5412, 47.3787], [8.5412, 47.3785]]
+      ]
+    }}
+  ]
+}`), 0o600))
+
+	station := geofenceStation
+	station.Geofence.GeoJSON = path
+	g, err := NewGeofence(station)
+	require.NoError(t, err)
+	assert.True(t, g.Match(47.37825, 8.5417, true, "").Inside)
+	assert.False(t, g.Match(47.3786, 8.5413, true, "").Inside, "inside the hole")
+	assert.False(t, g.Match(47.3800, 8.5417, true, "").Inside)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"type": "Point", "coordinates": [8.5417, 47.3769]}`), 0o600))
+	_, err = NewGeofence(station)
+	assert.ErrorContains(t, err, "no polygon found")
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"type": "Polygon", "coordinates": [[[8.5410, 147.3768], [8.5425, 47.3768], [8.5425, 47.3790], [8.5410, 147.3768]]]}`), 0o600))
+	_, err = NewGeofence(station)
+	assert.ErrorContains(t, err, "invalid latitude 147.3768")
+
+	station.Geofence.GeoJSON = filepath.Join(t.TempDir(), "missing.geojson")
+	_, err = NewGeofence(station)
+	assert.ErrorContains(t, err, "failed to read geofence")
+}
+
+func TestGeofence_TeslaMate(t *testing.T) {
+	station := geofenceStation
+	station.Geofence.TeslaMate = "Home"
+	g, err := NewGeofence(station)
+	require.NoError(t, err)
+	assert.Equal(t, `<= 100 m or in TeslaMate geofence "Home"`, g.Want())
+
+	// The GPS drifted out of the circle, but TeslaMate knows the car is home
+	match := g.Match(47.3800, 8.5417, true, "home")
+	assert.True(t, match.Inside)
+	assert.Equal(t, `in TeslaMate geofence "home"`, match.Reason)
+
+	// Another geofence without location: not at the station
+	match = g.Match(0, 0, false, "Work")
+	assert.False(t, match.Inside)
+	assert.True(t, match.Known)
+
+	// Another geofence with a location inside the circle: the location decides
+	assert.True(t, g.Match(47.3769, 8.5417, true, "Work").Inside)
+}
+
+func TestGeofenceConfig_Validate(t *testing.T) {
+	require.NoError(t, GeofenceConfig{}.Validate())
+	assert.ErrorContains(t, GeofenceConfig{Radius: -1}.Validate(), "radius can't be negative")
+	assert.ErrorContains(t, GeofenceConfig{Polygon: [][2]float64{{47, 8}, {47.1, 8}}}.Validate(), "at least 3 vertices")
+	assert.ErrorContains(t, GeofenceConfig{Polygon: [][2]float64{{47, 8}, {147, 8}, {47, 9}}}.Validate(), "invalid latitude 147 or longitude 8")
+	assert.ErrorContains(t, GeofenceConfig{Polygon: [][2]float64{{47, 8}, {47.1, 8}, {47.1, 8.1}}, GeoJSON: "garage.geojson"}.Validate(),
+		"exclusive")
+}
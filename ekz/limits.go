@@ -0,0 +1,105 @@
+package ekz
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SessionLimits bounds a charging session. Zero values mean no limit.
+type SessionLimits struct {
+	// Energy is the maximum charged energy in kWh
+	Energy float64 `json:"energy_kwh,omitempty"`
+	// Duration is the maximum session duration
+	Duration time.Duration `json:"duration,omitempty"`
+	// Cost is the maximum session cost in CHF
+	Cost float64 `json:"max_cost,omitempty"`
+}
+
+// IsZero returns true if no limit is set
+func (l SessionLimits) IsZero() bool {
+	return l.Energy <= 0 && l.Duration <= 0 && l.Cost <= 0
+}
+
+// Validate checks that no limit is negative
+func (l SessionLimits) Validate() error {
+	if l.Energy < 0 {
+		return fmt.Errorf("energy limit must not be negative")
+	}
+	if l.Duration < 0 {
+		return fmt.Errorf("duration limit must not be negative")
+	}
+	if l.Cost < 0 {
+		return fmt.Errorf("cost limit must not be negative")
+	}
+	return nil
+}
+
+// Conditions returns a stop condition for every limit that is set. The tariffs estimate the
+// cost until the backend reports it.
+func (l SessionLimits) Conditions(tariffs Tariffs) []StopCondition {
+	var conditions []StopCondition
+	if l.Energy > 0 {
+		conditions = append(conditions, EnergyLimit(l.Energy))
+	}
+	if l.Duration > 0 {
+		conditions = append(conditions, DurationLimit(l.Duration))
+	}
+	if l.Cost > 0 {
+		conditions = append(conditions, CostLimit(l.Cost, tariffs))
+	}
+	return conditions
+}
+
+func (l SessionLimits) String() string {
+	var parts []string
+	if l.Energy > 0 {
+		parts = append(parts, fmt.Sprintf("%.2f kWh", l.Energy))
+	}
+	if l.Duration > 0 {
+		parts = append(parts, l.Duration.String())
+	}
+	if l.Cost > 0 {
+		parts = append(parts, fmt.Sprintf("CHF %.2f", l.Cost))
+	}
+	if len(parts) == 0 {
+		return "no limits"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// EnergyLimit stops the session once the charged energy reaches kWh
+func EnergyLimit(kWh float64) StopCondition {
+	return func(liveData *LiveDataResponse) (string, bool) {
+		if liveData.ChargedEnergy < kWh {
+			return "", false
+		}
+		return fmt.Sprintf("energy limit of %.2f kWh reached (%.2f kWh)", kWh, liveData.ChargedEnergy), true
+	}
+}
+
+// DurationLimit stops the session once it has been running for d
+func DurationLimit(d time.Duration) StopCondition {
+	return func(liveData *LiveDataResponse) (string, bool) {
+		if liveData.Starttimestamp == 0 {
+			return "", false
+		}
+		elapsed := time.Since(liveData.StartTime())
+		if elapsed < d {
+			return "", false
+		}
+		return fmt.Sprintf("duration limit of %s reached (%s)", d, elapsed.Truncate(time.Second)), true
+	}
+}
+
+// CostLimit stops the session once its cost reaches chf. While the session runs, the cost
+// is estimated from the charged energy with the tariffs, see LiveDataResponse.Cost.
+func CostLimit(chf float64, tariffs Tariffs) StopCondition {
+	return func(liveData *LiveDataResponse) (string, bool) {
+		cost := liveData.Cost(tariffs)
+		if cost < chf {
+			return "", false
+		}
+		return fmt.Sprintf("cost limit of CHF %.2f reached (CHF %.2f)", chf, cost), true
+	}
+}
@@ -0,0 +1,91 @@
+package ekz
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionLimits_Conditions(t *testing.T) {
+	started := int(time.Now().Add(-90 * time.Minute).Unix())
+	tests := []struct {
+		name     string
+		limits   SessionLimits
+		liveData LiveDataResponse
+		stop     bool
+	}{
+		{
+			name:     "no limits",
+			liveData: LiveDataResponse{ChargedEnergy: 100},
+		},
+		{
+			name:     "energy below limit",
+			limits:   SessionLimits{Energy: 15},
+			liveData: LiveDataResponse{ChargedEnergy: 14.9},
+		},
+		{
+			name:     "energy limit reached",
+			limits:   SessionLimits{Energy: 15},
+			liveData: LiveDataResponse{ChargedEnergy: 15},
+			stop:     true,
+		},
+		{
+			name:     "duration below limit",
+			limits:   SessionLimits{Duration: 2 * time.Hour},
+			liveData: LiveDataResponse{Starttimestamp: started},
+		},
+		{
+			name:     "duration limit reached",
+			limits:   SessionLimits{Duration: time.Hour},
+			liveData: LiveDataResponse{Starttimestamp: started},
+			stop:     true,
+		},
+		{
+			name:     "duration without start time",
+			limits:   SessionLimits{Duration: time.Hour},
+			liveData: LiveDataResponse{},
+		},
+		{
+			name:   "estimated cost limit reached",
+			limits: SessionLimits{Cost: 5},
+			liveData: LiveDataResponse{
+				ChargedEnergy: 20,
+				CurrentTariff: CurrentTariff{TariffPrice: 25},
+			},
+			stop: true,
+		},
+		{
+			name:   "reported cost below limit",
+			limits: SessionLimits{Cost: 5},
+			liveData: LiveDataResponse{
+				ChargedEnergy: 20,
+				CurrentTariff: CurrentTariff{TariffPrice: 25},
+				Totalcost:     4.5,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stop := false
+			for _, condition := range tt.limits.Conditions(nil) {
+				if reason, ok := condition(&tt.liveData); ok {
+					assert.NotEmpty(t, reason)
+					stop = true
+				}
+			}
+			assert.Equal(t, tt.stop, stop)
+		})
+	}
+}
+
+func TestSessionLimits_Validate(t *testing.T) {
+	require.NoError(t, SessionLimits{Energy: 10, Duration: time.Hour, Cost: 5}.Validate())
+	assert.Error(t, SessionLimits{Energy: -1}.Validate())
+	assert.Error(t, SessionLimits{Duration: -time.Second}.Validate())
+	assert.Error(t, SessionLimits{Cost: -1}.Validate())
+	assert.True(t, SessionLimits{}.IsZero())
+	assert.Equal(t, "15.00 kWh, 2h0m0s, CHF 5.00", SessionLimits{Energy: 15, Duration: 2 * time.Hour, Cost: 5}.String())
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 type LiveDataRequest struct {
@@ -108,3 +110,85 @@ func toJson[T any](request T) (io.Reader, error) {
 	err := json.NewEncoder(buffer).Encode(request)
 	return buffer, err
 }
+
+// StartTime returns the start of the charging session
+func (l *LiveDataResponse) StartTime() time.Time {
+	return time.Unix(int64(l.Starttimestamp), 0)
+}
+
+// StopTime returns the end of the charging session, if it has ended
+func (l *LiveDataResponse) StopTime() (time.Time, bool) {
+	ts, ok := anyToFloat(l.Stoptimestamp)
+	if !ok || ts == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(ts), 0), true
+}
+
+// IsFinished returns true if the backend reports the session as ended
+func (l *LiveDataResponse) IsFinished() bool {
+	_, stopped := l.StopTime()
+	return stopped
+}
+
+// Energy returns the energy charged in the session in kWh, the total usage once the
+// backend reports it
+func (l *LiveDataResponse) Energy() float64 {
+	if total, ok := anyToFloat(l.Totalusage); ok && total > l.ChargedEnergy {
+		return total
+	}
+	return l.ChargedEnergy
+}
+
+// CostEstimated tells whether Cost is an estimate, as the backend doesn't report the total cost yet
+func (l *LiveDataResponse) CostEstimated() bool {
+	_, ok := anyToFloat(l.Totalcost)
+	return !ok
+}
+
+// Cost returns the session cost in CHF. While the backend doesn't report the total cost
+// yet, it is estimated from the charged energy with the tariffs in force during the session,
+// or with the current tariff price when the tariffs don't cover the session. The estimate
+// spreads the energy evenly over the session, it is off when the car stopped drawing power
+// long before the session closed.
+func (l *LiveDataResponse) Cost(tariffs Tariffs) float64 {
+	if cost, ok := anyToFloat(l.Totalcost); ok {
+		return cost
+	}
+	if estimate, ok := l.estimate(tariffs); ok {
+		return estimate.TotalCost
+	}
+	return l.Energy() * l.CurrentTariff.TariffPrice / 100
+}
+
+// estimate prices the charged energy with the tariffs from the start of the session to its stop,
+// or now while it runs
+func (l *LiveDataResponse) estimate(tariffs Tariffs) (*CostEstimate, bool) {
+	if len(tariffs) == 0 || l.Starttimestamp == 0 {
+		return nil, false
+	}
+	stop, ok := l.StopTime()
+	if !ok {
+		stop = time.Now()
+	}
+	estimate, err := tariffs.EstimateCost(l.StartTime(), stop, l.Energy())
+	return estimate, err == nil
+}
+
+// anyToFloat converts the loosely typed numeric fields of the live data
+func anyToFloat(v any) (float64, bool) {
+	switch value := v.(type) {
+	case float64:
+		return value, true
+	case int:
+		return float64(value), true
+	case json.Number:
+		f, err := value.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(value, 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,182 @@
+package ekz
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// PreflightCode identifies a problem found before starting a session
+type PreflightCode string
+
+const (
+	PreflightBoxNotFound          PreflightCode = "box_not_found"
+	PreflightConnectorNotFound    PreflightCode = "connector_not_found"
+	PreflightOffline              PreflightCode = "offline"
+	PreflightNoPermission         PreflightCode = "no_permission"
+	PreflightForeignSession       PreflightCode = "foreign_session"
+	PreflightConnectorUnavailable PreflightCode = "connector_unavailable"
+)
+
+// PreflightSeverity tells whether an issue prevents starting a session
+type PreflightSeverity string
+
+const (
+	PreflightError   PreflightSeverity = "error"
+	PreflightWarning PreflightSeverity = "warning"
+)
+
+// PreflightIssue is a problem found during the pre-flight checks
+type PreflightIssue struct {
+	Code     PreflightCode     `json:"code"`
+	Severity PreflightSeverity `json:"severity"`
+	Message  string            `json:"message"`
+}
+
+// PreflightResult is the state of a connector before starting a session
+type PreflightResult struct {
+	ChargeBox *ChargeBox `json:"charge_box,omitempty"`
+	Connector *Connector `json:"connector,omitempty"`
+	// Session is our own active session on the connector, if any
+	Session *LiveDataResponse `json:"session,omitempty"`
+	Issues  []PreflightIssue  `json:"issues,omitempty"`
+}
+
+// ErrPreflightFailed is wrapped by PreflightFailedError
+var ErrPreflightFailed = errors.New("pre-flight checks failed")
+
+// PreflightFailedError lists the issues that prevent starting a session
+type PreflightFailedError struct {
+	Issues []PreflightIssue
+}
+
+func (e *PreflightFailedError) Error() string {
+	messages := make([]string, 0, len(e.Issues))
+	for _, issue := range e.Issues {
+		messages = append(messages, issue.Message)
+	}
+	return fmt.Sprintf("%s: %s", ErrPreflightFailed, strings.Join(messages, "; "))
+}
+
+func (e *PreflightFailedError) Unwrap() error {
+	return ErrPreflightFailed
+}
+
+// busyConnectorStatuses are the OCPP statuses of a connector with a running transaction
+var busyConnectorStatuses = map[string]bool{
+	"CHARGING":      true,
+	"SUSPENDEDEV":   true,
+	"SUSPENDEDEVSE": true,
+}
+
+// unavailableConnectorStatuses are the OCPP statuses of a connector that may refuse a start.
+// FINISHING is a connector whose transaction ended, typically ours, with the cable still plugged in.
+var unavailableConnectorStatuses = map[string]bool{
+	"UNAVAILABLE": true,
+	"FAULTED":     true,
+	"RESERVED":    true,
+	"FINISHING":   true,
+}
+
+// Preflight fetches the state of the charging station and checks that a session can be started
+// on the connector: the box must be online, we must have permission to use the connector,
+// and the connector must not be in use by a session that isn't ours
+func (c *Client) Preflight(chargeBoxID string, connectorID int) (*PreflightResult, error) {
+	stations, err := c.GetUserChargingStations()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get charging stations: %w", err)
+	}
+
+	result := &PreflightResult{}
+	for _, station := range stations {
+		for i := range station.ChargeBoxes {
+			if station.ChargeBoxes[i].ChargeBoxID == chargeBoxID {
+				result.ChargeBox = &station.ChargeBoxes[i]
+			}
+		}
+	}
+	if result.ChargeBox == nil {
+		result.addIssue(PreflightBoxNotFound, PreflightError, fmt.Sprintf("charge box %s not found in your account", chargeBoxID))
+		return result, nil
+	}
+
+	for i := range result.ChargeBox.Connectors {
+		if result.ChargeBox.Connectors[i].ConnectorID == connectorID {
+			result.Connector = &result.ChargeBox.Connectors[i]
+		}
+	}
+
+	if !result.ChargeBox.Online {
+		result.addIssue(PreflightOffline, PreflightError, fmt.Sprintf("charge box %s is offline", chargeBoxID))
+	}
+
+	if result.Connector == nil {
+		result.addIssue(PreflightConnectorNotFound, PreflightError,
+			fmt.Sprintf("connector %d not found on charge box %s", connectorID, chargeBoxID))
+		return result, nil
+	}
+
+	if !result.Connector.HasPermission {
+		result.addIssue(PreflightNoPermission, PreflightError,
+			fmt.Sprintf("no permission to use connector %d of charge box %s", connectorID, chargeBoxID))
+	}
+
+	status := strings.ToUpper(result.Connector.Status)
+	if unavailableConnectorStatuses[status] {
+		result.addIssue(PreflightConnectorUnavailable, PreflightWarning,
+			fmt.Sprintf("connector %d is %s", connectorID, result.Connector.Status))
+	}
+
+	if busyConnectorStatuses[status] || strings.EqualFold(result.Connector.ChargingProcessStatus, "active") {
+		session, err := c.GetLiveData(chargeBoxID, connectorID, ConnectorStatusCharging)
+		switch {
+		case errors.Is(err, ErrTransactionNotFoundInTable):
+			result.addIssue(PreflightForeignSession, PreflightError,
+				fmt.Sprintf("connector %d is in use by another session", connectorID))
+		case err != nil:
+			return nil, fmt.Errorf("failed to get live data: %w", err)
+		default:
+			result.Session = session
+		}
+	}
+
+	return result, nil
+}
+
+// AlreadyCharging returns true if our own session is already running on the connector
+func (r *PreflightResult) AlreadyCharging() bool {
+	return r.Session != nil
+}
+
+// OK returns true if no issue prevents starting a session
+func (r *PreflightResult) OK() bool {
+	return r.Err() == nil
+}
+
+// Warnings returns the issues that don't prevent starting a session
+func (r *PreflightResult) Warnings() []PreflightIssue {
+	return r.issues(PreflightWarning)
+}
+
+// Err returns a *PreflightFailedError if an issue prevents starting a session
+func (r *PreflightResult) Err() error {
+	errs := r.issues(PreflightError)
+	if len(errs) == 0 {
+		return nil
+	}
+	return &PreflightFailedError{Issues: errs}
+}
+
+func (r *PreflightResult) issues(severity PreflightSeverity) []PreflightIssue {
+	var issues []PreflightIssue
+	for _, issue := range r.Issues {
+		if issue.Severity == severity {
+			issues = append(issues, issue)
+		}
+	}
+	return issues
+}
+
+func (r *PreflightResult) addIssue(code PreflightCode, severity PreflightSeverity, message string) {
+	r.Issues = append(r.Issues, PreflightIssue{Code: code, Severity: severity, Message: message})
+}
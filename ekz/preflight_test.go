@@ -0,0 +1,162 @@
+package ekz
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/h2non/gock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mockChargingStations(online bool, connector map[string]any) {
+	gock.New(Backend).
+		Post("/charging-stations/user-charging-stations").
+		Reply(http.StatusOK).
+		JSON(map[string]any{
+			"status_code": 200,
+			"data": map[string]any{
+				"charging_stations": []any{
+					map[string]any{
+						"chargeBoxes": []any{
+							map[string]any{
+								"chargeBoxId": "1234",
+								"online":      online,
+								"connectors":  []any{connector},
+							},
+						},
+					},
+				},
+			},
+		})
+}
+
+func newTestPreflightClient(t *testing.T) *Client {
+	c, err := New(&Config{})
+	require.NoError(t, err)
+	c.token = "foo"
+	return c
+}
+
+func TestClient_Preflight_OK(t *testing.T) {
+	defer gock.Off()
+	gock.New(Backend).
+		Post("/charging-stations/user-charging-stations").
+		Reply(http.StatusOK).
+		File("../resources/user-charging-stations.json")
+
+	result, err := newTestPreflightClient(t).Preflight("1234", 1)
+	require.NoError(t, err)
+	assert.True(t, result.OK())
+	assert.False(t, result.AlreadyCharging())
+	assert.Empty(t, result.Issues)
+	assert.Equal(t, "Typ 2 Kabel", result.Connector.ConnectorName)
+}
+
+func TestClient_Preflight_Issues(t *testing.T) {
+	tests := []struct {
+		name      string
+		boxID     string
+		online    bool
+		connector map[string]any
+		codes     []PreflightCode
+		ok        bool
+	}{
+		{
+			name:      "unknown box",
+			boxID:     "9999",
+			online:    true,
+			connector: map[string]any{"connectorId": 1, "status": "Available", "hasPermission": true},
+			codes:     []PreflightCode{PreflightBoxNotFound},
+		},
+		{
+			name:      "offline box",
+			boxID:     "1234",
+			online:    false,
+			connector: map[string]any{"connectorId": 1, "status": "Available", "hasPermission": true},
+			codes:     []PreflightCode{PreflightOffline},
+		},
+		{
+			name:      "unknown connector",
+			boxID:     "1234",
+			online:    true,
+			connector: map[string]any{"connectorId": 2, "status": "Available", "hasPermission": true},
+			codes:     []PreflightCode{PreflightConnectorNotFound},
+		},
+		{
+			name:      "no permission",
+			boxID:     "1234",
+			online:    true,
+			connector: map[string]any{"connectorId": 1, "status": "Available", "hasPermission": false},
+			codes:     []PreflightCode{PreflightNoPermission},
+		},
+		{
+			name:      "faulted connector is only a warning",
+			boxID:     "1234",
+			online:    true,
+			connector: map[string]any{"connectorId": 1, "status": "Faulted", "hasPermission": true},
+			codes:     []PreflightCode{PreflightConnectorUnavailable},
+			ok:        true,
+		},
+		{
+			// The session ended, e.g. stopped at the target, and the car is still plugged in
+			name:      "finishing connector without a live session is only a warning",
+			boxID:     "1234",
+			online:    true,
+			connector: map[string]any{"connectorId": 1, "status": "Finishing", "hasPermission": true},
+			codes:     []PreflightCode{PreflightConnectorUnavailable},
+			ok:        true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer gock.Off()
+			mockChargingStations(tt.online, tt.connector)
+
+			result, err := newTestPreflightClient(t).Preflight(tt.boxID, 1)
+			require.NoError(t, err)
+
+			var codes []PreflightCode
+			for _, issue := range result.Issues {
+				codes = append(codes, issue.Code)
+			}
+			assert.Equal(t, tt.codes, codes)
+			assert.Equal(t, tt.ok, result.OK())
+			if !tt.ok {
+				assert.True(t, errors.Is(result.Err(), ErrPreflightFailed))
+			}
+		})
+	}
+}
+
+func TestClient_Preflight_ForeignSession(t *testing.T) {
+	defer gock.Off()
+	mockChargingStations(true, map[string]any{"connectorId": 1, "status": "Charging", "hasPermission": true})
+	gock.New(Backend).
+		Post("/charging-stations/charging-live-data").
+		Reply(http.StatusNotFound).
+		File("../resources/live-data-fail.json")
+
+	result, err := newTestPreflightClient(t).Preflight("1234", 1)
+	require.NoError(t, err)
+	assert.False(t, result.OK())
+	require.Len(t, result.Issues, 1)
+	assert.Equal(t, PreflightForeignSession, result.Issues[0].Code)
+}
+
+func TestClient_Preflight_OwnSession(t *testing.T) {
+	defer gock.Off()
+	mockChargingStations(true, map[string]any{"connectorId": 1, "status": "Charging", "hasPermission": true})
+	gock.New(Backend).
+		Post("/charging-stations/charging-live-data").
+		Reply(http.StatusOK).
+		File("../resources/live-data.json")
+
+	result, err := newTestPreflightClient(t).Preflight("1234", 1)
+	require.NoError(t, err)
+	assert.True(t, result.OK())
+	assert.True(t, result.AlreadyCharging())
+	assert.Equal(t, 1, result.Session.TransactionID)
+}
@@ -14,7 +14,9 @@ import (
 // ScheduleScheduler manages charging based on predefined tariff schedules
 type ScheduleScheduler struct {
 	autostartFunc   func() error
+	highTariffFunc  func() error
 	highTariffTimes []TimeRange
+	tariffs         Tariffs
 	stopChan        chan struct{}
 	wg              sync.WaitGroup
 	mu              sync.RWMutex
@@ -105,25 +107,11 @@ func ParseTimeRangeString(s string) (TimeRange, error) {
 	if weekdaysPart != "" {
 		weekdayNames := strings.Split(weekdaysPart, ",")
 		for _, name := range weekdayNames {
-			name = strings.TrimSpace(name)
-			switch strings.ToLower(name) {
-			case "mon", "monday":
-				tr.Weekdays = append(tr.Weekdays, time.Monday)
-			case "tue", "tuesday":
-				tr.Weekdays = append(tr.Weekdays, time.Tuesday)
-			case "wed", "wednesday":
-				tr.Weekdays = append(tr.Weekdays, time.Wednesday)
-			case "thu", "thursday":
-				tr.Weekdays = append(tr.Weekdays, time.Thursday)
-			case "fri", "friday":
-				tr.Weekdays = append(tr.Weekdays, time.Friday)
-			case "sat", "saturday":
-				tr.Weekdays = append(tr.Weekdays, time.Saturday)
-			case "sun", "sunday":
-				tr.Weekdays = append(tr.Weekdays, time.Sunday)
-			default:
-				return tr, fmt.Errorf("invalid weekday: %s", name)
+			wd, err := parseWeekday(name)
+			if err != nil {
+				return tr, err
 			}
+			tr.Weekdays = append(tr.Weekdays, wd)
 		}
 	}
 
@@ -143,6 +131,46 @@ func NewScheduleScheduler(autostartFunc func() error, highTariffTimes []TimeRang
 	}
 }
 
+// NewTariffScheduler creates the scheduler of the tariff periods: the custom high tariff times
+// (in the ParseTimeRangeString format) replace the tariff versions when given, otherwise the
+// high tariff times of the version in force apply, falling back to the default EKZ schedule
+func NewTariffScheduler(autostartFunc func() error, highTariffTimes []string, tariffs Tariffs) (*ScheduleScheduler, error) {
+	if err := tariffs.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid tariffs config: %w", err)
+	}
+	if len(highTariffTimes) == 0 {
+		ss := NewScheduleScheduler(autostartFunc, DefaultHighTariffSchedule())
+		ss.SetTariffs(tariffs)
+		return ss, nil
+	}
+
+	var schedule []TimeRange
+	for _, timeStr := range highTariffTimes {
+		tr, err := ParseTimeRangeString(timeStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse high tariff time '%s': %w", timeStr, err)
+		}
+		schedule = append(schedule, tr)
+	}
+	return NewScheduleScheduler(autostartFunc, schedule), nil
+}
+
+// SetTariffs configures dated tariff versions. Whenever a version is in force,
+// its high tariff times replace the static schedule passed to NewScheduleScheduler.
+func (ss *ScheduleScheduler) SetTariffs(tariffs Tariffs) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	ss.tariffs = tariffs
+}
+
+// SetHighTariffFunc configures a function that is called instead of the autostart
+// function during high tariff periods, e.g. to handle emergency charging
+func (ss *ScheduleScheduler) SetHighTariffFunc(f func() error) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	ss.highTariffFunc = f
+}
+
 // Start begins the schedule-based scheduling
 func (ss *ScheduleScheduler) Start(ctx context.Context) error {
 	ss.mu.Lock()
@@ -210,14 +238,30 @@ func (ss *ScheduleScheduler) run(ctx context.Context) {
 
 // checkAndCharge checks if we should charge based on current time
 func (ss *ScheduleScheduler) checkAndCharge() {
-	now := time.Now()
+	ss.checkAndChargeAt(time.Now())
+}
+
+// checkAndChargeAt checks if we should charge at the given time
+func (ss *ScheduleScheduler) checkAndChargeAt(now time.Time) {
 	isHighTariff := ss.isHighTariffTime(now)
 
 	logrus.Debugf("Current time: %s, High tariff: %v", now.Format("2006-01-02 15:04:05 Mon"), isHighTariff)
 
 	// Only start charging during low tariff periods
 	if isHighTariff {
-		logrus.Debugf("Currently in high tariff period, skipping charge attempt")
+		ss.mu.RLock()
+		highTariffFunc := ss.highTariffFunc
+		ss.mu.RUnlock()
+
+		if highTariffFunc == nil {
+			logrus.Debugf("Currently in high tariff period, skipping charge attempt")
+			return
+		}
+
+		logrus.Debugf("Currently in high tariff period, running high tariff check")
+		if err := highTariffFunc(); err != nil {
+			logrus.Errorf("Failed to run high tariff check: %v", err)
+		}
 		return
 	}
 
@@ -229,8 +273,22 @@ func (ss *ScheduleScheduler) checkAndCharge() {
 	}
 }
 
-// isHighTariffTime checks if the given time falls within any high tariff period
+// IsHighTariffTime checks if the given time falls within any high tariff period
+func (ss *ScheduleScheduler) IsHighTariffTime(t time.Time) bool {
+	return ss.isHighTariffTime(t)
+}
+
+// isHighTariffTime checks if the given time falls within any high tariff period.
+// A configured tariff version in force at t takes precedence over the static schedule.
 func (ss *ScheduleScheduler) isHighTariffTime(t time.Time) bool {
+	ss.mu.RLock()
+	tariffs := ss.tariffs
+	ss.mu.RUnlock()
+
+	if tv, ok := tariffs.At(t); ok {
+		return tv.IsHighTariff(t)
+	}
+
 	for _, tr := range ss.highTariffTimes {
 		if ss.timeInRange(t, tr) {
 			return true
@@ -241,6 +299,11 @@ func (ss *ScheduleScheduler) isHighTariffTime(t time.Time) bool {
 
 // timeInRange checks if a given time falls within a TimeRange
 func (ss *ScheduleScheduler) timeInRange(t time.Time, tr TimeRange) bool {
+	return tr.Contains(t)
+}
+
+// Contains checks if a given time falls within the TimeRange
+func (tr TimeRange) Contains(t time.Time) bool {
 	// Check weekdays if specified
 	if len(tr.Weekdays) > 0 {
 		weekdayMatch := false
@@ -287,4 +350,33 @@ func (ss *ScheduleScheduler) GetNextLowTariffPeriod() time.Time {
 
 	// Fallback: return tomorrow at the same time
 	return now.Add(24 * time.Hour)
-}
\ No newline at end of file
+}
+
+// PeriodStart returns the start of the tariff period containing t, to the minute.
+// Periods longer than a week are cut off a week before t.
+func (ss *ScheduleScheduler) PeriodStart(t time.Time) time.Time {
+	start := t.Truncate(time.Minute)
+	high := ss.isHighTariffTime(start)
+	for i := 0; i < 7*24*60; i++ {
+		previous := start.Add(-time.Minute)
+		if ss.isHighTariffTime(previous) != high {
+			return start
+		}
+		start = previous
+	}
+	return start
+}
+
+// PeriodEnd returns the end of the tariff period containing t, to the minute: the first
+// minute of the next period. Periods longer than a week are cut off a week after t.
+func (ss *ScheduleScheduler) PeriodEnd(t time.Time) time.Time {
+	end := t.Truncate(time.Minute)
+	high := ss.isHighTariffTime(end)
+	for i := 0; i < 7*24*60; i++ {
+		end = end.Add(time.Minute)
+		if ss.isHighTariffTime(end) != high {
+			return end
+		}
+	}
+	return end
+}
@@ -346,6 +346,96 @@ func TestScheduleScheduler_GetNextLowTariffPeriod(t *testing.T) {
 	}
 }
 
+func TestScheduleScheduler_PeriodStart(t *testing.T) {
+	scheduler := NewScheduleScheduler(func() error { return nil }, DefaultHighTariffSchedule())
+
+	tests := []struct {
+		name     string
+		time     time.Time
+		expected time.Time
+	}{
+		{
+			name:     "weekday night before midnight",
+			time:     time.Date(2025, 1, 13, 22, 30, 15, 0, time.UTC), // Monday
+			expected: time.Date(2025, 1, 13, 20, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "weekday night after midnight",
+			time:     time.Date(2025, 1, 14, 3, 0, 0, 0, time.UTC), // Tuesday
+			expected: time.Date(2025, 1, 13, 20, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "weekday high tariff",
+			time:     time.Date(2025, 1, 14, 12, 0, 0, 0, time.UTC),
+			expected: time.Date(2025, 1, 14, 7, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "weekend",
+			time:     time.Date(2025, 1, 12, 10, 0, 0, 0, time.UTC), // Sunday
+			expected: time.Date(2025, 1, 10, 20, 0, 0, 0, time.UTC), // Friday evening
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, scheduler.PeriodStart(tt.time))
+		})
+	}
+}
+
+func TestScheduleScheduler_PeriodEnd(t *testing.T) {
+	scheduler := NewScheduleScheduler(func() error { return nil }, DefaultHighTariffSchedule())
+
+	tests := []struct {
+		name     string
+		time     time.Time
+		expected time.Time
+	}{
+		{
+			name:     "weekday night",
+			time:     time.Date(2025, 1, 13, 22, 30, 15, 0, time.UTC), // Monday
+			expected: time.Date(2025, 1, 14, 7, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "weekday high tariff",
+			time:     time.Date(2025, 1, 14, 12, 0, 0, 0, time.UTC),
+			expected: time.Date(2025, 1, 14, 20, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "weekend",
+			time:     time.Date(2025, 1, 11, 10, 0, 0, 0, time.UTC), // Saturday
+			expected: time.Date(2025, 1, 13, 7, 0, 0, 0, time.UTC),  // Monday morning
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, scheduler.PeriodEnd(tt.time))
+		})
+	}
+}
+
+func TestNewTariffScheduler(t *testing.T) {
+	// Summer version in force: Monday 9:00 is low tariff, 11:00 high tariff
+	nine := time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC)
+	eleven := time.Date(2025, 6, 2, 11, 0, 0, 0, time.UTC)
+	ss, err := NewTariffScheduler(nil, nil, testTariffs())
+	require.NoError(t, err)
+	assert.False(t, ss.IsHighTariffTime(nine))
+	assert.True(t, ss.IsHighTariffTime(eleven))
+
+	// Custom high tariff times replace the versions
+	ss, err = NewTariffScheduler(nil, []string{"8:00-10:00"}, testTariffs())
+	require.NoError(t, err)
+	assert.True(t, ss.IsHighTariffTime(nine))
+	assert.False(t, ss.IsHighTariffTime(eleven))
+
+	_, err = NewTariffScheduler(nil, []string{"8:00"}, nil)
+	assert.Error(t, err)
+	_, err = NewTariffScheduler(nil, nil, Tariffs{{ValidFrom: "someday"}})
+	assert.Error(t, err)
+}
+
 func TestScheduleScheduler_StartStop(t *testing.T) {
 	scheduler := NewScheduleScheduler(func() error {
 		return nil
@@ -373,4 +463,30 @@ func TestScheduleScheduler_StartStop(t *testing.T) {
 	// Stopping again should be safe
 	scheduler.Stop()
 	assert.False(t, scheduler.IsRunning())
-}
\ No newline at end of file
+}
+
+func TestScheduleScheduler_HighTariffFunc(t *testing.T) {
+	autostartCalls := 0
+	highTariffCalls := 0
+	scheduler := NewScheduleScheduler(func() error {
+		autostartCalls++
+		return nil
+	}, DefaultHighTariffSchedule())
+
+	// Without a high tariff function nothing is called during high tariff
+	scheduler.checkAndChargeAt(time.Date(2025, 1, 13, 9, 0, 0, 0, time.UTC)) // Monday morning
+	assert.Equal(t, 0, autostartCalls)
+
+	scheduler.SetHighTariffFunc(func() error {
+		highTariffCalls++
+		return nil
+	})
+
+	scheduler.checkAndChargeAt(time.Date(2025, 1, 13, 9, 0, 0, 0, time.UTC)) // Monday morning
+	assert.Equal(t, 0, autostartCalls)
+	assert.Equal(t, 1, highTariffCalls)
+
+	scheduler.checkAndChargeAt(time.Date(2025, 1, 13, 22, 0, 0, 0, time.UTC)) // Monday night
+	assert.Equal(t, 1, autostartCalls)
+	assert.Equal(t, 1, highTariffCalls)
+}
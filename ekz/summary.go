@@ -0,0 +1,58 @@
+package ekz
+
+import "time"
+
+// SessionSummary is the outcome of a charging session. Energies are in kWh, costs in CHF.
+type SessionSummary struct {
+	TransactionID    int           `json:"transaction_id"`
+	ChargeBoxID      string        `json:"charge_box_id"`
+	Start            time.Time     `json:"start"`
+	Stop             time.Time     `json:"stop"`
+	Duration         time.Duration `json:"-"`
+	DurationSeconds  int64         `json:"duration_seconds"`
+	Energy           float64       `json:"energy_kwh"`
+	HighTariffEnergy float64       `json:"high_tariff_energy_kwh"`
+	LowTariffEnergy  float64       `json:"low_tariff_energy_kwh"`
+	HighTariffCost   float64       `json:"high_tariff_cost"`
+	LowTariffCost    float64       `json:"low_tariff_cost"`
+	TotalCost        float64       `json:"total_cost"`
+	// CostEstimated is set when the backend didn't report the total cost, see LiveDataResponse.Cost
+	CostEstimated bool `json:"cost_estimated,omitempty"`
+}
+
+// Summary returns the summary of the session. While the session is running, the stop
+// time is now and the total cost is an estimate. When the backend doesn't split the
+// energy by tariff, the split is estimated from the tariffs.
+func (l *LiveDataResponse) Summary(tariffs Tariffs) SessionSummary {
+	stop, ok := l.StopTime()
+	if !ok {
+		stop = time.Now()
+	}
+
+	summary := SessionSummary{
+		TransactionID: l.TransactionID,
+		ChargeBoxID:   l.ChargeBoxID,
+		Start:         l.StartTime(),
+		Stop:          stop,
+		Energy:        l.Energy(),
+		TotalCost:     l.Cost(tariffs),
+		CostEstimated: l.CostEstimated(),
+	}
+	var highReported, lowReported bool
+	summary.HighTariffEnergy, highReported = anyToFloat(l.Hightariffusage)
+	summary.LowTariffEnergy, lowReported = anyToFloat(l.Lowtariffusage)
+	summary.HighTariffCost, _ = anyToFloat(l.Hightariffcost)
+	summary.LowTariffCost, _ = anyToFloat(l.Lowtariffcost)
+	if !highReported && !lowReported {
+		if estimate, ok := l.estimate(tariffs); ok {
+			summary.HighTariffEnergy = estimate.HighTariffEnergy
+			summary.LowTariffEnergy = estimate.LowTariffEnergy
+			summary.HighTariffCost = estimate.HighTariffCost
+			summary.LowTariffCost = estimate.LowTariffCost
+		}
+	}
+
+	summary.Duration = summary.Stop.Sub(summary.Start)
+	summary.DurationSeconds = int64(summary.Duration.Seconds())
+	return summary
+}
@@ -0,0 +1,100 @@
+package ekz
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLiveDataResponse_Summary(t *testing.T) {
+	var liveData LiveDataResponse
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"transaction_id": 42,
+		"chargeBoxId": "1234",
+		"starttimestamp": 1701209658,
+		"stoptimestamp": 1701216858,
+		"charged_energy": 15.5,
+		"hightariffusage": 5.5,
+		"lowtariffusage": 10.0,
+		"hightariffcost": "1.30",
+		"lowtariffcost": 1.94,
+		"totalcost": 3.24
+	}`), &liveData))
+
+	summary := liveData.Summary(nil)
+	assert.Equal(t, 42, summary.TransactionID)
+	assert.Equal(t, 2*time.Hour, summary.Duration)
+	assert.Equal(t, int64(7200), summary.DurationSeconds)
+	assert.Equal(t, 15.5, summary.Energy)
+	assert.Equal(t, 5.5, summary.HighTariffEnergy)
+	assert.Equal(t, 10.0, summary.LowTariffEnergy)
+	assert.Equal(t, 1.30, summary.HighTariffCost)
+	assert.Equal(t, 1.94, summary.LowTariffCost)
+	assert.Equal(t, 3.24, summary.TotalCost)
+	assert.False(t, summary.CostEstimated)
+}
+
+func TestLiveDataResponse_Summary_Running(t *testing.T) {
+	liveData := LiveDataResponse{
+		Starttimestamp: int(time.Now().Add(-time.Hour).Unix()),
+		ChargedEnergy:  10,
+		CurrentTariff:  CurrentTariff{TariffPrice: 20},
+	}
+
+	summary := liveData.Summary(nil)
+	assert.InDelta(t, time.Hour.Seconds(), summary.Duration.Seconds(), 5)
+	assert.InDelta(t, 2.0, summary.TotalCost, 0.001)
+}
+
+func TestLiveDataResponse_Summary_EstimatedFromTariffs(t *testing.T) {
+	start := time.Date(2024, 6, 3, 19, 0, 0, 0, time.UTC)
+	liveData := LiveDataResponse{
+		Starttimestamp: int(start.Unix()),
+		Stoptimestamp:  int(start.Add(2 * time.Hour).Unix()),
+		ChargedEnergy:  10,
+		CurrentTariff:  CurrentTariff{TariffPrice: 50},
+	}
+	tariffs := testTariffs()
+	estimate, err := tariffs.EstimateCost(liveData.StartTime(), start.Add(2*time.Hour), 10)
+	require.NoError(t, err)
+
+	summary := liveData.Summary(tariffs)
+	assert.InDelta(t, estimate.TotalCost, summary.TotalCost, 0.001)
+	assert.True(t, summary.CostEstimated)
+	assert.InDelta(t, estimate.HighTariffEnergy, summary.HighTariffEnergy, 0.001)
+	assert.InDelta(t, estimate.LowTariffEnergy, summary.LowTariffEnergy, 0.001)
+	assert.InDelta(t, estimate.TotalCost, liveData.Cost(tariffs), 0.001)
+
+	// Without a tariff covering the session, the current price is used
+	assert.InDelta(t, 5.0, liveData.Cost(nil), 0.001)
+	liveData.Starttimestamp = int(time.Date(2023, 6, 3, 19, 0, 0, 0, time.UTC).Unix())
+	assert.InDelta(t, 5.0, liveData.Cost(tariffs), 0.001)
+
+	// The cost reported by the backend wins
+	liveData.Totalcost = 3.24
+	assert.Equal(t, 3.24, liveData.Cost(tariffs))
+	assert.False(t, liveData.CostEstimated())
+}
+
+func TestLiveDataResponse_Summary_EstimatedFromTotalUsage(t *testing.T) {
+	start := time.Date(2024, 6, 3, 19, 0, 0, 0, time.UTC)
+	liveData := LiveDataResponse{
+		Starttimestamp: int(start.Unix()),
+		Stoptimestamp:  int(start.Add(2 * time.Hour).Unix()),
+		ChargedEnergy:  10,
+		Totalusage:     12,
+	}
+	tariffs := testTariffs()
+	estimate, err := tariffs.EstimateCost(liveData.StartTime(), start.Add(2*time.Hour), 12)
+	require.NoError(t, err)
+
+	// The energy shown is the energy priced
+	summary := liveData.Summary(tariffs)
+	assert.Equal(t, 12.0, summary.Energy)
+	assert.InDelta(t, estimate.TotalCost, summary.TotalCost, 0.001)
+	assert.InDelta(t, 12.0, summary.HighTariffEnergy+summary.LowTariffEnergy, 0.001)
+	assert.True(t, summary.CostEstimated)
+}
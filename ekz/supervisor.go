@@ -0,0 +1,123 @@
+package ekz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	DefaultSupervisorInterval = time.Minute
+	// supervisorMaxMisses is the number of polls to wait for a session to appear in the live data
+	supervisorMaxMisses = 10
+)
+
+// StopCondition decides whether a running session should be stopped.
+// It returns true and a human-readable reason to stop the session.
+type StopCondition func(liveData *LiveDataResponse) (reason string, stop bool)
+
+// SessionResult describes how a supervised session ended
+type SessionResult struct {
+	// Reason is the reason why the session ended
+	Reason string
+	// Stopped is true if the supervisor stopped the session
+	Stopped bool
+	// LiveData is the last live data seen for the session, nil if it was never seen
+	LiveData *LiveDataResponse
+}
+
+// SessionSupervisor follows a charging session through the live data and
+// stops it as soon as one of its stop conditions is met
+type SessionSupervisor struct {
+	client      *Client
+	chargeBoxID string
+	connectorID int
+	interval    time.Duration
+	conditions  []StopCondition
+	progress    func(liveData *LiveDataResponse)
+}
+
+// NewSessionSupervisor creates a supervisor for the session on the given connector
+func NewSessionSupervisor(client *Client, chargeBoxID string, connectorID int) *SessionSupervisor {
+	return &SessionSupervisor{
+		client:      client,
+		chargeBoxID: chargeBoxID,
+		connectorID: connectorID,
+		interval:    DefaultSupervisorInterval,
+	}
+}
+
+// SetInterval sets how often the live data is polled
+func (s *SessionSupervisor) SetInterval(interval time.Duration) {
+	s.interval = interval
+}
+
+// AddStopCondition adds a condition that stops the session when met
+func (s *SessionSupervisor) AddStopCondition(condition StopCondition) {
+	s.conditions = append(s.conditions, condition)
+}
+
+// SetProgress sets a function called with every live data poll of the running session
+func (s *SessionSupervisor) SetProgress(progress func(liveData *LiveDataResponse)) {
+	s.progress = progress
+}
+
+// Run polls the live data until the session ends, either because a stop condition
+// is met and the supervisor stops it, or because it ends on its own
+func (s *SessionSupervisor) Run(ctx context.Context) (*SessionResult, error) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	var last *LiveDataResponse
+	misses := 0
+	for {
+		liveData, err := s.client.GetLiveData(s.chargeBoxID, s.connectorID, ConnectorStatusCharging)
+		switch {
+		case errors.Is(err, ErrTransactionNotFoundInTable):
+			if last != nil {
+				return &SessionResult{Reason: "session ended", LiveData: last}, nil
+			}
+			misses++
+			if misses >= supervisorMaxMisses {
+				return nil, fmt.Errorf("no session found on box %s connector %d", s.chargeBoxID, s.connectorID)
+			}
+			log.Debugf("Session not visible in live data yet (%d/%d)", misses, supervisorMaxMisses)
+		case err != nil:
+			log.Warnf("Failed to get live data, retrying: %v", err)
+		case liveData.IsFinished():
+			return &SessionResult{Reason: "session ended", LiveData: liveData}, nil
+		default:
+			last = liveData
+			log.Debugf("Supervising session %d: %.2f kW, %.2f kWh", liveData.TransactionID, liveData.Power, liveData.ChargedEnergy)
+			if s.progress != nil {
+				s.progress(liveData)
+			}
+			for _, condition := range s.conditions {
+				if reason, stop := condition(liveData); stop {
+					log.Infof("Stopping session %d: %s", liveData.TransactionID, reason)
+					final, err := s.client.StopAndVerify(ctx, s.chargeBoxID, s.connectorID, StopOptions{
+						PollInterval: min(s.interval, DefaultStopPollInterval),
+					})
+					switch {
+					case errors.Is(err, ErrNoActiveSession):
+						return &SessionResult{Reason: "session ended", LiveData: last}, nil
+					case errors.Is(err, ErrStopNotConfirmed):
+						log.Warnf("Session %d keeps running after the stop, retrying: %v", liveData.TransactionID, err)
+					case err != nil:
+						return nil, fmt.Errorf("failed to stop session: %w", err)
+					default:
+						return &SessionResult{Reason: reason, Stopped: true, LiveData: final}, nil
+					}
+					break
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
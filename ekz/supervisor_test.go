@@ -0,0 +1,109 @@
+package ekz
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/h2non/gock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mockLiveData(energy float64) {
+	gock.New(Backend).
+		Post("/charging-stations/charging-live-data").
+		Reply(http.StatusOK).
+		JSON(map[string]any{
+			"transaction_id": 1,
+			"status":         "ONGOING",
+			"power":          11.0,
+			"charged_energy": energy,
+			"current_tariff": map[string]any{"tariff_status": "low", "tariff_price": 20.0},
+		})
+}
+
+func TestSessionSupervisor_StopCondition(t *testing.T) {
+	defer gock.Off()
+
+	mockLiveData(5)
+	mockLiveData(12)
+	mockLiveData(12)
+	gock.New(Backend).
+		Post("/saascharge/remote-stop").
+		BodyString(`{"charge_box_id":"1234","connector_id":1}`).
+		Reply(http.StatusOK).
+		File("../resources/remote-start.json")
+	gock.New(Backend).
+		Post("/charging-stations/charging-live-data").
+		Reply(http.StatusNotFound).
+		File("../resources/live-data-fail.json")
+	gock.New(Backend).
+		Post("/charging-stations/charging-live-data").
+		Reply(http.StatusNotFound).
+		File("../resources/live-data-fail.json")
+
+	c, err := New(&Config{})
+	require.NoError(t, err)
+	c.token = "foo"
+
+	supervisor := NewSessionSupervisor(c, "1234", 1)
+	supervisor.SetInterval(10 * time.Millisecond)
+	supervisor.AddStopCondition(func(liveData *LiveDataResponse) (string, bool) {
+		return "energy limit reached", liveData.ChargedEnergy >= 10
+	})
+
+	result, err := supervisor.Run(context.Background())
+	require.NoError(t, err)
+	assert.True(t, result.Stopped)
+	assert.Equal(t, "energy limit reached", result.Reason)
+	assert.Equal(t, 12.0, result.LiveData.ChargedEnergy)
+	assert.InDelta(t, 2.4, result.LiveData.Cost(nil), 0.001)
+	assert.True(t, gock.IsDone())
+}
+
+func TestSessionSupervisor_SessionEnded(t *testing.T) {
+	defer gock.Off()
+
+	mockLiveData(5)
+	gock.New(Backend).
+		Post("/charging-stations/charging-live-data").
+		Reply(http.StatusNotFound).
+		File("../resources/live-data-fail.json")
+
+	c, err := New(&Config{})
+	require.NoError(t, err)
+	c.token = "foo"
+
+	supervisor := NewSessionSupervisor(c, "1234", 1)
+	supervisor.SetInterval(10 * time.Millisecond)
+
+	result, err := supervisor.Run(context.Background())
+	require.NoError(t, err)
+	assert.False(t, result.Stopped)
+	assert.Equal(t, "session ended", result.Reason)
+	assert.Equal(t, 5.0, result.LiveData.ChargedEnergy)
+}
+
+func TestSessionSupervisor_Cancel(t *testing.T) {
+	defer gock.Off()
+
+	gock.New(Backend).
+		Post("/charging-stations/charging-live-data").
+		Persist().
+		Reply(http.StatusOK).
+		File("../resources/live-data.json")
+
+	c, err := New(&Config{})
+	require.NoError(t, err)
+	c.token = "foo"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	supervisor := NewSessionSupervisor(c, "1234", 1)
+	supervisor.SetInterval(10 * time.Millisecond)
+	_, err = supervisor.Run(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,199 @@
+package ekz
+
+import (
+	"fmt"
+	"time"
+)
+
+const tariffDateLayout = "2006-01-02"
+
+// TariffVersion describes a named HT/NT tariff and the period in which it is in force.
+// Prices are expressed in Rp./kWh, like the tariff_price returned by the EKZ backend.
+type TariffVersion struct {
+	Name string `yaml:"name"`
+	// ValidFrom and ValidUntil are inclusive dates (YYYY-MM-DD). Empty means unbounded.
+	ValidFrom  string `yaml:"valid_from"`
+	ValidUntil string `yaml:"valid_until"`
+	// Months restricts the version to some months of the year (1-12), e.g. for summer/winter variants.
+	// Empty means all months.
+	Months []int `yaml:"months"`
+	// HighTariffTimes uses the --high-tariff-times format ("7:00-20:00:Mon,Tue,Wed,Thu,Fri").
+	// Empty means the default EKZ high tariff schedule.
+	HighTariffTimes []string `yaml:"high_tariff_times"`
+	HighPrice       float64  `yaml:"high_price"`
+	LowPrice        float64  `yaml:"low_price"`
+}
+
+// Tariffs is a list of tariff versions, selected by date
+type Tariffs []TariffVersion
+
+// CostEstimate is the cost of a charging session split by tariff. Costs are in CHF.
+type CostEstimate struct {
+	HighTariffEnergy float64
+	LowTariffEnergy  float64
+	HighTariffCost   float64
+	LowTariffCost    float64
+	TotalCost        float64
+}
+
+// Validate checks that all tariff versions are well-formed
+func (ts Tariffs) Validate() error {
+	for i, tv := range ts {
+		name := tv.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+		if tv.ValidFrom != "" {
+			if _, err := time.Parse(tariffDateLayout, tv.ValidFrom); err != nil {
+				return fmt.Errorf("tariff %s: invalid valid_from %q", name, tv.ValidFrom)
+			}
+		}
+		if tv.ValidUntil != "" {
+			if _, err := time.Parse(tariffDateLayout, tv.ValidUntil); err != nil {
+				return fmt.Errorf("tariff %s: invalid valid_until %q", name, tv.ValidUntil)
+			}
+		}
+		if tv.ValidFrom != "" && tv.ValidUntil != "" && tv.ValidUntil < tv.ValidFrom {
+			return fmt.Errorf("tariff %s: valid_until is before valid_from", name)
+		}
+		for _, m := range tv.Months {
+			if m < 1 || m > 12 {
+				return fmt.Errorf("tariff %s: invalid month %d", name, m)
+			}
+		}
+		if _, err := tv.HighTariffRanges(); err != nil {
+			return fmt.Errorf("tariff %s: %w", name, err)
+		}
+		if tv.HighPrice < 0 || tv.LowPrice < 0 {
+			return fmt.Errorf("tariff %s: prices must not be negative", name)
+		}
+	}
+	return nil
+}
+
+// At returns the tariff version in force at t.
+// When several versions match, the one with the latest valid_from wins;
+// on a tie, a seasonal version (with months) wins over a year-round one.
+func (ts Tariffs) At(t time.Time) (*TariffVersion, bool) {
+	var best *TariffVersion
+	for i := range ts {
+		tv := &ts[i]
+		if !tv.appliesTo(t) {
+			continue
+		}
+		if best == nil ||
+			tv.ValidFrom > best.ValidFrom ||
+			(tv.ValidFrom == best.ValidFrom && len(tv.Months) > 0 && len(best.Months) == 0) {
+			best = tv
+		}
+	}
+	return best, best != nil
+}
+
+// EstimateCost splits energyKWh evenly over [start, end) and prices every minute
+// with the tariff version that was in force at that time
+func (ts Tariffs) EstimateCost(start, end time.Time, energyKWh float64) (*CostEstimate, error) {
+	if !end.After(start) {
+		return nil, fmt.Errorf("end time must be after start time")
+	}
+
+	minutes := int(end.Sub(start).Minutes())
+	if minutes == 0 {
+		minutes = 1
+	}
+	energyPerMinute := energyKWh / float64(minutes)
+
+	parsed := make(map[*TariffVersion][]TimeRange)
+	var estimate CostEstimate
+	for i := 0; i < minutes; i++ {
+		t := start.Add(time.Duration(i) * time.Minute)
+		tv, ok := ts.At(t)
+		if !ok {
+			return nil, fmt.Errorf("no tariff configured for %s", t.Format(tariffDateLayout))
+		}
+
+		ranges, ok := parsed[tv]
+		if !ok {
+			var err error
+			ranges, err = tv.HighTariffRanges()
+			if err != nil {
+				return nil, err
+			}
+			parsed[tv] = ranges
+		}
+
+		if inAnyRange(t, ranges) {
+			estimate.HighTariffEnergy += energyPerMinute
+			estimate.HighTariffCost += energyPerMinute * tv.HighPrice / 100
+		} else {
+			estimate.LowTariffEnergy += energyPerMinute
+			estimate.LowTariffCost += energyPerMinute * tv.LowPrice / 100
+		}
+	}
+	estimate.TotalCost = estimate.HighTariffCost + estimate.LowTariffCost
+	return &estimate, nil
+}
+
+// HighTariffRanges parses the high tariff times of the version
+func (tv *TariffVersion) HighTariffRanges() ([]TimeRange, error) {
+	if len(tv.HighTariffTimes) == 0 {
+		return DefaultHighTariffSchedule(), nil
+	}
+
+	ranges := make([]TimeRange, 0, len(tv.HighTariffTimes))
+	for _, s := range tv.HighTariffTimes {
+		tr, err := ParseTimeRangeString(s)
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, tr)
+	}
+	return ranges, nil
+}
+
+// IsHighTariff checks if t falls within the high tariff times of the version.
+// Invalid time ranges are ignored, use Tariffs.Validate to detect them.
+func (tv *TariffVersion) IsHighTariff(t time.Time) bool {
+	ranges, err := tv.HighTariffRanges()
+	if err != nil {
+		return false
+	}
+	return inAnyRange(t, ranges)
+}
+
+// PriceAt returns the price in Rp./kWh applicable at t
+func (tv *TariffVersion) PriceAt(t time.Time) float64 {
+	if tv.IsHighTariff(t) {
+		return tv.HighPrice
+	}
+	return tv.LowPrice
+}
+
+// appliesTo checks the validity dates and months of the version against t
+func (tv *TariffVersion) appliesTo(t time.Time) bool {
+	date := t.Format(tariffDateLayout)
+	if tv.ValidFrom != "" && date < tv.ValidFrom {
+		return false
+	}
+	if tv.ValidUntil != "" && date > tv.ValidUntil {
+		return false
+	}
+	if len(tv.Months) == 0 {
+		return true
+	}
+	for _, m := range tv.Months {
+		if time.Month(m) == t.Month() {
+			return true
+		}
+	}
+	return false
+}
+
+func inAnyRange(t time.Time, ranges []TimeRange) bool {
+	for _, tr := range ranges {
+		if tr.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package ekz
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testTariffs() Tariffs {
+	return Tariffs{
+		{
+			Name:       "2024",
+			ValidFrom:  "2024-01-01",
+			ValidUntil: "2024-12-31",
+			HighPrice:  30,
+			LowPrice:   20,
+		},
+		{
+			Name:      "2025",
+			ValidFrom: "2025-01-01",
+			HighPrice: 25,
+			LowPrice:  15,
+		},
+		{
+			Name:            "2025-summer",
+			ValidFrom:       "2025-01-01",
+			Months:          []int{4, 5, 6, 7, 8, 9},
+			HighTariffTimes: []string{"10:00-16:00:Mon,Tue,Wed,Thu,Fri"},
+			HighPrice:       22,
+			LowPrice:        12,
+		},
+	}
+}
+
+func TestTariffs_Validate(t *testing.T) {
+	require.NoError(t, testTariffs().Validate())
+
+	tests := []struct {
+		name    string
+		tariffs Tariffs
+	}{
+		{name: "invalid date", tariffs: Tariffs{{ValidFrom: "2025-13-01"}}},
+		{name: "reversed dates", tariffs: Tariffs{{ValidFrom: "2025-02-01", ValidUntil: "2025-01-01"}}},
+		{name: "invalid month", tariffs: Tariffs{{Months: []int{13}}}},
+		{name: "invalid time range", tariffs: Tariffs{{HighTariffTimes: []string{"invalid"}}}},
+		{name: "negative price", tariffs: Tariffs{{HighPrice: -1}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Error(t, tt.tariffs.Validate())
+		})
+	}
+}
+
+func TestTariffs_At(t *testing.T) {
+	tariffs := testTariffs()
+
+	tests := []struct {
+		name     string
+		time     time.Time
+		expected string
+	}{
+		{name: "2024 version", time: time.Date(2024, 6, 3, 9, 0, 0, 0, time.UTC), expected: "2024"},
+		{name: "last day of 2024", time: time.Date(2024, 12, 31, 23, 59, 0, 0, time.UTC), expected: "2024"},
+		{name: "winter 2025", time: time.Date(2025, 1, 13, 9, 0, 0, 0, time.UTC), expected: "2025"},
+		{name: "summer 2025", time: time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC), expected: "2025-summer"},
+		{name: "before any tariff", time: time.Date(2023, 6, 2, 9, 0, 0, 0, time.UTC), expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tv, ok := tariffs.At(tt.time)
+			if tt.expected == "" {
+				assert.False(t, ok)
+				return
+			}
+			require.True(t, ok)
+			assert.Equal(t, tt.expected, tv.Name)
+		})
+	}
+}
+
+func TestTariffVersion_PriceAt(t *testing.T) {
+	tariffs := testTariffs()
+
+	// Monday 9:00 in winter: default schedule, high tariff
+	winter := time.Date(2025, 1, 13, 9, 0, 0, 0, time.UTC)
+	tv, ok := tariffs.At(winter)
+	require.True(t, ok)
+	assert.True(t, tv.IsHighTariff(winter))
+	assert.Equal(t, 25.0, tv.PriceAt(winter))
+
+	// Monday 9:00 in summer: custom schedule starts at 10:00, low tariff
+	summer := time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC)
+	tv, ok = tariffs.At(summer)
+	require.True(t, ok)
+	assert.False(t, tv.IsHighTariff(summer))
+	assert.Equal(t, 12.0, tv.PriceAt(summer))
+}
+
+func TestTariffs_EstimateCost(t *testing.T) {
+	tariffs := testTariffs()
+
+	// Monday 2024 19:00-21:00, 10 kWh: one hour high tariff, one hour low tariff
+	start := time.Date(2024, 6, 3, 19, 0, 0, 0, time.UTC)
+	estimate, err := tariffs.EstimateCost(start, start.Add(2*time.Hour), 10)
+	require.NoError(t, err)
+	assert.InDelta(t, 5, estimate.HighTariffEnergy, 0.001)
+	assert.InDelta(t, 5, estimate.LowTariffEnergy, 0.001)
+	assert.InDelta(t, 1.5, estimate.HighTariffCost, 0.001)
+	assert.InDelta(t, 1.0, estimate.LowTariffCost, 0.001)
+	assert.InDelta(t, 2.5, estimate.TotalCost, 0.001)
+
+	// Across the new year, each half uses its own version
+	start = time.Date(2024, 12, 31, 23, 0, 0, 0, time.UTC)
+	estimate, err = tariffs.EstimateCost(start, start.Add(2*time.Hour), 10)
+	require.NoError(t, err)
+	assert.InDelta(t, 10, estimate.LowTariffEnergy, 0.001)
+	assert.InDelta(t, 5*0.20+5*0.15, estimate.TotalCost, 0.001)
+
+	// No tariff known
+	start = time.Date(2023, 6, 3, 19, 0, 0, 0, time.UTC)
+	_, err = tariffs.EstimateCost(start, start.Add(time.Hour), 10)
+	assert.Error(t, err)
+}
+
+func TestScheduleScheduler_SetTariffs(t *testing.T) {
+	scheduler := NewScheduleScheduler(func() error { return nil }, DefaultHighTariffSchedule())
+	scheduler.SetTariffs(testTariffs())
+
+	// Summer version: Monday 9:00 is low tariff, 11:00 high tariff
+	assert.False(t, scheduler.isHighTariffTime(time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC)))
+	assert.True(t, scheduler.isHighTariffTime(time.Date(2025, 6, 2, 11, 0, 0, 0, time.UTC)))
+
+	// No version in force: fall back to the static schedule
+	assert.True(t, scheduler.isHighTariffTime(time.Date(2023, 6, 5, 9, 0, 0, 0, time.UTC)))
+}
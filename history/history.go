@@ -0,0 +1,96 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/adrg/xdg"
+)
+
+// Session is a finished charging session
+type Session struct {
+	ChargeBoxID   string    `json:"charge_box_id"`
+	ConnectorID   int       `json:"connector_id"`
+	TransactionID int       `json:"transaction_id"`
+	CarID         int       `json:"car_id,omitempty"`
+	Start         time.Time `json:"start"`
+	Stop          time.Time `json:"stop"`
+	// Energy is the charged energy in kWh
+	Energy float64 `json:"energy"`
+	// HighTariffEnergy and LowTariffEnergy split the energy by tariff, estimated from the
+	// configured tariffs when the backend doesn't report the split
+	HighTariffEnergy float64 `json:"high_tariff_energy,omitempty"`
+	LowTariffEnergy  float64 `json:"low_tariff_energy,omitempty"`
+	// Cost is the session cost in CHF
+	Cost float64 `json:"cost"`
+	// CostEstimated is set when the backend didn't report the cost, which was estimated from the tariffs
+	CostEstimated bool   `json:"cost_estimated,omitempty"`
+	StopReason    string `json:"stop_reason,omitempty"`
+}
+
+// Store appends sessions to a JSON lines file
+type Store struct {
+	path string
+	mu   sync.Mutex
+}
+
+// DefaultPath returns the default location of the session history
+func DefaultPath() string {
+	return filepath.Join(xdg.StateHome, "ekz-tesla", "sessions.jsonl")
+}
+
+// New creates a store backed by the file at path
+func New(path string) *Store {
+	return &Store{path: path}
+}
+
+// Append records a session
+func (s *Store) Append(session Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	return json.NewEncoder(f).Encode(session)
+}
+
+// List returns all recorded sessions, oldest first
+func (s *Store) List() ([]Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.Open(s.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	var sessions []Session
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var session Session
+		if err := json.Unmarshal(scanner.Bytes(), &session); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, scanner.Err()
+}
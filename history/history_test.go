@@ -0,0 +1,40 @@
+package history_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/ekz-tesla/history"
+)
+
+func TestStore_AppendList(t *testing.T) {
+	store := history.New(filepath.Join(t.TempDir(), "state", "sessions.jsonl"))
+
+	sessions, err := store.List()
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+
+	start := time.Date(2025, 1, 13, 20, 0, 0, 0, time.UTC)
+	require.NoError(t, store.Append(history.Session{
+		ChargeBoxID:   "1234",
+		ConnectorID:   1,
+		TransactionID: 1,
+		Start:         start,
+		Stop:          start.Add(2 * time.Hour),
+		Energy:        15.2,
+		Cost:          3.1,
+		StopReason:    "target reached",
+	}))
+	require.NoError(t, store.Append(history.Session{ChargeBoxID: "1234", TransactionID: 2}))
+
+	sessions, err = store.List()
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	assert.Equal(t, 15.2, sessions[0].Energy)
+	assert.True(t, sessions[0].Stop.Equal(start.Add(2*time.Hour)))
+	assert.Equal(t, 2, sessions[1].TransactionID)
+}
@@ -6,11 +6,16 @@ import (
 	"github.com/sirupsen/logrus"
 
 	_ "github.com/denysvitali/ekz-tesla/cmd/autostart"
+	_ "github.com/denysvitali/ekz-tesla/cmd/car"
 	_ "github.com/denysvitali/ekz-tesla/cmd/list"
 	_ "github.com/denysvitali/ekz-tesla/cmd/livedata"
+	_ "github.com/denysvitali/ekz-tesla/cmd/queue"
 	"github.com/denysvitali/ekz-tesla/cmd/root"
+	_ "github.com/denysvitali/ekz-tesla/cmd/rules"
 	_ "github.com/denysvitali/ekz-tesla/cmd/start"
+	_ "github.com/denysvitali/ekz-tesla/cmd/status"
 	_ "github.com/denysvitali/ekz-tesla/cmd/stop"
+	_ "github.com/denysvitali/ekz-tesla/cmd/sync"
 	_ "github.com/denysvitali/ekz-tesla/cmd/version"
 )
 
@@ -19,4 +24,4 @@ func main() {
 		logrus.Error(err)
 		os.Exit(1)
 	}
-}
\ No newline at end of file
+}
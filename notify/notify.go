@@ -0,0 +1,57 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// defaultTimeout bounds the delivery of a single alert
+const defaultTimeout = 10 * time.Second
+
+// Alert is a problem that needs the attention of the user
+type Alert struct {
+	Time        time.Time `json:"time"`
+	Title       string    `json:"title"`
+	Message     string    `json:"message"`
+	ChargeBoxID string    `json:"charge_box_id,omitempty"`
+	ConnectorID int       `json:"connector_id,omitempty"`
+	CarID       int       `json:"car_id,omitempty"`
+}
+
+// Webhook posts alerts as JSON to a URL
+type Webhook struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhook creates a webhook posting to url
+func NewWebhook(url string) *Webhook {
+	return &Webhook{url: url, client: &http.Client{Timeout: defaultTimeout}}
+}
+
+// Send posts the alert, any non-2xx response is an error
+func (w *Webhook) Send(ctx context.Context, alert Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send webhook: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
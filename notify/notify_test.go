@@ -0,0 +1,47 @@
+package notify_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/ekz-tesla/notify"
+)
+
+func TestWebhook_Send(t *testing.T) {
+	var received notify.Alert
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	alert := notify.Alert{
+		Time:        time.Date(2025, 1, 13, 23, 0, 0, 0, time.UTC),
+		Title:       "Charging interrupted",
+		Message:     "restart failed 3 times",
+		ChargeBoxID: "1234",
+		ConnectorID: 1,
+		CarID:       1,
+	}
+	require.NoError(t, notify.NewWebhook(server.URL).Send(context.Background(), alert))
+	assert.Equal(t, alert, received)
+}
+
+func TestWebhook_SendError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	err := notify.NewWebhook(server.URL).Send(context.Background(), notify.Alert{Title: "test"})
+	assert.ErrorContains(t, err, "500")
+}
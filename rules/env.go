@@ -0,0 +1,214 @@
+package rules
+
+import (
+	"time"
+
+	geo "github.com/kellydunn/golang-geo"
+
+	"github.com/denysvitali/ekz-tesla/ekz"
+	"github.com/denysvitali/ekz-tesla/teslamateapi"
+	"github.com/denysvitali/ekz-tesla/vehicle"
+)
+
+// Env holds the variables available to rule expressions. It is also the
+// snapshot format used to test rules.
+type Env struct {
+	Car     Car     `expr:"car" json:"car"`
+	Session Session `expr:"session" json:"session"`
+	Tariff  Tariff  `expr:"tariff" json:"tariff"`
+	// Target is the planned charge level in percent
+	Target int `expr:"target" json:"target"`
+
+	Now     time.Time `expr:"now" json:"now"`
+	Hour    int       `expr:"hour" json:"hour"`
+	Minute  int       `expr:"minute" json:"minute"`
+	Weekday string    `expr:"weekday" json:"weekday"`
+	// Today is the current date, named so as not to hide the date() builtin
+	Today string `expr:"today" json:"today"`
+}
+
+// Car holds the car status reported by the vehicle provider
+type Car struct {
+	SoC           int     `expr:"soc" json:"soc"`
+	ChargeLimit   int     `expr:"charge_limit" json:"charge_limit"`
+	PluggedIn     bool    `expr:"plugged_in" json:"plugged_in"`
+	ChargingState string  `expr:"charging_state" json:"charging_state"`
+	State         string  `expr:"state" json:"state"`
+	SentryMode    bool    `expr:"sentry_mode" json:"sentry_mode"`
+	Locked        bool    `expr:"locked" json:"locked"`
+	UserPresent   bool    `expr:"user_present" json:"user_present"`
+	ClimateOn     bool    `expr:"climate_on" json:"climate_on"`
+	InsideTemp    float64 `expr:"inside_temp" json:"inside_temp"`
+	OutsideTemp   float64 `expr:"outside_temp" json:"outside_temp"`
+	Range         float64 `expr:"range" json:"range"`
+	Geofence      string  `expr:"geofence" json:"geofence"`
+	Latitude      float64 `expr:"latitude" json:"latitude"`
+	Longitude     float64 `expr:"longitude" json:"longitude"`
+	// Distance is the distance from the charging station in meters
+	Distance float64 `expr:"distance" json:"distance"`
+	// DataAge is the age of the car data in minutes
+	DataAge float64 `expr:"data_age" json:"data_age"`
+}
+
+// Session holds the live data of the running EKZ session
+type Session struct {
+	Active bool   `expr:"active" json:"active"`
+	Status string `expr:"status" json:"status"`
+	// Power is the charging power in kW
+	Power float64 `expr:"power" json:"power"`
+	// Energy is the charged energy in kWh
+	Energy float64 `expr:"energy" json:"energy"`
+	// Cost is the session cost in CHF
+	Cost float64 `expr:"cost" json:"cost"`
+	// Duration is the session duration in minutes
+	Duration float64 `expr:"duration" json:"duration"`
+}
+
+// Tariff holds the electricity tariff in force
+type Tariff struct {
+	Low  bool `expr:"low" json:"low"`
+	High bool `expr:"high" json:"high"`
+	// Price is the price in Rp./kWh, 0 if unknown
+	Price float64 `expr:"price" json:"price"`
+}
+
+// Variable documents a variable available to rule expressions
+type Variable struct {
+	Name        string
+	Type        string
+	Description string
+}
+
+// Variables lists the variables available to rule expressions
+var Variables = []Variable{
+	{"car.soc", "int", "battery level in percent"},
+	{"car.charge_limit", "int", "charge limit set in the car in percent"},
+	{"car.plugged_in", "bool", "the charge cable is plugged in"},
+	{"car.charging_state", "string", "Charging, Starting, Complete, Stopped, Disconnected or NoPower"},
+	{"car.state", "string", "online, asleep, offline, charging, driving, ..."},
+	{"car.sentry_mode", "bool", "sentry mode is on"},
+	{"car.locked", "bool", "the car is locked"},
+	{"car.user_present", "bool", "a user is in the car"},
+	{"car.climate_on", "bool", "the climate control is on"},
+	{"car.inside_temp", "float", "inside temperature in °C"},
+	{"car.outside_temp", "float", "outside temperature in °C"},
+	{"car.range", "float", "estimated range in km"},
+	{"car.geofence", "string", "TeslaMate geofence the car is in"},
+	{"car.latitude", "float", "car latitude"},
+	{"car.longitude", "float", "car longitude"},
+	{"car.distance", "float", "distance from the charging station in meters"},
+	{"car.data_age", "float", "age of the car data in minutes"},
+	{"session.active", "bool", "an EKZ session is running (stop rules only)"},
+	{"session.status", "string", "status of the EKZ session"},
+	{"session.power", "float", "charging power in kW"},
+	{"session.energy", "float", "charged energy in kWh"},
+	{"session.cost", "float", "session cost in CHF"},
+	{"session.duration", "float", "session duration in minutes"},
+	{"tariff.low", "bool", "low tariff in force"},
+	{"tariff.high", "bool", "high tariff in force"},
+	{"tariff.price", "float", "current price in Rp./kWh, 0 if unknown"},
+	{"target", "int", "planned charge level in percent"},
+	{"now", "time", "current time"},
+	{"hour", "int", "current hour (0-23)"},
+	{"minute", "int", "current minute (0-59)"},
+	{"weekday", "string", "current weekday (Mon, Tue, ...)"},
+	{"today", "string", "current date (YYYY-MM-DD)"},
+}
+
+// Input is the data the variables are computed from
+type Input struct {
+	Now     time.Time
+	Vehicle vehicle.Status
+	// LiveData is the running session, nil if there is none
+	LiveData *ekz.LiveDataResponse
+	Station  ekz.ChargingStationConfig
+	// HighTariff tells whether the high tariff is in force at Now
+	HighTariff bool
+	// Tariffs give the price in force, falling back to the price of the live data
+	Tariffs ekz.Tariffs
+	Target  int
+}
+
+// Build computes the variables from the input
+func Build(in Input) Env {
+	env := NewEnv(in.Now)
+	env.Car = CarFromVehicle(in.Vehicle, in.Now)
+	if location := in.Vehicle.Location; location != nil {
+		station := geo.NewPoint(in.Station.Latitude, in.Station.Longitude)
+		car := geo.NewPoint(location.Latitude, location.Longitude)
+		env.Car.Distance = station.GreatCircleDistance(car) * 1000
+	}
+	env.Session = SessionFromLiveData(in.LiveData, in.Tariffs, in.Now)
+	env.Target = in.Target
+
+	env.Tariff = Tariff{Low: !in.HighTariff, High: in.HighTariff}
+	if tv, ok := in.Tariffs.At(in.Now); ok {
+		env.Tariff.Price = tv.PriceAt(in.Now)
+	} else if in.LiveData != nil {
+		env.Tariff.Price = in.LiveData.CurrentTariff.TariffPrice
+	}
+	return env
+}
+
+// NewEnv creates the variables for the given time. The car, session and tariff
+// variables are filled in with the other constructors.
+func NewEnv(now time.Time) Env {
+	return Env{
+		Now:     now,
+		Hour:    now.Hour(),
+		Minute:  now.Minute(),
+		Weekday: now.Weekday().String()[:3],
+		Today:   now.Format("2006-01-02"),
+	}
+}
+
+// CarFromStatus returns the car variables for the status reported by TeslaMateApi.
+// The distance from the station is left to the caller.
+func CarFromStatus(status teslamateapi.CarStatus, now time.Time) Car {
+	return CarFromVehicle(vehicle.FromTeslaMateAPI(status), now)
+}
+
+// CarFromVehicle returns the car variables for the status of the vehicle provider.
+// The distance from the station is left to the caller, it is 0 if the location is unknown.
+func CarFromVehicle(status vehicle.Status, now time.Time) Car {
+	car := Car{
+		SoC:           status.SoC,
+		ChargeLimit:   status.ChargeLimit,
+		PluggedIn:     status.PluggedIn,
+		ChargingState: string(status.CurrentChargingState()),
+		State:         status.State,
+		SentryMode:    status.SentryMode,
+		Locked:        status.Locked,
+		UserPresent:   status.UserPresent,
+		ClimateOn:     status.ClimateOn,
+		InsideTemp:    status.InsideTemp,
+		OutsideTemp:   status.OutsideTemp,
+		Range:         status.Range,
+		Geofence:      status.Geofence,
+		DataAge:       status.DataAge(now).Minutes(),
+	}
+	if status.Location != nil {
+		car.Latitude = status.Location.Latitude
+		car.Longitude = status.Location.Longitude
+	}
+	return car
+}
+
+// SessionFromLiveData returns the session variables, inactive if liveData is nil. The tariffs
+// estimate the cost until the backend reports it.
+func SessionFromLiveData(liveData *ekz.LiveDataResponse, tariffs ekz.Tariffs, now time.Time) Session {
+	if liveData == nil {
+		return Session{}
+	}
+	session := Session{
+		Active: !liveData.IsFinished(),
+		Status: liveData.Status,
+		Power:  liveData.Power,
+		Energy: liveData.ChargedEnergy,
+		Cost:   liveData.Cost(tariffs),
+	}
+	if liveData.Starttimestamp > 0 {
+		session.Duration = now.Sub(liveData.StartTime()).Minutes()
+	}
+	return session
+}
@@ -0,0 +1,79 @@
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+)
+
+// maxNodes bounds the size of an expression
+const maxNodes = 1000
+
+// Rule is a compiled boolean expression over the variables of Env. Besides the operators
+// and builtins of expr, e.g. date() and duration(), expressions can only call the methods
+// of the variables, e.g. now.Hour(). None of them has side effects.
+type Rule struct {
+	source  string
+	program *vm.Program
+}
+
+// Compile checks the expression against the variables of Env and compiles it
+func Compile(source string) (*Rule, error) {
+	program, err := expr.Compile(source, expr.Env(Env{}), expr.AsBool(), expr.MaxNodes(maxNodes))
+	if err != nil {
+		return nil, fmt.Errorf("invalid rule %q: %w", source, err)
+	}
+	return &Rule{source: source, program: program}, nil
+}
+
+// Eval evaluates the rule against env
+func (r *Rule) Eval(env Env) (bool, error) {
+	result, err := expr.Run(r.program, env)
+	if err != nil {
+		return false, fmt.Errorf("failed to evaluate rule %q: %w", r.source, err)
+	}
+	return result.(bool), nil
+}
+
+func (r *Rule) String() string {
+	return r.source
+}
+
+// Set holds the compiled start and stop rules, nil if not configured
+type Set struct {
+	Start *Rule
+	Stop  *Rule
+}
+
+// CompileSet compiles the configured rules, skipping empty expressions
+func CompileSet(start, stop string) (*Set, error) {
+	var set Set
+	var err error
+	if start != "" {
+		if set.Start, err = Compile(start); err != nil {
+			return nil, fmt.Errorf("start: %w", err)
+		}
+	}
+	if stop != "" {
+		if set.Stop, err = Compile(stop); err != nil {
+			return nil, fmt.Errorf("stop: %w", err)
+		}
+	}
+	return &set, nil
+}
+
+// LoadSnapshot reads variables recorded as JSON
+func LoadSnapshot(path string) (Env, error) {
+	var env Env
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return env, err
+	}
+	if err := json.Unmarshal(data, &env); err != nil {
+		return env, fmt.Errorf("failed to parse snapshot %s: %w", path, err)
+	}
+	return env, nil
+}
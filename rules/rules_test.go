@@ -0,0 +1,145 @@
+package rules_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/ekz-tesla/ekz"
+	"github.com/denysvitali/ekz-tesla/rules"
+	"github.com/denysvitali/ekz-tesla/teslamateapi"
+	"github.com/denysvitali/ekz-tesla/vehicle"
+)
+
+func TestRule_Eval(t *testing.T) {
+	env, err := rules.LoadSnapshot("testdata/snapshot.json")
+	require.NoError(t, err)
+
+	tests := []struct {
+		expression string
+		want       bool
+	}{
+		{`car.soc < 60 && tariff.low && !car.sentry_mode && weekday != "Sun"`, true},
+		{`car.soc < target - 40`, false},
+		{`car.geofence == "Home" && car.distance < 100`, true},
+		{`session.energy >= 12 || session.cost > 5`, true},
+		{`hour >= 22 || hour < 6`, true},
+		{`weekday in ["Sat", "Sun"]`, false},
+		{`tariff.price <= 20 && today == "2025-01-13"`, true},
+		{`now.Hour() == 22`, true},
+		{`date(today) < date("2025-02-01")`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			rule, err := rules.Compile(tt.expression)
+			require.NoError(t, err)
+			got, err := rule.Eval(env)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCompile_Invalid(t *testing.T) {
+	for _, expression := range []string{
+		`car.unknown > 1`,
+		`car.soc`,
+		`car.soc <`,
+		`os.Exit(1)`,
+	} {
+		t.Run(expression, func(t *testing.T) {
+			_, err := rules.Compile(expression)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestCompileSet(t *testing.T) {
+	set, err := rules.CompileSet("tariff.low", "")
+	require.NoError(t, err)
+	assert.NotNil(t, set.Start)
+	assert.Nil(t, set.Stop)
+
+	_, err = rules.CompileSet("", "car.soc")
+	assert.ErrorContains(t, err, "stop")
+}
+
+func TestNewEnv(t *testing.T) {
+	now := time.Date(2025, 1, 19, 7, 30, 0, 0, time.UTC)
+	env := rules.NewEnv(now)
+	env.Car = rules.CarFromStatus(teslamateapi.CarStatus{
+		BatteryDetails:  teslamateapi.BatteryDetails{BatteryLevel: 55},
+		ChargingDetails: teslamateapi.ChargingDetails{PluggedIn: true, ChargeLimitSoc: 90},
+		CarStatus:       teslamateapi.PhysicalStatus{SentryMode: true},
+		State:           "online",
+	}, now)
+	env.Session = rules.SessionFromLiveData(&ekz.LiveDataResponse{
+		Status:         "ONGOING",
+		ChargedEnergy:  10,
+		CurrentTariff:  ekz.CurrentTariff{TariffPrice: 25},
+		Starttimestamp: int(now.Add(-time.Hour).Unix()),
+	}, nil, now)
+
+	assert.Equal(t, "Sun", env.Weekday)
+	assert.Equal(t, "2025-01-19", env.Today)
+	assert.Equal(t, 7, env.Hour)
+	assert.Equal(t, 55, env.Car.SoC)
+	assert.Equal(t, 90, env.Car.ChargeLimit)
+	assert.Equal(t, "Stopped", env.Car.ChargingState)
+	assert.True(t, env.Car.SentryMode)
+	assert.True(t, env.Session.Active)
+	assert.InDelta(t, 2.5, env.Session.Cost, 0.001)
+	assert.InDelta(t, 60, env.Session.Duration, 0.001)
+	assert.False(t, rules.SessionFromLiveData(nil, nil, now).Active)
+}
+
+func TestVariables_Documented(t *testing.T) {
+	documented := make(map[string]bool)
+	for _, v := range rules.Variables {
+		documented[v.Name] = true
+	}
+
+	var names []string
+	var collect func(prefix string, typ reflect.Type)
+	collect = func(prefix string, typ reflect.Type) {
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			name := prefix + field.Tag.Get("expr")
+			if field.Type.Kind() == reflect.Struct && field.Type != reflect.TypeOf(time.Time{}) {
+				collect(name+".", field.Type)
+				continue
+			}
+			names = append(names, name)
+		}
+	}
+	collect("", reflect.TypeOf(rules.Env{}))
+
+	for _, name := range names {
+		assert.True(t, documented[name], "variable %s is not documented", name)
+	}
+	assert.Len(t, rules.Variables, len(names), "documented variables: %s", strings.Join(names, ", "))
+}
+
+func TestBuild(t *testing.T) {
+	now := time.Date(2025, 1, 13, 22, 0, 0, 0, time.UTC)
+	env := rules.Build(rules.Input{
+		Now: now,
+		Vehicle: vehicle.Status{
+			Location: &vehicle.Location{Latitude: 47.3770, Longitude: 8.5417},
+		},
+		Station:    ekz.ChargingStationConfig{Latitude: 47.3769, Longitude: 8.5417},
+		HighTariff: true,
+		Tariffs:    ekz.Tariffs{{Name: "2025", HighTariffTimes: []string{"20:00-23:00"}, HighPrice: 30, LowPrice: 20}},
+		Target:     80,
+	})
+	assert.InDelta(t, 11, env.Car.Distance, 1)
+	assert.True(t, env.Tariff.High)
+	assert.False(t, env.Tariff.Low)
+	assert.Equal(t, 30.0, env.Tariff.Price)
+	assert.Equal(t, 80, env.Target)
+	assert.False(t, env.Session.Active)
+}
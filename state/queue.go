@@ -0,0 +1,207 @@
+package state
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/denysvitali/ekz-tesla/ekz"
+)
+
+// DefaultCatchUpWindow is how late a queued action may still run, e.g. after the daemon was down
+const DefaultCatchUpWindow = 10 * time.Minute
+
+// FailedRetention is how long failed actions stay queued after their time, unless cancelled
+const FailedRetention = 7 * 24 * time.Hour
+
+// ActionKind is what a queued action does
+type ActionKind string
+
+const (
+	ActionStart ActionKind = "start"
+	ActionStop  ActionKind = "stop"
+)
+
+// ActionStatus tells where a queued action is in its execution
+type ActionStatus string
+
+const (
+	// ActionPending actions wait for their time
+	ActionPending ActionStatus = ""
+	// ActionRunning actions were picked up by the daemon and are being executed
+	ActionRunning ActionStatus = "running"
+	// ActionFailed actions failed or were missed, they stay queued until cancelled or pruned
+	ActionFailed ActionStatus = "failed"
+)
+
+// Action is a one-off command executed by the daemon at a given time. It stays in the
+// queue while it runs and, if it fails, until it is cancelled or FailedRetention passed.
+type Action struct {
+	ID          int        `json:"id"`
+	Kind        ActionKind `json:"kind"`
+	At          time.Time  `json:"at"`
+	ChargeBoxID string     `json:"charge_box_id"`
+	ConnectorID int        `json:"connector_id"`
+	// Limits bound the session started by a start action
+	Limits  ekz.SessionLimits `json:"limits,omitzero"`
+	Created time.Time         `json:"created"`
+	Status  ActionStatus      `json:"status,omitempty"`
+	// Error tells why a failed action failed
+	Error string `json:"error,omitempty"`
+}
+
+func (a Action) String() string {
+	s := fmt.Sprintf("%s at %s on box %s, connector %d", a.Kind, a.At.Local().Format("2006-01-02 15:04"), a.ChargeBoxID, a.ConnectorID)
+	if !a.Limits.IsZero() {
+		s += fmt.Sprintf(" (%s)", a.Limits)
+	}
+	return s
+}
+
+// Enqueue adds the action to the queue and returns it with its ID
+func (s *State) Enqueue(action Action) Action {
+	s.NextActionID++
+	action.ID = s.NextActionID
+	s.Queue = append(s.Queue, action)
+	sort.SliceStable(s.Queue, func(i, j int) bool { return s.Queue[i].At.Before(s.Queue[j].At) })
+	return action
+}
+
+// Cancel removes the action with the given ID from the queue
+func (s *State) Cancel(id int) (Action, bool) {
+	for i, action := range s.Queue {
+		if action.ID == id {
+			s.Queue = append(s.Queue[:i], s.Queue[i+1:]...)
+			return action, true
+		}
+	}
+	return Action{}, false
+}
+
+// StartDue marks the pending actions due at now as running and returns them as due. Actions
+// that are due since longer than the catch-up window are marked failed and returned as missed.
+// The actions stay in the queue until FinishAction.
+func (s *State) StartDue(now time.Time, catchUpWindow time.Duration) (due []Action, missed []Action) {
+	for i := range s.Queue {
+		action := &s.Queue[i]
+		switch {
+		case action.Status != ActionPending || action.At.After(now):
+			continue
+		case now.Sub(action.At) > catchUpWindow:
+			action.Status = ActionFailed
+			action.Error = fmt.Sprintf("missed, its time passed more than %s ago", catchUpWindow)
+			missed = append(missed, *action)
+		default:
+			action.Status = ActionRunning
+			due = append(due, *action)
+		}
+	}
+	return due, missed
+}
+
+// FinishAction removes the action with the given ID from the queue once it ran,
+// or marks it failed with err
+func (s *State) FinishAction(id int, err error) {
+	for i := range s.Queue {
+		if s.Queue[i].ID != id {
+			continue
+		}
+		if err == nil {
+			s.Queue = append(s.Queue[:i], s.Queue[i+1:]...)
+			return
+		}
+		s.Queue[i].Status = ActionFailed
+		s.Queue[i].Error = err.Error()
+		return
+	}
+}
+
+// InterruptRunning marks the actions left running by a daemon that stopped as failed and returns them
+func (s *State) InterruptRunning() []Action {
+	var interrupted []Action
+	for i := range s.Queue {
+		if s.Queue[i].Status == ActionRunning {
+			s.Queue[i].Status = ActionFailed
+			s.Queue[i].Error = "interrupted, the daemon stopped while it ran"
+			interrupted = append(interrupted, s.Queue[i])
+		}
+	}
+	return interrupted
+}
+
+// NextAction returns the next pending action
+func (s *State) NextAction() (Action, bool) {
+	for _, action := range s.Queue {
+		if action.Status == ActionPending {
+			return action, true
+		}
+	}
+	return Action{}, false
+}
+
+// PruneFailed removes the failed actions whose time passed more than FailedRetention
+// before now and returns them
+func (s *State) PruneFailed(now time.Time) []Action {
+	var pruned []Action
+	queue := s.Queue[:0]
+	for _, action := range s.Queue {
+		if action.Status == ActionFailed && now.Sub(action.At) > FailedRetention {
+			pruned = append(pruned, action)
+			continue
+		}
+		queue = append(queue, action)
+	}
+	s.Queue = queue
+	return pruned
+}
+
+// FailedActions returns the actions that failed or were missed
+func (s *State) FailedActions() []Action {
+	var failed []Action
+	for _, action := range s.Queue {
+		if action.Status == ActionFailed {
+			failed = append(failed, action)
+		}
+	}
+	return failed
+}
+
+// ParseAt parses the time of a queued action: either a clock time such as "22:15",
+// meaning its next occurrence after now, or a date and time such as "2025-01-14 05:30"
+// or RFC 3339, in the local time zone of now. Dates in the past are rejected.
+func ParseAt(value string, now time.Time) (time.Time, error) {
+	if t, err := time.ParseInLocation("15:04", value, now.Location()); err == nil {
+		at := time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, now.Location())
+		if !at.After(now) {
+			at = at.AddDate(0, 0, 1)
+		}
+		return at, nil
+	}
+	at, err := time.Parse(time.RFC3339, value)
+	for _, layout := range []string{"2006-01-02 15:04", "2006-01-02T15:04"} {
+		if err == nil {
+			break
+		}
+		at, err = time.ParseInLocation(layout, value, now.Location())
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q (use HH:MM, YYYY-MM-DD HH:MM or RFC 3339)", value)
+	}
+	if !at.After(now) {
+		return time.Time{}, fmt.Errorf("time %s is in the past", at.Format(time.RFC3339))
+	}
+	return at, nil
+}
+
+// Enqueue adds the action to the queue in the state file. It returns the queued action
+// and whether a daemon is currently running to execute it.
+func (s *Store) Enqueue(action Action) (Action, bool, error) {
+	alive := false
+	err := s.Update(func(st *State) error {
+		action.Created = time.Now()
+		action = st.Enqueue(action)
+		alive = st.Daemon.Alive(action.Created)
+		return nil
+	})
+	return action, alive, err
+}
@@ -0,0 +1,132 @@
+package state_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/ekz-tesla/state"
+)
+
+func TestState_Queue(t *testing.T) {
+	now := time.Date(2025, 1, 13, 22, 0, 0, 0, time.UTC)
+	var st state.State
+
+	stop := st.Enqueue(state.Action{Kind: state.ActionStop, At: now.Add(7 * time.Hour)})
+	start := st.Enqueue(state.Action{Kind: state.ActionStart, At: now.Add(15 * time.Minute)})
+	late := st.Enqueue(state.Action{Kind: state.ActionStart, At: now.Add(-5 * time.Minute)})
+	missed := st.Enqueue(state.Action{Kind: state.ActionStart, At: now.Add(-time.Hour)})
+	assert.Equal(t, []int{1, 2, 3, 4}, []int{stop.ID, start.ID, late.ID, missed.ID})
+	assert.Equal(t, []int{4, 3, 2, 1}, []int{st.Queue[0].ID, st.Queue[1].ID, st.Queue[2].ID, st.Queue[3].ID})
+
+	due, skipped := st.StartDue(now, state.DefaultCatchUpWindow)
+	require.Len(t, due, 1)
+	assert.Equal(t, late.ID, due[0].ID)
+	assert.Equal(t, state.ActionRunning, due[0].Status)
+	require.Len(t, skipped, 1)
+	assert.Equal(t, missed.ID, skipped[0].ID)
+	assert.Equal(t, state.ActionFailed, skipped[0].Status)
+	assert.Len(t, st.Queue, 4, "actions stay queued until they finished")
+	next, ok := st.NextAction()
+	require.True(t, ok)
+	assert.Equal(t, start.ID, next.ID)
+
+	// Running and failed actions are not started again
+	due, skipped = st.StartDue(now.Add(time.Minute), state.DefaultCatchUpWindow)
+	assert.Empty(t, due)
+	assert.Empty(t, skipped)
+
+	st.FinishAction(late.ID, nil)
+	assert.Len(t, st.Queue, 3)
+
+	cancelled, ok := st.Cancel(start.ID)
+	require.True(t, ok)
+	assert.Equal(t, state.ActionStart, cancelled.Kind)
+	_, ok = st.Cancel(start.ID)
+	assert.False(t, ok)
+
+	due, skipped = st.StartDue(now.Add(7*time.Hour), state.DefaultCatchUpWindow)
+	require.Len(t, due, 1)
+	assert.Equal(t, stop.ID, due[0].ID)
+	assert.Empty(t, skipped)
+	_, ok = st.NextAction()
+	assert.False(t, ok)
+
+	// A failed action stays visible with its error until cancelled
+	st.FinishAction(stop.ID, errors.New("no answer from the station"))
+	failed := st.FailedActions()
+	require.Len(t, failed, 2)
+	assert.Equal(t, "no answer from the station", failed[1].Error)
+	_, ok = st.Cancel(missed.ID)
+	require.True(t, ok)
+	_, ok = st.Cancel(stop.ID)
+	require.True(t, ok)
+	assert.Empty(t, st.Queue)
+
+	// IDs are not reused
+	assert.Equal(t, 5, st.Enqueue(state.Action{Kind: state.ActionStop, At: now}).ID)
+}
+
+func TestState_InterruptRunning(t *testing.T) {
+	now := time.Date(2025, 1, 13, 22, 0, 0, 0, time.UTC)
+	var st state.State
+	running := st.Enqueue(state.Action{Kind: state.ActionStart, At: now})
+	pending := st.Enqueue(state.Action{Kind: state.ActionStop, At: now.Add(time.Hour)})
+	st.StartDue(now, state.DefaultCatchUpWindow)
+
+	interrupted := st.InterruptRunning()
+	require.Len(t, interrupted, 1)
+	assert.Equal(t, running.ID, interrupted[0].ID)
+	assert.Equal(t, state.ActionFailed, st.Queue[0].Status)
+	assert.NotEmpty(t, st.Queue[0].Error)
+	next, ok := st.NextAction()
+	require.True(t, ok)
+	assert.Equal(t, pending.ID, next.ID)
+}
+
+func TestState_PruneFailed(t *testing.T) {
+	now := time.Date(2025, 1, 13, 22, 0, 0, 0, time.UTC)
+	var st state.State
+	old := st.Enqueue(state.Action{Kind: state.ActionStart, At: now.Add(-8 * 24 * time.Hour), Status: state.ActionFailed})
+	recent := st.Enqueue(state.Action{Kind: state.ActionStart, At: now.Add(-24 * time.Hour), Status: state.ActionFailed})
+	pending := st.Enqueue(state.Action{Kind: state.ActionStop, At: now.Add(time.Hour)})
+
+	pruned := st.PruneFailed(now)
+	require.Len(t, pruned, 1)
+	assert.Equal(t, old.ID, pruned[0].ID)
+	require.Len(t, st.Queue, 2)
+	assert.Equal(t, recent.ID, st.Queue[0].ID, "recent failures stay listed")
+	assert.Equal(t, pending.ID, st.Queue[1].ID)
+	assert.Empty(t, st.PruneFailed(now))
+}
+
+func TestParseAt(t *testing.T) {
+	loc := time.FixedZone("CET", 3600)
+	now := time.Date(2025, 1, 13, 21, 0, 0, 0, loc)
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"22:15", time.Date(2025, 1, 13, 22, 15, 0, 0, loc)},
+		{"05:30", time.Date(2025, 1, 14, 5, 30, 0, 0, loc)},
+		{"21:00", time.Date(2025, 1, 14, 21, 0, 0, 0, loc)},
+		{"2025-01-20 06:00", time.Date(2025, 1, 20, 6, 0, 0, 0, loc)},
+		{"2025-01-20T06:00", time.Date(2025, 1, 20, 6, 0, 0, 0, loc)},
+		{"2025-01-20T06:00:00Z", time.Date(2025, 1, 20, 6, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := state.ParseAt(tt.value, now)
+			require.NoError(t, err)
+			assert.True(t, tt.want.Equal(got), "got %s", got)
+		})
+	}
+
+	_, err := state.ParseAt("tomorrow", now)
+	assert.Error(t, err)
+	_, err = state.ParseAt("2025-01-13 20:00", now)
+	assert.Error(t, err)
+}
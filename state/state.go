@@ -0,0 +1,270 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/adrg/xdg"
+
+	"github.com/denysvitali/ekz-tesla/ekz"
+)
+
+const (
+	// DaemonHeartbeatInterval is how often a running daemon refreshes its heartbeat
+	DaemonHeartbeatInterval = 30 * time.Second
+	// daemonHeartbeatTimeout is how old a heartbeat may be for the daemon to count as running
+	daemonHeartbeatTimeout = 3 * DaemonHeartbeatInterval
+
+	lockRetryInterval = 50 * time.Millisecond
+	lockTimeout       = 5 * time.Second
+	// staleLockAge is the age after which a lock file left behind by a crashed process is removed
+	staleLockAge = 30 * time.Second
+)
+
+// ErrLocked is returned when the state file stays locked by another process
+var ErrLocked = errors.New("state file is locked")
+
+// State is the state shared between the CLI and a running daemon
+type State struct {
+	Daemon   *Daemon   `json:"daemon,omitempty"`
+	Sessions []Session `json:"sessions,omitempty"`
+	// Queue holds the pending one-off actions, earliest first
+	Queue        []Action `json:"queue,omitempty"`
+	NextActionID int      `json:"next_action_id,omitempty"`
+	// Stations holds the autostart state of the connectors, keyed by StationKey
+	Stations map[string]*StationState `json:"stations,omitempty"`
+}
+
+// Daemon describes the running daemon
+type Daemon struct {
+	PID       int       `json:"pid"`
+	Heartbeat time.Time `json:"heartbeat"`
+}
+
+// Alive returns true if the daemon refreshed its heartbeat recently
+func (d *Daemon) Alive(now time.Time) bool {
+	return d != nil && now.Sub(d.Heartbeat) < daemonHeartbeatTimeout
+}
+
+// SessionOwner tells how a session is supervised
+type SessionOwner string
+
+const (
+	// OwnerDaemon sessions were handed over by `start --detach` or a queued start, and are supervised with their limits
+	OwnerDaemon SessionOwner = ""
+	// OwnerAutostart sessions were started by autostart, and are supervised up to their target
+	OwnerAutostart SessionOwner = "autostart"
+)
+
+// Session is a running session supervised by the daemon
+type Session struct {
+	ChargeBoxID   string            `json:"charge_box_id"`
+	ConnectorID   int               `json:"connector_id"`
+	TransactionID int               `json:"transaction_id"`
+	Owner         SessionOwner      `json:"owner,omitempty"`
+	Limits        ekz.SessionLimits `json:"limits"`
+	// Target is the charge level of an autostart session
+	Target int `json:"target,omitempty"`
+	// CarID is the TeslaMate ID of the car charged by an autostart session, 0 if unknown
+	CarID int `json:"car_id,omitempty"`
+	// Emergency is set for an autostart session started below the emergency floor
+	Emergency bool      `json:"emergency,omitempty"`
+	Requested time.Time `json:"requested"`
+	// StopRequested is when a stop of the session was requested, by autostart, `stop` or a
+	// queued stop. A supervised session that ends after it is not restarted.
+	StopRequested time.Time `json:"stop_requested,omitzero"`
+}
+
+// String describes how the session is supervised
+func (s Session) String() string {
+	var how string
+	switch {
+	case s.Owner == OwnerAutostart && s.Emergency:
+		how = fmt.Sprintf("autostart, emergency charge to %d%%", s.Target)
+	case s.Owner == OwnerAutostart:
+		how = fmt.Sprintf("autostart, target %d%%", s.Target)
+	default:
+		how = s.Limits.String()
+	}
+	if s.CarID != 0 {
+		how += fmt.Sprintf(", car %d", s.CarID)
+	}
+	return fmt.Sprintf("session %d on %s/%d (%s)", s.TransactionID, s.ChargeBoxID, s.ConnectorID, how)
+}
+
+// Session returns the session on the given connector, if any
+func (s *State) Session(chargeBoxID string, connectorID int) (*Session, bool) {
+	for i := range s.Sessions {
+		if s.Sessions[i].ChargeBoxID == chargeBoxID && s.Sessions[i].ConnectorID == connectorID {
+			return &s.Sessions[i], true
+		}
+	}
+	return nil, false
+}
+
+// SetSession adds the session, replacing any session on the same connector
+func (s *State) SetSession(session Session) {
+	if existing, ok := s.Session(session.ChargeBoxID, session.ConnectorID); ok {
+		*existing = session
+		return
+	}
+	s.Sessions = append(s.Sessions, session)
+}
+
+// RequestStop records that the session on the given connector is being stopped on purpose.
+// It returns false if no session is supervised on the connector.
+func (s *State) RequestStop(chargeBoxID string, connectorID int, now time.Time) bool {
+	session, ok := s.Session(chargeBoxID, connectorID)
+	if ok {
+		session.StopRequested = now
+	}
+	return ok
+}
+
+// RemoveSession removes the session on the given connector
+func (s *State) RemoveSession(chargeBoxID string, connectorID int) {
+	sessions := s.Sessions[:0]
+	for _, session := range s.Sessions {
+		if session.ChargeBoxID != chargeBoxID || session.ConnectorID != connectorID {
+			sessions = append(sessions, session)
+		}
+	}
+	s.Sessions = sessions
+}
+
+// Store keeps the state in a JSON file. Updates are serialized across
+// processes with a lock file next to the state file.
+type Store struct {
+	path string
+	mu   sync.Mutex
+}
+
+// DefaultPath returns the default location of the state file
+func DefaultPath() string {
+	return filepath.Join(xdg.StateHome, "ekz-tesla", "state.json")
+}
+
+// New creates a store backed by the file at path
+func New(path string) *Store {
+	return &Store{path: path}
+}
+
+// Load returns the current state
+func (s *Store) Load() (*State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.read()
+}
+
+// Update loads the state, applies fn and saves the result. Nothing is saved if fn fails.
+func (s *Store) Update(fn func(*State) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	st, err := s.read()
+	if err != nil {
+		return err
+	}
+	if err := fn(st); err != nil {
+		return err
+	}
+	return s.write(st)
+}
+
+// errNoSession leaves the state file untouched when there is no session to update
+var errNoSession = errors.New("no supervised session")
+
+// RequestStop records in the state file that the session on the given connector, if any,
+// is being stopped on purpose, before the stop is sent
+func (s *Store) RequestStop(chargeBoxID string, connectorID int) error {
+	err := s.Update(func(st *State) error {
+		if !st.RequestStop(chargeBoxID, connectorID, time.Now()) {
+			return errNoSession
+		}
+		return nil
+	})
+	if errors.Is(err, errNoSession) {
+		return nil
+	}
+	return err
+}
+
+func (s *Store) read() (*State, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &State{}, nil
+		}
+		return nil, err
+	}
+
+	var st State
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, fmt.Errorf("failed to parse state file %s: %w", s.path, err)
+	}
+	return &st, nil
+}
+
+// write replaces the state file atomically
+func (s *Store) write(st *State) error {
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".state-*.json")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// lock creates the lock file, waiting for other processes to release it
+func (s *Store) lock() (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return nil, err
+	}
+
+	lockPath := s.path + ".lock"
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			_ = f.Close()
+			return func() { _ = os.Remove(lockPath) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+
+		if info, statErr := os.Stat(lockPath); statErr == nil && time.Since(info.ModTime()) > staleLockAge {
+			_ = os.Remove(lockPath)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%w: %s", ErrLocked, lockPath)
+		}
+		time.Sleep(lockRetryInterval)
+	}
+}
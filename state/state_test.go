@@ -0,0 +1,150 @@
+package state_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/ekz-tesla/ekz"
+	"github.com/denysvitali/ekz-tesla/state"
+)
+
+func TestStore_Update(t *testing.T) {
+	store := state.New(filepath.Join(t.TempDir(), "ekz-tesla", "state.json"))
+
+	st, err := store.Load()
+	require.NoError(t, err)
+	assert.Empty(t, st.Sessions)
+
+	require.NoError(t, store.Update(func(st *state.State) error {
+		st.SetSession(state.Session{ChargeBoxID: "1234", ConnectorID: 1, TransactionID: 1})
+		st.SetSession(state.Session{ChargeBoxID: "1234", ConnectorID: 2, TransactionID: 2})
+		st.SetSession(state.Session{
+			ChargeBoxID:   "1234",
+			ConnectorID:   1,
+			TransactionID: 3,
+			Limits:        ekz.SessionLimits{Energy: 15, Duration: 2 * time.Hour},
+		})
+		return nil
+	}))
+
+	st, err = store.Load()
+	require.NoError(t, err)
+	require.Len(t, st.Sessions, 2)
+	session, ok := st.Session("1234", 1)
+	require.True(t, ok)
+	assert.Equal(t, 3, session.TransactionID)
+	assert.Equal(t, ekz.SessionLimits{Energy: 15, Duration: 2 * time.Hour}, session.Limits)
+
+	// A failing update doesn't change the state
+	require.Error(t, store.Update(func(st *state.State) error {
+		st.RemoveSession("1234", 1)
+		return errors.New("fail")
+	}))
+	require.NoError(t, store.Update(func(st *state.State) error {
+		st.RemoveSession("1234", 2)
+		return nil
+	}))
+
+	st, err = store.Load()
+	require.NoError(t, err)
+	require.Len(t, st.Sessions, 1)
+	assert.Equal(t, 1, st.Sessions[0].ConnectorID)
+}
+
+func TestStore_ConcurrentUpdates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	var wg sync.WaitGroup
+	for i := 1; i <= 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Separate stores behave like separate processes sharing the file
+			store := state.New(path)
+			assert.NoError(t, store.Update(func(st *state.State) error {
+				st.SetSession(state.Session{ChargeBoxID: "1234", ConnectorID: i})
+				return nil
+			}))
+		}()
+	}
+	wg.Wait()
+
+	st, err := state.New(path).Load()
+	require.NoError(t, err)
+	assert.Len(t, st.Sessions, 10)
+}
+
+func TestStore_StaleLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	require.NoError(t, os.WriteFile(path+".lock", nil, 0644))
+	old := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(path+".lock", old, old))
+
+	require.NoError(t, state.New(path).Update(func(st *state.State) error { return nil }))
+	assert.NoFileExists(t, path+".lock")
+}
+
+func TestDaemon_Alive(t *testing.T) {
+	now := time.Now()
+	var missing *state.Daemon
+	assert.False(t, missing.Alive(now))
+	assert.True(t, (&state.Daemon{Heartbeat: now.Add(-time.Minute)}).Alive(now))
+	assert.False(t, (&state.Daemon{Heartbeat: now.Add(-time.Hour)}).Alive(now))
+}
+
+func TestSession_String(t *testing.T) {
+	assert.Equal(t, "session 7 on 1234/1 (15.00 kWh)", state.Session{
+		ChargeBoxID: "1234", ConnectorID: 1, TransactionID: 7, Limits: ekz.SessionLimits{Energy: 15},
+	}.String())
+	assert.Equal(t, "session 7 on 1234/1 (autostart, target 80%)", state.Session{
+		ChargeBoxID: "1234", ConnectorID: 1, TransactionID: 7, Owner: state.OwnerAutostart, Target: 80,
+	}.String())
+	assert.Equal(t, "session 7 on 1234/1 (autostart, emergency charge to 50%)", state.Session{
+		ChargeBoxID: "1234", ConnectorID: 1, TransactionID: 7, Owner: state.OwnerAutostart, Target: 50, Emergency: true,
+	}.String())
+	assert.Equal(t, "session 7 on 1234/1 (autostart, target 80%, car 2)", state.Session{
+		ChargeBoxID: "1234", ConnectorID: 1, TransactionID: 7, Owner: state.OwnerAutostart, Target: 80, CarID: 2,
+	}.String())
+}
+
+func TestStore_SessionOwner(t *testing.T) {
+	store := state.New(filepath.Join(t.TempDir(), "state.json"))
+	require.NoError(t, store.Update(func(st *state.State) error {
+		st.SetSession(state.Session{ChargeBoxID: "1234", ConnectorID: 1, TransactionID: 7, Owner: state.OwnerAutostart, Target: 80})
+		return nil
+	}))
+
+	st, err := store.Load()
+	require.NoError(t, err)
+	session, ok := st.Session("1234", 1)
+	require.True(t, ok)
+	assert.Equal(t, state.OwnerAutostart, session.Owner)
+	assert.Equal(t, 80, session.Target)
+}
+
+func TestStore_RequestStop(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	store := state.New(path)
+
+	// Without a supervised session, the state file is left alone
+	require.NoError(t, store.RequestStop("1234", 1))
+	assert.NoFileExists(t, path)
+
+	require.NoError(t, store.Update(func(st *state.State) error {
+		st.SetSession(state.Session{ChargeBoxID: "1234", ConnectorID: 1, TransactionID: 7, Owner: state.OwnerAutostart})
+		return nil
+	}))
+	require.NoError(t, store.RequestStop("1234", 1))
+	st, err := store.Load()
+	require.NoError(t, err)
+	session, ok := st.Session("1234", 1)
+	require.True(t, ok)
+	assert.False(t, session.StopRequested.IsZero())
+}
@@ -0,0 +1,174 @@
+package state
+
+import (
+	"fmt"
+	"time"
+)
+
+// StationPhase is the autostart phase of a charging station
+type StationPhase string
+
+const (
+	// StationReady allows start attempts
+	StationReady StationPhase = "ready"
+	// StationBackoff waits after a failed start attempt
+	StationBackoff StationPhase = "backoff"
+	// StationGaveUp doesn't attempt to start until the next tariff window
+	StationGaveUp StationPhase = "gave_up"
+)
+
+// BackoffPolicy limits how often autostart attempts to start a session
+type BackoffPolicy struct {
+	// Initial is the wait after the first failed attempt, doubled after every further failure
+	Initial time.Duration
+	// Max caps the wait between attempts
+	Max time.Duration
+	// MaxAttempts is the maximum number of failed start attempts per tariff window, 0 for no limit
+	MaxAttempts int
+	// Hysteresis is how many percent the battery must drop below the target
+	// before a new session starts once the target was reached
+	Hysteresis int
+}
+
+// DefaultBackoffPolicy is the backoff policy used by autostart
+var DefaultBackoffPolicy = BackoffPolicy{
+	Initial:     5 * time.Minute,
+	Max:         2 * time.Hour,
+	MaxAttempts: 3,
+	Hysteresis:  2,
+}
+
+// StationState tracks the start attempts on a charging station
+type StationState struct {
+	// Window identifies the tariff window the counters apply to
+	Window         string `json:"window,omitempty"`
+	WindowAttempts int    `json:"window_attempts,omitempty"`
+	// WindowFailures counts the failed attempts of the window, successful starts don't reset it
+	WindowFailures int       `json:"window_failures,omitempty"`
+	Failures       int       `json:"failures,omitempty"`
+	NextAttempt    time.Time `json:"next_attempt,omitzero"`
+	LastError      string    `json:"last_error,omitempty"`
+	GaveUp         bool      `json:"gave_up,omitempty"`
+	// TargetReached is set once the battery reached the target, until it drops below the hysteresis
+	TargetReached bool `json:"target_reached,omitempty"`
+	// Car is the car TargetReached refers to, 0 if unknown
+	Car int `json:"car,omitempty"`
+	// Turns is the order in which the cars sharing the connector charge in the window
+	Turns *TurnPlan `json:"turns,omitempty"`
+}
+
+// Phase returns the phase of the station at now
+func (s *StationState) Phase(now time.Time) StationPhase {
+	switch {
+	case s.GaveUp:
+		return StationGaveUp
+	case now.Before(s.NextAttempt):
+		return StationBackoff
+	default:
+		return StationReady
+	}
+}
+
+// Describe returns a human-readable description of the phase
+func (s *StationState) Describe(now time.Time) string {
+	switch s.Phase(now) {
+	case StationGaveUp:
+		return fmt.Sprintf("gave up after %d failed attempts in this tariff window (last error: %s)", s.WindowFailures, s.LastError)
+	case StationBackoff:
+		return fmt.Sprintf("backing off until %s after %d failure(s) (last error: %s)",
+			s.NextAttempt.Local().Format("15:04:05"), s.Failures, s.LastError)
+	default:
+		return "ready"
+	}
+}
+
+// EnterWindow resets the attempt counters when a new tariff window starts
+func (s *StationState) EnterWindow(window string) {
+	if s.Window == window {
+		return
+	}
+	s.Window = window
+	s.WindowAttempts = 0
+	s.WindowFailures = 0
+	s.Failures = 0
+	s.NextAttempt = time.Time{}
+	s.GaveUp = false
+	s.Turns = nil
+}
+
+// RecordAttempt records the outcome of a start attempt, err is nil on success.
+// It returns true if the attempt made autostart give up on the station.
+func (s *StationState) RecordAttempt(now time.Time, err error, policy BackoffPolicy) bool {
+	s.WindowAttempts++
+	if err == nil {
+		s.Failures = 0
+		s.NextAttempt = time.Time{}
+		s.LastError = ""
+		return false
+	}
+
+	s.Failures++
+	s.WindowFailures++
+	s.LastError = err.Error()
+	wait := policy.Initial
+	for i := 1; i < s.Failures && wait < policy.Max; i++ {
+		wait *= 2
+	}
+	if policy.Max > 0 && wait > policy.Max {
+		wait = policy.Max
+	}
+	s.NextAttempt = now.Add(wait)
+
+	if policy.MaxAttempts > 0 && s.WindowFailures >= policy.MaxAttempts && !s.GaveUp {
+		s.GaveUp = true
+		return true
+	}
+	return false
+}
+
+// ObserveSoC tracks whether the battery reached the target, for the hysteresis
+func (s *StationState) ObserveSoC(soc int, target int, policy BackoffPolicy) {
+	switch {
+	case soc >= target:
+		s.TargetReached = true
+	case soc < target-policy.Hysteresis:
+		s.TargetReached = false
+	}
+}
+
+// SwitchCar makes the hysteresis refer to the given car: another car sharing the station
+// didn't reach its target yet
+func (s *StationState) SwitchCar(carID int) {
+	if s.Car != 0 && s.Car != carID {
+		s.TargetReached = false
+	}
+	s.Car = carID
+}
+
+// StartThreshold returns the battery level below which a session starts: the target,
+// or the target minus the hysteresis once the target was reached
+func (s *StationState) StartThreshold(target int, policy BackoffPolicy) int {
+	if s.TargetReached {
+		return target - policy.Hysteresis
+	}
+	return target
+}
+
+// StationKey identifies a connector in State.Stations
+func StationKey(chargeBoxID string, connectorID int) string {
+	return fmt.Sprintf("%s/%d", chargeBoxID, connectorID)
+}
+
+// Station returns the state of the connector, creating it if needed
+func (s *State) Station(chargeBoxID string, connectorID int) *StationState {
+	if s.Stations == nil {
+		s.Stations = make(map[string]*StationState)
+	}
+	key := StationKey(chargeBoxID, connectorID)
+	station, ok := s.Stations[key]
+	if !ok {
+		station = &StationState{}
+		s.Stations[key] = station
+	}
+	return station
+}
@@ -0,0 +1,124 @@
+package state_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/denysvitali/ekz-tesla/state"
+)
+
+func TestStationState_Backoff(t *testing.T) {
+	policy := state.BackoffPolicy{Initial: 5 * time.Minute, Max: 15 * time.Minute, MaxAttempts: 4}
+	now := time.Date(2025, 1, 13, 22, 0, 0, 0, time.UTC)
+	failure := errors.New("remote start rejected")
+
+	var s state.StationState
+	s.EnterWindow("2025-01-13T20:00")
+	assert.Equal(t, state.StationReady, s.Phase(now))
+
+	assert.False(t, s.RecordAttempt(now, failure, policy))
+	assert.Equal(t, state.StationBackoff, s.Phase(now))
+	assert.Equal(t, now.Add(5*time.Minute), s.NextAttempt)
+	assert.Equal(t, state.StationReady, s.Phase(now.Add(5*time.Minute)))
+
+	assert.False(t, s.RecordAttempt(now, failure, policy))
+	assert.Equal(t, now.Add(10*time.Minute), s.NextAttempt)
+	assert.False(t, s.RecordAttempt(now, failure, policy))
+	assert.Equal(t, now.Add(15*time.Minute), s.NextAttempt, "capped at the maximum")
+	assert.Contains(t, s.Describe(now), "3 failure(s)")
+
+	assert.True(t, s.RecordAttempt(now, failure, policy))
+	assert.Equal(t, state.StationGaveUp, s.Phase(now.Add(time.Hour)))
+	assert.Contains(t, s.Describe(now), "gave up after 4 failed attempts")
+
+	// Same window: still given up
+	s.EnterWindow("2025-01-13T20:00")
+	assert.Equal(t, state.StationGaveUp, s.Phase(now.Add(time.Hour)))
+
+	// A new window resets the counters
+	s.EnterWindow("2025-01-14T20:00")
+	assert.Equal(t, state.StationReady, s.Phase(now))
+	assert.Zero(t, s.WindowAttempts)
+}
+
+func TestStationState_SuccessResetsBackoff(t *testing.T) {
+	policy := state.DefaultBackoffPolicy
+	now := time.Date(2025, 1, 13, 22, 0, 0, 0, time.UTC)
+
+	var s state.StationState
+	s.RecordAttempt(now, errors.New("car not drawing power"), policy)
+	assert.Equal(t, state.StationBackoff, s.Phase(now))
+
+	s.RecordAttempt(now, nil, policy)
+	assert.Equal(t, state.StationReady, s.Phase(now))
+	assert.Zero(t, s.Failures)
+	assert.Empty(t, s.LastError)
+	assert.Equal(t, 2, s.WindowAttempts)
+}
+
+func TestStationState_OnlyFailuresGiveUp(t *testing.T) {
+	policy := state.DefaultBackoffPolicy
+	now := time.Date(2025, 1, 13, 22, 0, 0, 0, time.UTC)
+	failure := errors.New("car not drawing power")
+
+	// Two good starts in the window, e.g. a restart after an interruption
+	var s state.StationState
+	assert.False(t, s.RecordAttempt(now, nil, policy))
+	assert.False(t, s.RecordAttempt(now, nil, policy))
+	assert.False(t, s.RecordAttempt(now, failure, policy))
+	assert.Equal(t, state.StationBackoff, s.Phase(now))
+
+	// Failures count across the successes of the window
+	assert.False(t, s.RecordAttempt(now, nil, policy))
+	assert.False(t, s.RecordAttempt(now, failure, policy))
+	assert.True(t, s.RecordAttempt(now, failure, policy))
+	assert.Equal(t, 6, s.WindowAttempts)
+	assert.Equal(t, 3, s.WindowFailures)
+	assert.Contains(t, s.Describe(now), "gave up after 3 failed attempts")
+}
+
+func TestStationState_Hysteresis(t *testing.T) {
+	policy := state.BackoffPolicy{Hysteresis: 3}
+
+	var s state.StationState
+	assert.Equal(t, 80, s.StartThreshold(80, policy))
+
+	s.ObserveSoC(80, 80, policy)
+	assert.Equal(t, 77, s.StartThreshold(80, policy))
+
+	// A small drop keeps the hysteresis
+	s.ObserveSoC(78, 80, policy)
+	assert.Equal(t, 77, s.StartThreshold(80, policy))
+
+	// Dropping below the hysteresis allows charging to the target again
+	s.ObserveSoC(76, 80, policy)
+	assert.Equal(t, 80, s.StartThreshold(80, policy))
+}
+
+func TestStationState_SwitchCar(t *testing.T) {
+	policy := state.BackoffPolicy{Hysteresis: 3}
+
+	// The state recorded before the cars were known keeps its hysteresis
+	s := state.StationState{TargetReached: true}
+	s.SwitchCar(1)
+	assert.Equal(t, 77, s.StartThreshold(80, policy))
+	s.SwitchCar(1)
+	assert.Equal(t, 77, s.StartThreshold(80, policy))
+
+	// Another car at the station didn't reach its target
+	s.SwitchCar(2)
+	assert.Equal(t, 80, s.StartThreshold(80, policy))
+	assert.Equal(t, 2, s.Car)
+}
+
+func TestState_Station(t *testing.T) {
+	var st state.State
+	st.Station("1234", 1).Failures = 2
+	assert.Equal(t, 2, st.Station("1234", 1).Failures)
+	assert.Zero(t, st.Station("1234", 2).Failures)
+	assert.Len(t, st.Stations, 2)
+	assert.Contains(t, st.Stations, state.StationKey("1234", 1))
+}
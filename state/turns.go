@@ -0,0 +1,154 @@
+package state
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/denysvitali/ekz-tesla/ekz"
+)
+
+// CarNeed is the energy a car needs to reach its target
+type CarNeed struct {
+	Car  int
+	Name string
+	// Need is the energy in kWh
+	Need     float64
+	Priority int
+}
+
+// Turn is the part of the tariff window a car may charge in when several cars share the connector
+type Turn struct {
+	Car  int    `json:"car"`
+	Name string `json:"name,omitempty"`
+	// Need is the energy in kWh the car needed when the turns were planned
+	Need  float64   `json:"need"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	// Reminded is set once the owner was reminded to plug the car in
+	Reminded bool `json:"reminded,omitempty"`
+}
+
+// String describes the turn
+func (t Turn) String() string {
+	car := fmt.Sprintf("car %d", t.Car)
+	if t.Name != "" {
+		car = fmt.Sprintf("car %d (%s)", t.Car, t.Name)
+	}
+	return fmt.Sprintf("%s from %s to %s", car, t.Start.Local().Format("15:04"), t.End.Local().Format("15:04"))
+}
+
+// TurnPlan is the order in which the cars charge in a tariff window
+type TurnPlan struct {
+	// Cars are the cars that needed charge when the turns were planned, including those
+	// left without a turn
+	Cars  []int  `json:"cars"`
+	Turns []Turn `json:"turns"`
+}
+
+// PlanTurns splits the window from start to end between the cars that need charge. The
+// need policy gives each car a share of the window in proportion to its need; the priority
+// policy gives the cars, highest priority first, the time they need at the charge power until
+// the window is used up. The car plugged in, first, charges first among equals to spare a swap.
+// Turns shorter than the minimum are merged into the previous one.
+func PlanTurns(config ekz.FairnessConfig, needs []CarNeed, first int, start, end time.Time) *TurnPlan {
+	plan := &TurnPlan{}
+	var cars []CarNeed
+	for _, need := range needs {
+		if need.Need > 0 {
+			cars = append(cars, need)
+			plan.Cars = append(plan.Cars, need.Car)
+		}
+	}
+	window := end.Sub(start)
+	if len(cars) == 0 || window <= 0 {
+		return plan
+	}
+
+	sort.SliceStable(cars, func(i, j int) bool {
+		if config.Policy == ekz.FairnessPriority && cars[i].Priority != cars[j].Priority {
+			return cars[i].Priority > cars[j].Priority
+		}
+		if (cars[i].Car == first) != (cars[j].Car == first) {
+			return cars[i].Car == first
+		}
+		return cars[i].Need > cars[j].Need
+	})
+
+	durations := make([]time.Duration, len(cars))
+	switch config.Policy {
+	case ekz.FairnessPriority:
+		remaining := window
+		for i, car := range cars {
+			durations[i] = min(time.Duration(car.Need/config.Power()*float64(time.Hour)), remaining)
+			remaining -= durations[i]
+		}
+		// The time left is not needed by anyone, the last car keeps it
+		durations[len(durations)-1] += remaining
+	default:
+		var total float64
+		for _, car := range cars {
+			total += car.Need
+		}
+		for i, car := range cars {
+			durations[i] = time.Duration(float64(window) * car.Need / total)
+		}
+	}
+
+	next := start
+	for i, car := range cars {
+		duration := durations[i].Truncate(time.Minute)
+		if duration < config.MinimumTurn() && len(plan.Turns) > 0 {
+			plan.Turns[len(plan.Turns)-1].End = plan.Turns[len(plan.Turns)-1].End.Add(duration)
+			next = next.Add(duration)
+			continue
+		}
+		if duration < config.MinimumTurn() && i < len(cars)-1 {
+			// The next car takes over the time
+			durations[i+1] += duration
+			continue
+		}
+		plan.Turns = append(plan.Turns, Turn{Car: car.Car, Name: car.Name, Need: car.Need, Start: next, End: next.Add(duration)})
+		next = next.Add(duration)
+	}
+	// Rounding to the minute leaves the end of the window to the last turn
+	plan.Turns[len(plan.Turns)-1].End = end
+	return plan
+}
+
+// At returns the turn at now
+func (p *TurnPlan) At(now time.Time) (*Turn, bool) {
+	if p == nil {
+		return nil, false
+	}
+	for i := range p.Turns {
+		if !now.Before(p.Turns[i].Start) && now.Before(p.Turns[i].End) {
+			return &p.Turns[i], true
+		}
+	}
+	return nil, false
+}
+
+// Covers tells whether all the cars were considered when the turns were planned
+func (p *TurnPlan) Covers(cars []int) bool {
+	if p == nil {
+		return false
+	}
+	for _, car := range cars {
+		if !slices.Contains(p.Cars, car) {
+			return false
+		}
+	}
+	return true
+}
+
+// Remind returns the turn at now if its car wasn't reminded yet, and marks it reminded
+func (p *TurnPlan) Remind(now time.Time) (Turn, bool) {
+	turn, ok := p.At(now)
+	if !ok || turn.Reminded {
+		return Turn{}, false
+	}
+	turn.Reminded = true
+	return *turn, true
+}
@@ -0,0 +1,104 @@
+package state_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/ekz-tesla/ekz"
+	"github.com/denysvitali/ekz-tesla/state"
+)
+
+func TestPlanTurns_Need(t *testing.T) {
+	start := time.Date(2025, 1, 13, 20, 0, 0, 0, time.UTC)
+	end := start.Add(10 * time.Hour)
+	needs := []state.CarNeed{
+		{Car: 1, Name: "Blue", Need: 10},
+		{Car: 2, Need: 30},
+		{Car: 3, Need: 0},
+	}
+
+	plan := state.PlanTurns(ekz.FairnessConfig{Policy: ekz.FairnessNeed}, needs, 1, start, end)
+	assert.Equal(t, []int{1, 2}, plan.Cars, "cars without need are left out")
+	require.Len(t, plan.Turns, 2)
+	assert.Equal(t, state.Turn{Car: 1, Name: "Blue", Need: 10, Start: start, End: start.Add(150 * time.Minute)}, plan.Turns[0],
+		"the car plugged in starts")
+	assert.Equal(t, state.Turn{Car: 2, Need: 30, Start: start.Add(150 * time.Minute), End: end}, plan.Turns[1])
+
+	turn, ok := plan.At(start.Add(3 * time.Hour))
+	require.True(t, ok)
+	assert.Equal(t, 2, turn.Car)
+	_, ok = plan.At(end)
+	assert.False(t, ok)
+
+	assert.True(t, plan.Covers([]int{2}))
+	assert.False(t, plan.Covers([]int{1, 3}))
+	assert.False(t, (*state.TurnPlan)(nil).Covers(nil))
+
+	// Without the car plugged in, the car needing more starts
+	plan = state.PlanTurns(ekz.FairnessConfig{Policy: ekz.FairnessNeed}, needs, 0, start, end)
+	assert.Equal(t, 2, plan.Turns[0].Car)
+}
+
+func TestPlanTurns_Priority(t *testing.T) {
+	start := time.Date(2025, 1, 13, 20, 0, 0, 0, time.UTC)
+	end := start.Add(8 * time.Hour)
+	config := ekz.FairnessConfig{Policy: ekz.FairnessPriority, ChargePower: 10}
+	needs := []state.CarNeed{
+		{Car: 1, Need: 60},
+		{Car: 2, Need: 20, Priority: 1},
+		{Car: 3, Need: 40},
+	}
+
+	plan := state.PlanTurns(config, needs, 1, start, end)
+	assert.Equal(t, []int{1, 2, 3}, plan.Cars)
+	require.Len(t, plan.Turns, 2, "no time is left for car 3")
+	assert.Equal(t, 2, plan.Turns[0].Car, "highest priority first")
+	assert.Equal(t, start.Add(2*time.Hour), plan.Turns[0].End)
+	assert.Equal(t, 1, plan.Turns[1].Car, "the car plugged in before equal priorities")
+	assert.Equal(t, end, plan.Turns[1].End, "capped at the end of the window")
+
+	// Time nobody needs goes to the last car
+	plan = state.PlanTurns(config, needs[1:2], 0, start, end)
+	require.Len(t, plan.Turns, 1)
+	assert.Equal(t, end, plan.Turns[0].End)
+}
+
+func TestPlanTurns_MinTurn(t *testing.T) {
+	start := time.Date(2025, 1, 13, 20, 0, 0, 0, time.UTC)
+	end := start.Add(10 * time.Hour)
+	needs := []state.CarNeed{
+		{Car: 1, Need: 1},
+		{Car: 2, Need: 49},
+		{Car: 3, Need: 50},
+	}
+
+	plan := state.PlanTurns(ekz.FairnessConfig{Policy: ekz.FairnessNeed, MinTurn: time.Hour}, needs, 1, start, end)
+	assert.Equal(t, []int{1, 2, 3}, plan.Cars)
+	require.Len(t, plan.Turns, 2, "the 6 minutes of car 1 are not worth a swap")
+	assert.Equal(t, 3, plan.Turns[0].Car)
+	assert.Equal(t, start, plan.Turns[0].Start)
+	assert.Equal(t, 2, plan.Turns[1].Car)
+	assert.Equal(t, end, plan.Turns[1].End)
+}
+
+func TestTurnPlan_Remind(t *testing.T) {
+	start := time.Date(2025, 1, 13, 20, 0, 0, 0, time.UTC)
+	plan := state.PlanTurns(ekz.FairnessConfig{Policy: ekz.FairnessNeed},
+		[]state.CarNeed{{Car: 1, Need: 10}, {Car: 2, Need: 10}}, 1, start, start.Add(4*time.Hour))
+
+	turn, ok := plan.Remind(start.Add(3 * time.Hour))
+	require.True(t, ok)
+	assert.Equal(t, 2, turn.Car)
+	assert.Equal(t, "car 2 from "+start.Add(2*time.Hour).Local().Format("15:04")+" to "+start.Add(4*time.Hour).Local().Format("15:04"), turn.String())
+	_, ok = plan.Remind(start.Add(3 * time.Hour))
+	assert.False(t, ok, "reminded once per turn")
+
+	var s state.StationState
+	s.EnterWindow("2025-01-13T20:00")
+	s.Turns = plan
+	s.EnterWindow("2025-01-14T20:00")
+	assert.Nil(t, s.Turns, "turns are planned per window")
+}
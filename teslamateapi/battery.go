@@ -0,0 +1,32 @@
+package teslamateapi
+
+import (
+	"context"
+	"strconv"
+)
+
+// BatteryHealth is the battery degradation estimated by TeslaMate
+type BatteryHealth struct {
+	MaxRange        float64 `json:"max_range"`
+	CurrentRange    float64 `json:"current_range"`
+	MaxCapacity     float64 `json:"max_capacity"`
+	CurrentCapacity float64 `json:"current_capacity"`
+	RatedEfficiency float64 `json:"rated_efficiency"`
+	// HealthPercentage is the current capacity relative to the maximum capacity
+	HealthPercentage float64 `json:"battery_health_percentage"`
+}
+
+type batteryHealthResponse struct {
+	Car           Car           `json:"car"`
+	BatteryHealth BatteryHealth `json:"battery_health"`
+	Units         Units         `json:"units"`
+}
+
+// GetBatteryHealth returns the battery health of the car
+func (c *Client) GetBatteryHealth(ctx context.Context, carID int) (*BatteryHealth, error) {
+	var response genericResponse[batteryHealthResponse]
+	if err := c.get(ctx, &response, nil, "cars", strconv.Itoa(carID), "battery-health"); err != nil {
+		return nil, err
+	}
+	return &response.Data.BatteryHealth, nil
+}
@@ -0,0 +1,94 @@
+package teslamateapi
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type CarDetails struct {
+	EID         int64   `json:"eid"`
+	VID         int64   `json:"vid"`
+	VIN         string  `json:"vin"`
+	Model       string  `json:"model"`
+	TrimBadging string  `json:"trim_badging"`
+	Efficiency  float64 `json:"efficiency"`
+}
+
+type CarSettings struct {
+	SuspendMin          int  `json:"suspend_min"`
+	SuspendAfterIdleMin int  `json:"suspend_after_idle_min"`
+	ReqNotUnlocked      bool `json:"req_not_unlocked"`
+	FreeSupercharging   bool `json:"free_supercharging"`
+	UseStreamingAPI     bool `json:"use_streaming_api"`
+}
+
+type TeslaMateDetails struct {
+	InsertedAt time.Time `json:"inserted_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type TeslaMateStats struct {
+	TotalCharges int `json:"total_charges"`
+	TotalDrives  int `json:"total_drives"`
+	TotalUpdates int `json:"total_updates"`
+}
+
+// CarInfo describes a car known to TeslaMate
+type CarInfo struct {
+	CarID            int              `json:"car_id"`
+	Name             string           `json:"name"`
+	CarDetails       CarDetails       `json:"car_details"`
+	CarExterior      Exterior         `json:"car_exterior"`
+	CarSettings      CarSettings      `json:"car_settings"`
+	TeslaMateDetails TeslaMateDetails `json:"teslamate_details"`
+	TeslaMateStats   TeslaMateStats   `json:"teslamate_stats"`
+}
+
+type carsResponse struct {
+	Cars []CarInfo `json:"cars"`
+}
+
+// ListCars returns the cars known to TeslaMate
+func (c *Client) ListCars(ctx context.Context) ([]CarInfo, error) {
+	var response genericResponse[carsResponse]
+	if err := c.get(ctx, &response, nil, "cars"); err != nil {
+		return nil, err
+	}
+	return response.Data.Cars, nil
+}
+
+// GetCar returns the details of the car
+func (c *Client) GetCar(ctx context.Context, carID int) (*CarInfo, error) {
+	var response genericResponse[carsResponse]
+	if err := c.get(ctx, &response, nil, "cars", strconv.Itoa(carID)); err != nil {
+		return nil, err
+	}
+	if len(response.Data.Cars) == 0 {
+		return nil, fmt.Errorf("car %d: %w", carID, ErrNotFound)
+	}
+	return &response.Data.Cars[0], nil
+}
+
+// DiscoverCarID returns the ID of the only car known to TeslaMate. It fails if
+// there is no car or several cars, listing them so one can be chosen.
+func (c *Client) DiscoverCarID(ctx context.Context) (int, error) {
+	cars, err := c.ListCars(ctx)
+	if err != nil {
+		return 0, err
+	}
+	switch len(cars) {
+	case 0:
+		return 0, fmt.Errorf("no car found in TeslaMate")
+	case 1:
+		return cars[0].CarID, nil
+	default:
+		var names []string
+		for _, car := range cars {
+			names = append(names, fmt.Sprintf("%d (%s)", car.CarID, car.Name))
+		}
+		return 0, fmt.Errorf("TeslaMate knows %d cars, choose one of %s", len(cars), strings.Join(names, ", "))
+	}
+}
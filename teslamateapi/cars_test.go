@@ -0,0 +1,49 @@
+package teslamateapi_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/ekz-tesla/teslamateapi"
+)
+
+func TestClient_ListCars(t *testing.T) {
+	c, _ := fixtureServer(t, map[string]string{"/api/v1/cars": "cars.json"})
+
+	cars, err := c.ListCars(context.Background())
+	require.NoError(t, err)
+	require.Len(t, cars, 2)
+	assert.Equal(t, 1, cars[0].CarID)
+	assert.Equal(t, "Snowflake", cars[0].Name)
+	assert.Equal(t, "5YJ3E7EA1LF000001", cars[0].CarDetails.VIN)
+	assert.Equal(t, "3", cars[0].CarDetails.Model)
+	assert.Equal(t, 412, cars[0].TeslaMateStats.TotalCharges)
+	assert.Equal(t, "Y", cars[1].CarDetails.Model)
+}
+
+func TestClient_GetCar(t *testing.T) {
+	c, _ := fixtureServer(t, map[string]string{"/api/v1/cars/1": "car.json"})
+
+	car, err := c.GetCar(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, "Snowflake", car.Name)
+	assert.InDelta(t, 0.153, car.CarDetails.Efficiency, 1e-9)
+	assert.True(t, car.CarSettings.UseStreamingAPI)
+
+	_, err = c.GetCar(context.Background(), 5)
+	assert.ErrorIs(t, err, teslamateapi.ErrNotFound)
+}
+
+func TestClient_DiscoverCarID(t *testing.T) {
+	c, _ := fixtureServer(t, map[string]string{"/api/v1/cars": "car.json"})
+	carID, err := c.DiscoverCarID(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, carID)
+
+	c, _ = fixtureServer(t, map[string]string{"/api/v1/cars": "cars.json"})
+	_, err = c.DiscoverCarID(context.Background())
+	assert.ErrorContains(t, err, "1 (Snowflake), 2 (Thunder)")
+}
@@ -0,0 +1,123 @@
+package teslamateapi
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+type BatteryLevels struct {
+	StartBatteryLevel int `json:"start_battery_level"`
+	EndBatteryLevel   int `json:"end_battery_level"`
+}
+
+type RangeDetails struct {
+	StartRange float64 `json:"start_range"`
+	EndRange   float64 `json:"end_range"`
+}
+
+// Charge is a charging session recorded by TeslaMate
+type Charge struct {
+	ChargeID          int           `json:"charge_id"`
+	StartDate         time.Time     `json:"start_date"`
+	EndDate           time.Time     `json:"end_date"`
+	Address           string        `json:"address"`
+	ChargeEnergyAdded float64       `json:"charge_energy_added"`
+	ChargeEnergyUsed  float64       `json:"charge_energy_used"`
+	Cost              float64       `json:"cost"`
+	DurationMin       int           `json:"duration_min"`
+	DurationStr       string        `json:"duration_str"`
+	BatteryDetails    BatteryLevels `json:"battery_details"`
+	RangeIdeal        RangeDetails  `json:"range_ideal"`
+	RangeRated        RangeDetails  `json:"range_rated"`
+	OutsideTempAvg    float64       `json:"outside_temp_avg"`
+	Odometer          float64       `json:"odometer"`
+	Latitude          float64       `json:"latitude"`
+	Longitude         float64       `json:"longitude"`
+}
+
+type ChargerDetails struct {
+	ChargerActualCurrent int     `json:"charger_actual_current"`
+	ChargerPhases        int     `json:"charger_phases"`
+	ChargerPilotCurrent  int     `json:"charger_pilot_current"`
+	ChargerPower         float64 `json:"charger_power"`
+	ChargerVoltage       int     `json:"charger_voltage"`
+}
+
+// ChargeDetail is a sample taken during a charging session
+type ChargeDetail struct {
+	DetailID           int            `json:"detail_id"`
+	Date               time.Time      `json:"date"`
+	BatteryLevel       int            `json:"battery_level"`
+	UsableBatteryLevel int            `json:"usable_battery_level"`
+	ChargeEnergyAdded  float64        `json:"charge_energy_added"`
+	ChargerDetails     ChargerDetails `json:"charger_details"`
+	OutsideTemp        float64        `json:"outside_temp"`
+}
+
+// ChargeWithDetails is a charging session with its samples
+type ChargeWithDetails struct {
+	Charge
+	ChargeDetails []ChargeDetail `json:"charge_details"`
+}
+
+type chargesResponse struct {
+	Car     Car      `json:"car"`
+	Charges []Charge `json:"charges"`
+	Units   Units    `json:"units"`
+}
+
+type chargeResponse struct {
+	Car    Car               `json:"car"`
+	Charge ChargeWithDetails `json:"charge"`
+	Units  Units             `json:"units"`
+}
+
+// ListOptions filters and pages the charges and drives
+type ListOptions struct {
+	// StartDate and EndDate restrict the results to the time range, if set
+	StartDate time.Time
+	EndDate   time.Time
+	// Page and Show select the page and the page size, if set
+	Page int
+	Show int
+}
+
+func (o *ListOptions) query() url.Values {
+	query := url.Values{}
+	if o == nil {
+		return query
+	}
+	if !o.StartDate.IsZero() {
+		query.Set("startDate", o.StartDate.Format(time.RFC3339))
+	}
+	if !o.EndDate.IsZero() {
+		query.Set("endDate", o.EndDate.Format(time.RFC3339))
+	}
+	if o.Page > 0 {
+		query.Set("page", strconv.Itoa(o.Page))
+	}
+	if o.Show > 0 {
+		query.Set("show", strconv.Itoa(o.Show))
+	}
+	return query
+}
+
+// ListCharges returns the charging sessions of the car, most recent first. opts may be nil.
+func (c *Client) ListCharges(ctx context.Context, carID int, opts *ListOptions) ([]Charge, error) {
+	var response genericResponse[chargesResponse]
+	if err := c.get(ctx, &response, opts.query(), "cars", strconv.Itoa(carID), "charges"); err != nil {
+		return nil, err
+	}
+	return response.Data.Charges, nil
+}
+
+// GetCharge returns the charging session with its samples
+func (c *Client) GetCharge(ctx context.Context, carID int, chargeID int) (*ChargeWithDetails, error) {
+	var response genericResponse[chargeResponse]
+	if err := c.get(ctx, &response, nil, "cars", strconv.Itoa(carID), "charges", strconv.Itoa(chargeID)); err != nil {
+		return nil, err
+	}
+	return &response.Data.Charge, nil
+}
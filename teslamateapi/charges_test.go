@@ -0,0 +1,56 @@
+package teslamateapi_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/ekz-tesla/teslamateapi"
+)
+
+func TestClient_ListCharges(t *testing.T) {
+	c, query := fixtureServer(t, map[string]string{"/api/v1/cars/1/charges": "charges.json"})
+
+	charges, err := c.ListCharges(context.Background(), 1, nil)
+	require.NoError(t, err)
+	require.Len(t, charges, 2)
+	assert.Empty(t, *query)
+
+	charge := charges[0]
+	assert.Equal(t, 412, charge.ChargeID)
+	assert.Equal(t, time.Date(2025, 1, 13, 20, 5, 12, 0, time.UTC), charge.StartDate)
+	assert.Equal(t, time.Date(2025, 1, 14, 1, 42, 30, 0, time.UTC), charge.EndDate)
+	assert.InDelta(t, 31.52, charge.ChargeEnergyAdded, 1e-9)
+	assert.InDelta(t, 6.82, charge.Cost, 1e-9)
+	assert.Equal(t, 38, charge.BatteryDetails.StartBatteryLevel)
+	assert.Equal(t, 80, charge.BatteryDetails.EndBatteryLevel)
+
+	_, err = c.ListCharges(context.Background(), 1, &teslamateapi.ListOptions{
+		StartDate: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC),
+		Page:      2,
+		Show:      50,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "2025-01-01T00:00:00Z", query.Get("startDate"))
+	assert.Equal(t, "2025-02-01T00:00:00Z", query.Get("endDate"))
+	assert.Equal(t, "2", query.Get("page"))
+	assert.Equal(t, "50", query.Get("show"))
+}
+
+func TestClient_GetCharge(t *testing.T) {
+	c, _ := fixtureServer(t, map[string]string{"/api/v1/cars/1/charges/412": "charge.json"})
+
+	charge, err := c.GetCharge(context.Background(), 1, 412)
+	require.NoError(t, err)
+	assert.Equal(t, 412, charge.ChargeID)
+	require.Len(t, charge.ChargeDetails, 2)
+	assert.Equal(t, 3, charge.ChargeDetails[0].ChargerDetails.ChargerPhases)
+	assert.InDelta(t, 11, charge.ChargeDetails[1].ChargerDetails.ChargerPower, 1e-9)
+
+	_, err = c.GetCharge(context.Background(), 1, 1)
+	assert.ErrorIs(t, err, teslamateapi.ErrNotFound)
+}
@@ -1,45 +1,201 @@
 package teslamateapi
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
+	"strconv"
+	"time"
 )
 
+const (
+	// DefaultTimeout bounds a request to TeslaMateApi, including reading the response
+	DefaultTimeout = 30 * time.Second
+	// DefaultBasePath is the path of the API below the TeslaMateApi address
+	DefaultBasePath = "/api/v1"
+
+	// maxErrorBody bounds the part of an error response kept in a StatusError
+	maxErrorBody = 512
+)
+
+var (
+	// ErrUnauthorized is returned when TeslaMateApi rejects the token (401 or 403)
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrNotFound is returned when the requested resource, e.g. the car, doesn't exist
+	ErrNotFound = errors.New("not found")
+	// ErrServer is returned on 5xx responses
+	ErrServer = errors.New("server error")
+)
+
+// StatusError is returned for unexpected HTTP status codes. It wraps ErrUnauthorized,
+// ErrNotFound or ErrServer depending on the status code.
+type StatusError struct {
+	StatusCode int
+	Status     string
+	URL        string
+	// Body is the start of the response body
+	Body string
+}
+
+func (e *StatusError) Error() string {
+	msg := fmt.Sprintf("unexpected status %s from %s", e.Status, e.URL)
+	if e.Body != "" {
+		msg += ": " + e.Body
+	}
+	return msg
+}
+
+func (e *StatusError) Unwrap() error {
+	switch {
+	case e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden:
+		return ErrUnauthorized
+	case e.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case e.StatusCode >= 500:
+		return ErrServer
+	default:
+		return nil
+	}
+}
+
 type Client struct {
-	endpoint string
+	endpoint   *url.URL
+	basePath   string
+	token      string
+	httpClient *http.Client
+	timeout    time.Duration
 }
 
-func New(addr string) (*Client, error) {
-	_, err := url.Parse(addr)
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client used for the requests
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithTimeout bounds every request, 0 disables the timeout
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = timeout
+	}
+}
+
+// WithToken authenticates the requests with the bearer token configured as API_TOKEN in TeslaMateApi
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithBasePath sets the path of the API below the address, by default /api/v1
+func WithBasePath(basePath string) Option {
+	return func(c *Client) {
+		c.basePath = basePath
+	}
+}
+
+// New creates a client for the TeslaMateApi instance at addr, e.g. http://teslamate-api:8080
+func New(addr string, opts ...Option) (*Client, error) {
+	endpoint, err := url.Parse(addr)
 	if err != nil {
 		return nil, err
 	}
+	if endpoint.Scheme != "http" && endpoint.Scheme != "https" || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid TeslaMateApi address %q, expected http(s)://host[:port]", addr)
+	}
 
 	c := Client{
-		endpoint: addr,
+		endpoint: endpoint,
+		basePath: DefaultBasePath,
+		timeout:  DefaultTimeout,
 	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	// Apply the timeout to a copy, the HTTP client may be shared
+	httpClient := http.Client{}
+	if c.httpClient != nil {
+		httpClient = *c.httpClient
+	}
+	httpClient.Timeout = c.timeout
+	c.httpClient = &httpClient
 
 	return &c, nil
 }
 
+// GetCarStatus returns the status of the car
 func (c *Client) GetCarStatus(carId int) (*CarStatusResponse, error) {
-	res, err := http.Get(c.endpoint + "/api/v1/cars/" + fmt.Sprintf("%d", carId) + "/status")
-	if err != nil {
+	return c.GetCarStatusContext(context.Background(), carId)
+}
+
+// GetCarStatusContext returns the status of the car
+func (c *Client) GetCarStatusContext(ctx context.Context, carID int) (*CarStatusResponse, error) {
+	var response genericResponse[CarStatusResponse]
+	if err := c.get(ctx, &response, nil, "cars", strconv.Itoa(carID), "status"); err != nil {
 		return nil, err
 	}
+	return &response.Data, nil
+}
 
-	if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status %s", res.Status)
+// url returns the URL of the API path made of elem, with the query if not empty
+func (c *Client) url(query url.Values, elem ...string) string {
+	u := c.endpoint.JoinPath(append([]string{c.basePath}, elem...)...)
+	if len(query) > 0 {
+		u.RawQuery = query.Encode()
 	}
+	return u.String()
+}
 
-	// Decode JSON
-	var response genericResponse[CarStatusResponse]
-	err = json.NewDecoder(res.Body).Decode(&response)
+// get decodes the JSON response of the API path made of elem into out
+func (c *Client) get(ctx context.Context, out any, query url.Values, elem ...string) error {
+	return c.do(ctx, http.MethodGet, nil, out, query, elem...)
+}
+
+// do sends body as JSON, if not nil, and decodes the JSON response of the API path made of elem into out
+func (c *Client) do(ctx context.Context, method string, body any, out any, query url.Values, elem ...string) error {
+	u := c.url(query, elem...)
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
 	}
 
-	return &response.Data, nil
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, maxErrorBody))
+		return &StatusError{StatusCode: res.StatusCode, Status: res.Status, URL: u, Body: string(body)}
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response from %s: %w", u, err)
+	}
+	return nil
 }
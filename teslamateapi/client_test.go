@@ -1,10 +1,17 @@
 package teslamateapi_test
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/denysvitali/ekz-tesla/teslamateapi"
 )
@@ -26,3 +33,109 @@ func TestClient_GetCarStatus(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, expectedCarName, status.Car.CarName)
 }
+
+func TestClient_Options(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/teslamate/api/v2/cars/3/status", r.URL.Path)
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(`{"data":{"car":{"car_id":3,"car_name":"Model 3"},"status":{"state":"online"}}}`))
+	}))
+	defer server.Close()
+
+	c, err := teslamateapi.New(server.URL+"/teslamate",
+		teslamateapi.WithToken("secret"),
+		teslamateapi.WithBasePath("/api/v2"),
+		teslamateapi.WithHTTPClient(server.Client()),
+	)
+	require.NoError(t, err)
+	status, err := c.GetCarStatus(3)
+	require.NoError(t, err)
+	assert.Equal(t, "Model 3", status.Car.CarName)
+	assert.Equal(t, "online", status.Status.State)
+}
+
+func TestClient_StatusErrors(t *testing.T) {
+	tests := []struct {
+		status   int
+		expected error
+	}{
+		{http.StatusUnauthorized, teslamateapi.ErrUnauthorized},
+		{http.StatusForbidden, teslamateapi.ErrUnauthorized},
+		{http.StatusNotFound, teslamateapi.ErrNotFound},
+		{http.StatusBadGateway, teslamateapi.ErrServer},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, `{"error":"nope"}`, tt.status)
+			}))
+			defer server.Close()
+
+			c, err := teslamateapi.New(server.URL)
+			require.NoError(t, err)
+			_, err = c.GetCarStatus(1)
+			assert.ErrorIs(t, err, tt.expected)
+
+			var statusErr *teslamateapi.StatusError
+			require.ErrorAs(t, err, &statusErr)
+			assert.Equal(t, tt.status, statusErr.StatusCode)
+			assert.Contains(t, statusErr.Body, "nope")
+		})
+	}
+}
+
+func TestClient_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	c, err := teslamateapi.New(server.URL, teslamateapi.WithTimeout(50*time.Millisecond))
+	require.NoError(t, err)
+	_, err = c.GetCarStatus(1)
+	assert.Error(t, err)
+
+	// The context cancels the request as well
+	c, err = teslamateapi.New(server.URL, teslamateapi.WithTimeout(0))
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = c.GetCarStatusContext(ctx, 1)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestNew_InvalidAddress(t *testing.T) {
+	for _, addr := range []string{"", "teslamate-api:8080", "ftp://teslamate-api"} {
+		_, err := teslamateapi.New(addr)
+		assert.Error(t, err, addr)
+	}
+}
+
+// fixtureServer serves the files of testdata by API path, and records the query of the last request
+func fixtureServer(t *testing.T, fixtures map[string]string) (*teslamateapi.Client, *url.Values) {
+	t.Helper()
+	var lastQuery url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fixture, ok := fixtures[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		lastQuery = r.URL.Query()
+		data, err := os.ReadFile(filepath.Join("testdata", fixture))
+		require.NoError(t, err)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(data)
+	}))
+	t.Cleanup(server.Close)
+
+	c, err := teslamateapi.New(server.URL)
+	require.NoError(t, err)
+	return c, &lastQuery
+}
@@ -0,0 +1,105 @@
+package teslamateapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+)
+
+// Commands accepted by TeslaMateApi. They require ENABLE_COMMANDS and an API_TOKEN in TeslaMateApi.
+const (
+	CommandSetChargeLimit  = "set_charge_limit"
+	CommandSetChargingAmps = "set_charging_amps"
+	CommandChargeStart     = "charge_start"
+	CommandChargeStop      = "charge_stop"
+	CommandClimateStart    = "auto_conditioning_start"
+	CommandClimateStop     = "auto_conditioning_stop"
+)
+
+// ErrCommandFailed is wrapped by the errors of commands the car refused
+var ErrCommandFailed = errors.New("command failed")
+
+// CommandError is returned when the car refuses a command
+type CommandError struct {
+	Command string
+	Reason  string
+}
+
+func (e *CommandError) Error() string {
+	return fmt.Sprintf("command %s failed: %s", e.Command, e.Reason)
+}
+
+func (e *CommandError) Unwrap() error {
+	return ErrCommandFailed
+}
+
+// CommandResult is the result of a command as reported by the car
+type CommandResult struct {
+	Result bool   `json:"result"`
+	Reason string `json:"reason"`
+}
+
+type commandResponse struct {
+	Response CommandResult `json:"response"`
+}
+
+type wakeUpResponse struct {
+	Response struct {
+		State string `json:"state"`
+	} `json:"response"`
+}
+
+// WakeUp wakes the car up and returns the state it reports, e.g. online or asleep while waking up
+func (c *Client) WakeUp(ctx context.Context, carID int) (string, error) {
+	var response wakeUpResponse
+	if err := c.do(ctx, http.MethodPost, nil, &response, nil, "cars", strconv.Itoa(carID), "wake_up"); err != nil {
+		return "", err
+	}
+	return response.Response.State, nil
+}
+
+// Command sends the command with the parameters, nil if it has none. Refusals for
+// one of the accepted reasons, e.g. "already_set", count as success.
+func (c *Client) Command(ctx context.Context, carID int, command string, params any, accepted ...string) error {
+	var response commandResponse
+	if err := c.do(ctx, http.MethodPost, params, &response, nil, "cars", strconv.Itoa(carID), "command", command); err != nil {
+		return err
+	}
+	if !response.Response.Result && !slices.Contains(accepted, response.Response.Reason) {
+		return &CommandError{Command: command, Reason: response.Response.Reason}
+	}
+	return nil
+}
+
+// SetChargeLimit sets the charge limit of the car in percent
+func (c *Client) SetChargeLimit(ctx context.Context, carID int, percent int) error {
+	return c.Command(ctx, carID, CommandSetChargeLimit, map[string]int{"percent": percent}, "already_set")
+}
+
+// SetChargingAmps sets the charging current of the car
+func (c *Client) SetChargingAmps(ctx context.Context, carID int, amps int) error {
+	return c.Command(ctx, carID, CommandSetChargingAmps, map[string]int{"charging_amps": amps})
+}
+
+// ChargeStart makes the car start charging, it succeeds if the car is already charging
+func (c *Client) ChargeStart(ctx context.Context, carID int) error {
+	return c.Command(ctx, carID, CommandChargeStart, nil, "is_charging", "complete")
+}
+
+// ChargeStop makes the car stop charging, it succeeds if the car isn't charging
+func (c *Client) ChargeStop(ctx context.Context, carID int) error {
+	return c.Command(ctx, carID, CommandChargeStop, nil, "not_charging")
+}
+
+// ClimateStart turns the climate control on
+func (c *Client) ClimateStart(ctx context.Context, carID int) error {
+	return c.Command(ctx, carID, CommandClimateStart, nil)
+}
+
+// ClimateStop turns the climate control off
+func (c *Client) ClimateStop(ctx context.Context, carID int) error {
+	return c.Command(ctx, carID, CommandClimateStop, nil)
+}
@@ -0,0 +1,87 @@
+package teslamateapi_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/ekz-tesla/teslamateapi"
+)
+
+type recordedCommand struct {
+	path string
+	body map[string]any
+}
+
+// commandServer replies to every command with the result and reason, and records the commands
+func commandServer(t *testing.T, result bool, reason string) (*teslamateapi.Client, *[]recordedCommand) {
+	t.Helper()
+	var commands []recordedCommand
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		command := recordedCommand{path: r.URL.Path}
+		data, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		if len(data) > 0 {
+			require.NoError(t, json.Unmarshal(data, &command.body))
+		}
+		commands = append(commands, command)
+
+		if r.URL.Path == "/api/v1/cars/1/wake_up" {
+			_, _ = w.Write([]byte(`{"response":{"id":1,"state":"online"}}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"response": map[string]any{"result": result, "reason": reason}})
+	}))
+	t.Cleanup(server.Close)
+
+	c, err := teslamateapi.New(server.URL, teslamateapi.WithToken("secret"))
+	require.NoError(t, err)
+	return c, &commands
+}
+
+func TestClient_Commands(t *testing.T) {
+	c, commands := commandServer(t, true, "")
+	ctx := context.Background()
+
+	state, err := c.WakeUp(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "online", state)
+	require.NoError(t, c.SetChargeLimit(ctx, 1, 80))
+	require.NoError(t, c.SetChargingAmps(ctx, 1, 16))
+	require.NoError(t, c.ChargeStart(ctx, 1))
+	require.NoError(t, c.ChargeStop(ctx, 1))
+	require.NoError(t, c.ClimateStart(ctx, 1))
+	require.NoError(t, c.ClimateStop(ctx, 1))
+
+	assert.Equal(t, []recordedCommand{
+		{path: "/api/v1/cars/1/wake_up"},
+		{path: "/api/v1/cars/1/command/set_charge_limit", body: map[string]any{"percent": 80.0}},
+		{path: "/api/v1/cars/1/command/set_charging_amps", body: map[string]any{"charging_amps": 16.0}},
+		{path: "/api/v1/cars/1/command/charge_start"},
+		{path: "/api/v1/cars/1/command/charge_stop"},
+		{path: "/api/v1/cars/1/command/auto_conditioning_start"},
+		{path: "/api/v1/cars/1/command/auto_conditioning_stop"},
+	}, *commands)
+}
+
+func TestClient_CommandRefused(t *testing.T) {
+	c, _ := commandServer(t, false, "is_charging")
+	ctx := context.Background()
+
+	require.NoError(t, c.ChargeStart(ctx, 1), "already charging counts as success")
+
+	err := c.SetChargingAmps(ctx, 1, 16)
+	assert.ErrorIs(t, err, teslamateapi.ErrCommandFailed)
+	var commandErr *teslamateapi.CommandError
+	require.ErrorAs(t, err, &commandErr)
+	assert.Equal(t, "set_charging_amps", commandErr.Command)
+	assert.Equal(t, "is_charging", commandErr.Reason)
+}
@@ -0,0 +1,60 @@
+package teslamateapi
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+type OdometerDetails struct {
+	OdometerStart    float64 `json:"odometer_start"`
+	OdometerEnd      float64 `json:"odometer_end"`
+	OdometerDistance float64 `json:"odometer_distance"`
+}
+
+type DriveBatteryDetails struct {
+	StartUsableBatteryLevel int  `json:"start_usable_battery_level"`
+	StartBatteryLevel       int  `json:"start_battery_level"`
+	EndUsableBatteryLevel   int  `json:"end_usable_battery_level"`
+	EndBatteryLevel         int  `json:"end_battery_level"`
+	ReducedRange            bool `json:"reduced_range"`
+	IsSufficientlyPrecise   bool `json:"is_sufficiently_precise"`
+}
+
+// Drive is a drive recorded by TeslaMate
+type Drive struct {
+	DriveID           int                 `json:"drive_id"`
+	StartDate         time.Time           `json:"start_date"`
+	EndDate           time.Time           `json:"end_date"`
+	StartAddress      string              `json:"start_address"`
+	EndAddress        string              `json:"end_address"`
+	OdometerDetails   OdometerDetails     `json:"odometer_details"`
+	DurationMin       int                 `json:"duration_min"`
+	DurationStr       string              `json:"duration_str"`
+	SpeedMax          int                 `json:"speed_max"`
+	SpeedAvg          float64             `json:"speed_avg"`
+	PowerMax          int                 `json:"power_max"`
+	PowerMin          int                 `json:"power_min"`
+	BatteryDetails    DriveBatteryDetails `json:"battery_details"`
+	RangeIdeal        RangeDetails        `json:"range_ideal"`
+	RangeRated        RangeDetails        `json:"range_rated"`
+	OutsideTempAvg    float64             `json:"outside_temp_avg"`
+	InsideTempAvg     float64             `json:"inside_temp_avg"`
+	EnergyConsumedNet float64             `json:"energy_consumed_net"`
+	ConsumptionNet    float64             `json:"consumption_net"`
+}
+
+type drivesResponse struct {
+	Car    Car     `json:"car"`
+	Drives []Drive `json:"drives"`
+	Units  Units   `json:"units"`
+}
+
+// ListDrives returns the drives of the car, most recent first. opts may be nil.
+func (c *Client) ListDrives(ctx context.Context, carID int, opts *ListOptions) ([]Drive, error) {
+	var response genericResponse[drivesResponse]
+	if err := c.get(ctx, &response, opts.query(), "cars", strconv.Itoa(carID), "drives"); err != nil {
+		return nil, err
+	}
+	return response.Data.Drives, nil
+}
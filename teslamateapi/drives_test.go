@@ -0,0 +1,32 @@
+package teslamateapi_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_ListDrives(t *testing.T) {
+	c, _ := fixtureServer(t, map[string]string{"/api/v1/cars/1/drives": "drives.json"})
+
+	drives, err := c.ListDrives(context.Background(), 1, nil)
+	require.NoError(t, err)
+	require.Len(t, drives, 1)
+	drive := drives[0]
+	assert.Equal(t, 3120, drive.DriveID)
+	assert.Equal(t, "Bahnhofstrasse 1, Zürich", drive.EndAddress)
+	assert.InDelta(t, 24.4, drive.OdometerDetails.OdometerDistance, 1e-9)
+	assert.Equal(t, 38, drive.BatteryDetails.EndBatteryLevel)
+	assert.Equal(t, -60, drive.PowerMin)
+}
+
+func TestClient_GetBatteryHealth(t *testing.T) {
+	c, _ := fixtureServer(t, map[string]string{"/api/v1/cars/1/battery-health": "battery-health.json"})
+
+	health, err := c.GetBatteryHealth(context.Background(), 1)
+	require.NoError(t, err)
+	assert.InDelta(t, 92.7, health.HealthPercentage, 1e-9)
+	assert.InDelta(t, 70.3, health.CurrentCapacity, 1e-9)
+}
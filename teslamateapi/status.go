@@ -41,6 +41,7 @@ type ChargingDetails struct {
 	ChargerPhases              int       `json:"charger_phases"`
 	ChargerPower               float32   `json:"charger_power"`
 	ChargerVoltage             float32   `json:"charger_voltage"`
+	ChargingState              string    `json:"charging_state"`
 	PluggedIn                  bool      `json:"plugged_in"`
 	ScheduledChargingStartTime time.Time `json:"scheduled_charging_start_time"`
 	TimeToFullCharge           float32   `json:"time_to_full_charge"`
@@ -119,3 +120,45 @@ type CarStatusResponse struct {
 type genericResponse[T any] struct {
 	Data T `json:"data"`
 }
+
+// ChargingState is the charging state reported by the car
+type ChargingState string
+
+const (
+	ChargingStateCharging     ChargingState = "Charging"
+	ChargingStateStarting     ChargingState = "Starting"
+	ChargingStateComplete     ChargingState = "Complete"
+	ChargingStateStopped      ChargingState = "Stopped"
+	ChargingStateDisconnected ChargingState = "Disconnected"
+	ChargingStateNoPower      ChargingState = "NoPower"
+)
+
+// CurrentChargingState returns the charging state of the car. Older TeslaMateApi
+// versions don't report it, in which case it is derived from the car state.
+func (s CarStatus) CurrentChargingState() ChargingState {
+	if s.ChargingDetails.ChargingState != "" {
+		return ChargingState(s.ChargingDetails.ChargingState)
+	}
+	switch {
+	case s.State == "charging":
+		return ChargingStateCharging
+	case !s.ChargingDetails.PluggedIn:
+		return ChargingStateDisconnected
+	default:
+		return ChargingStateStopped
+	}
+}
+
+// DataAge returns how old the reported data may be. While the car is awake, TeslaMate
+// receives live data; while it is asleep or offline, the data dates back to StateSince.
+func (s CarStatus) DataAge(now time.Time) time.Duration {
+	switch s.State {
+	case "asleep", "offline", "suspended":
+		if s.StateSince.IsZero() {
+			return 0
+		}
+		return now.Sub(s.StateSince)
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,55 @@
+package teslamateapi_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/denysvitali/ekz-tesla/teslamateapi"
+)
+
+func TestCarStatus_CurrentChargingState(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   teslamateapi.CarStatus
+		expected teslamateapi.ChargingState
+	}{
+		{
+			name:     "reported by the car",
+			status:   teslamateapi.CarStatus{State: "online", ChargingDetails: teslamateapi.ChargingDetails{ChargingState: "Complete", PluggedIn: true}},
+			expected: teslamateapi.ChargingStateComplete,
+		},
+		{
+			name:     "derived from charging state",
+			status:   teslamateapi.CarStatus{State: "charging", ChargingDetails: teslamateapi.ChargingDetails{PluggedIn: true}},
+			expected: teslamateapi.ChargingStateCharging,
+		},
+		{
+			name:     "derived from plugged in",
+			status:   teslamateapi.CarStatus{State: "online"},
+			expected: teslamateapi.ChargingStateDisconnected,
+		},
+		{
+			name:     "derived stopped",
+			status:   teslamateapi.CarStatus{State: "asleep", ChargingDetails: teslamateapi.ChargingDetails{PluggedIn: true}},
+			expected: teslamateapi.ChargingStateStopped,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.status.CurrentChargingState())
+		})
+	}
+}
+
+func TestCarStatus_DataAge(t *testing.T) {
+	now := time.Date(2025, 1, 13, 22, 0, 0, 0, time.UTC)
+
+	online := teslamateapi.CarStatus{State: "online", StateSince: now.Add(-5 * time.Hour)}
+	assert.Equal(t, time.Duration(0), online.DataAge(now))
+
+	asleep := teslamateapi.CarStatus{State: "asleep", StateSince: now.Add(-5 * time.Hour)}
+	assert.Equal(t, 5*time.Hour, asleep.DataAge(now))
+}
@@ -0,0 +1,114 @@
+package teslamatedb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	geo "github.com/kellydunn/golang-geo"
+)
+
+// Car is a car known to TeslaMate
+type Car struct {
+	ID          int
+	Name        string
+	Model       string
+	TrimBadging string
+	VIN         string
+}
+
+// Geofence is a circular area defined in TeslaMate
+type Geofence struct {
+	ID        int
+	Name      string
+	Latitude  float64
+	Longitude float64
+	// Radius is the radius in meters
+	Radius float64
+}
+
+// Contains tells whether the point is inside the geofence
+func (g Geofence) Contains(latitude, longitude float64) bool {
+	center := geo.NewPoint(g.Latitude, g.Longitude)
+	return center.GreatCircleDistance(geo.NewPoint(latitude, longitude))*1000 <= g.Radius
+}
+
+// ContainingGeofence returns the name of the smallest geofence containing the point,
+// empty if there is none, as TeslaMate does for overlapping geofences
+func ContainingGeofence(geofences []Geofence, latitude, longitude float64) string {
+	var match *Geofence
+	for i, g := range geofences {
+		if g.Contains(latitude, longitude) && (match == nil || g.Radius < match.Radius) {
+			match = &geofences[i]
+		}
+	}
+	if match == nil {
+		return ""
+	}
+	return match.Name
+}
+
+// Cars returns the cars known to TeslaMate, ordered by ID
+func (d *DB) Cars(ctx context.Context) ([]Car, error) {
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT id, COALESCE(name, ''), COALESCE(model, ''), COALESCE(trim_badging, ''), COALESCE(vin, '')
+		FROM cars
+		ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cars: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var cars []Car
+	for rows.Next() {
+		var car Car
+		if err := rows.Scan(&car.ID, &car.Name, &car.Model, &car.TrimBadging, &car.VIN); err != nil {
+			return nil, fmt.Errorf("failed to read car: %w", err)
+		}
+		cars = append(cars, car)
+	}
+	return cars, rows.Err()
+}
+
+// DiscoverCarID returns the ID of the only car known to TeslaMate. It fails if
+// there is no car or several cars, listing them so one can be chosen.
+func (d *DB) DiscoverCarID(ctx context.Context) (int, error) {
+	cars, err := d.Cars(ctx)
+	if err != nil {
+		return 0, err
+	}
+	switch len(cars) {
+	case 0:
+		return 0, fmt.Errorf("no car found in TeslaMate")
+	case 1:
+		return cars[0].ID, nil
+	default:
+		var names []string
+		for _, car := range cars {
+			names = append(names, fmt.Sprintf("%d (%s)", car.ID, car.Name))
+		}
+		return 0, fmt.Errorf("TeslaMate knows %d cars, choose one of %s", len(cars), strings.Join(names, ", "))
+	}
+}
+
+// Geofences returns the geofences defined in TeslaMate
+func (d *DB) Geofences(ctx context.Context) ([]Geofence, error) {
+	rows, err := d.db.QueryContext(ctx, `SELECT id, name, latitude, longitude, radius FROM geofences ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query geofences: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var geofences []Geofence
+	for rows.Next() {
+		var g Geofence
+		var radius sql.NullFloat64
+		if err := rows.Scan(&g.ID, &g.Name, &g.Latitude, &g.Longitude, &radius); err != nil {
+			return nil, fmt.Errorf("failed to read geofence: %w", err)
+		}
+		g.Radius = radius.Float64
+		geofences = append(geofences, g)
+	}
+	return geofences, rows.Err()
+}
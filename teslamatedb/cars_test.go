@@ -0,0 +1,41 @@
+package teslamatedb_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/ekz-tesla/teslamatedb"
+)
+
+func TestContainingGeofence(t *testing.T) {
+	geofences := []teslamatedb.Geofence{
+		{ID: 1, Name: "Zurich", Latitude: 47.3769, Longitude: 8.5417, Radius: 2000},
+		{ID: 2, Name: "Home", Latitude: 47.3769, Longitude: 8.5417, Radius: 50},
+	}
+	assert.Equal(t, "Home", teslamatedb.ContainingGeofence(geofences, 47.3770, 8.5417))
+	assert.Equal(t, "Zurich", teslamatedb.ContainingGeofence(geofences, 47.3800, 8.5417))
+	assert.Empty(t, teslamatedb.ContainingGeofence(geofences, 47.5, 8.7))
+	assert.Empty(t, teslamatedb.ContainingGeofence(nil, 47.3769, 8.5417))
+}
+
+func TestDB_Cars(t *testing.T) {
+	db := fixtureDB(t)
+
+	cars, err := db.Cars(context.Background())
+	require.NoError(t, err)
+	require.Len(t, cars, 2)
+	assert.Equal(t, teslamatedb.Car{ID: 1, Name: "Blue", Model: "3", TrimBadging: "P74D", VIN: "5YJ3E7EA1KF000001"}, cars[0])
+
+	_, err = db.DiscoverCarID(context.Background())
+	assert.ErrorContains(t, err, "choose one of 1 (Blue), 2 (White)")
+
+	geofences, err := db.Geofences(context.Background())
+	require.NoError(t, err)
+	require.Len(t, geofences, 2)
+	assert.Equal(t, "Home", geofences[1].Name)
+	assert.InDelta(t, 47.3769, geofences[1].Latitude, 1e-9)
+	assert.InDelta(t, 50, geofences[1].Radius, 1e-9)
+}
@@ -0,0 +1,147 @@
+package teslamatedb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ChargingProcess is a charge recorded by TeslaMate
+type ChargingProcess struct {
+	ID    int
+	CarID int
+	Start time.Time
+	// End is zero while the car is charging
+	End time.Time
+	// ChargeEnergyAdded is the energy added to the battery in kWh. While the car is
+	// charging, it is the energy added so far.
+	ChargeEnergyAdded float64
+	// ChargeEnergyUsed is the energy drawn from the charger in kWh, 0 if unknown
+	ChargeEnergyUsed  float64
+	StartBatteryLevel int
+	// EndBatteryLevel is the current battery level while the car is charging
+	EndBatteryLevel int
+	// Cost is the cost entered in TeslaMate, nil if there is none
+	Cost *float64
+	// Geofence is the name of the TeslaMate geofence the car charged in
+	Geofence string
+}
+
+// Ongoing tells whether the car is still charging
+func (c ChargingProcess) Ongoing() bool {
+	return c.End.IsZero()
+}
+
+const chargingProcessColumns = `
+	cp.id, cp.car_id, cp.start_date, cp.end_date, cp.charge_energy_added, cp.charge_energy_used,
+	cp.start_battery_level, cp.end_battery_level, cp.cost, COALESCE(g.name, '')`
+
+// ChargingProcesses returns the charges of the car started in [from, to), oldest first.
+// A car ID of 0 returns the charges of all cars.
+func (d *DB) ChargingProcesses(ctx context.Context, carID int, from, to time.Time) ([]ChargingProcess, error) {
+	// The timestamps are stored in UTC without time zone
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT `+chargingProcessColumns+`
+		FROM charging_processes cp
+		LEFT JOIN geofences g ON g.id = cp.geofence_id
+		WHERE ($1 = 0 OR cp.car_id = $1) AND cp.start_date >= $2 AND cp.start_date < $3
+		ORDER BY cp.start_date`, carID, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query charging processes: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var charges []ChargingProcess
+	for rows.Next() {
+		charge, err := scanChargingProcess(rows)
+		if err != nil {
+			return nil, err
+		}
+		charges = append(charges, *charge)
+	}
+	return charges, rows.Err()
+}
+
+// ChargingProcess returns the charge with the given ID
+func (d *DB) ChargingProcess(ctx context.Context, id int) (*ChargingProcess, error) {
+	row := d.db.QueryRowContext(ctx, `
+		SELECT `+chargingProcessColumns+`
+		FROM charging_processes cp
+		LEFT JOIN geofences g ON g.id = cp.geofence_id
+		WHERE cp.id = $1`, id)
+	charge, err := scanChargingProcess(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("charging process %d: %w", id, ErrNotFound)
+	}
+	return charge, err
+}
+
+// openChargingProcess returns the charge in progress with the values of its last sample, nil if there is none
+func (d *DB) openChargingProcess(ctx context.Context, carID int) (*ChargingProcess, error) {
+	row := d.db.QueryRowContext(ctx, `
+		SELECT `+chargingProcessColumns+`
+		FROM charging_processes cp
+		LEFT JOIN geofences g ON g.id = cp.geofence_id
+		WHERE cp.car_id = $1 AND cp.end_date IS NULL
+		ORDER BY cp.start_date DESC
+		LIMIT 1`, carID)
+	charge, err := scanChargingProcess(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The totals of the process are only written when it ends
+	var batteryLevel sql.NullInt64
+	var energyAdded sql.NullFloat64
+	err = d.db.QueryRowContext(ctx, `
+		SELECT battery_level, charge_energy_added
+		FROM charges
+		WHERE charging_process_id = $1
+		ORDER BY date DESC
+		LIMIT 1`, charge.ID).Scan(&batteryLevel, &energyAdded)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		return nil, fmt.Errorf("failed to query charges: %w", err)
+	default:
+		charge.EndBatteryLevel = int(batteryLevel.Int64)
+		charge.ChargeEnergyAdded = energyAdded.Float64
+	}
+	return charge, nil
+}
+
+// scanner is implemented by sql.Row and sql.Rows
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanChargingProcess(row scanner) (*ChargingProcess, error) {
+	var c ChargingProcess
+	var end sql.NullTime
+	var energyAdded, energyUsed, cost sql.NullFloat64
+	var startLevel, endLevel sql.NullInt64
+	err := row.Scan(&c.ID, &c.CarID, &c.Start, &end, &energyAdded, &energyUsed, &startLevel, &endLevel, &cost, &c.Geofence)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read charging process: %w", err)
+	}
+	c.Start = c.Start.UTC()
+	if end.Valid {
+		c.End = end.Time.UTC()
+	}
+	c.ChargeEnergyAdded = energyAdded.Float64
+	c.ChargeEnergyUsed = energyUsed.Float64
+	c.StartBatteryLevel = int(startLevel.Int64)
+	c.EndBatteryLevel = int(endLevel.Int64)
+	if cost.Valid {
+		c.Cost = &cost.Float64
+	}
+	return &c, nil
+}
@@ -0,0 +1,51 @@
+package teslamatedb_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/ekz-tesla/teslamatedb"
+)
+
+func TestDB_ChargingProcesses(t *testing.T) {
+	db := fixtureDB(t)
+	zurich, err := time.LoadLocation("Europe/Zurich")
+	require.NoError(t, err)
+
+	charges, err := db.ChargingProcesses(context.Background(), 0,
+		time.Date(2026, 2, 28, 0, 0, 0, 0, zurich), time.Date(2026, 3, 2, 0, 0, 0, 0, zurich))
+	require.NoError(t, err)
+	require.Len(t, charges, 2)
+	assert.Equal(t, time.Date(2026, 2, 28, 22, 0, 0, 0, time.UTC), charges[0].Start)
+	assert.Equal(t, time.Date(2026, 3, 1, 4, 0, 0, 0, time.UTC), charges[0].End)
+	assert.InDelta(t, 30.5, charges[0].ChargeEnergyAdded, 1e-9)
+	assert.InDelta(t, 33.1, charges[0].ChargeEnergyUsed, 1e-9)
+	assert.Equal(t, 75, charges[0].EndBatteryLevel)
+	require.NotNil(t, charges[0].Cost)
+	assert.InDelta(t, 6.1, *charges[0].Cost, 1e-9)
+	assert.Equal(t, "Home", charges[0].Geofence)
+	assert.True(t, charges[1].Ongoing())
+	assert.Nil(t, charges[1].Cost)
+
+	// The bounds are converted to UTC, the time zone TeslaMate stores the dates in
+	charges, err = db.ChargingProcesses(context.Background(), 1,
+		time.Date(2026, 2, 28, 23, 0, 0, 0, zurich), time.Date(2026, 3, 1, 19, 0, 0, 0, zurich))
+	require.NoError(t, err)
+	require.Len(t, charges, 1)
+	assert.Equal(t, 1, charges[0].ID)
+
+	charges, err = db.ChargingProcesses(context.Background(), 2, time.Time{}, time.Now())
+	require.NoError(t, err)
+	assert.Empty(t, charges)
+
+	charge, err := db.ChargingProcess(context.Background(), 2)
+	require.NoError(t, err)
+	assert.Equal(t, 40, charge.StartBatteryLevel)
+
+	_, err = db.ChargingProcess(context.Background(), 42)
+	assert.ErrorIs(t, err, teslamatedb.ErrNotFound)
+}
@@ -0,0 +1,176 @@
+package teslamatedb
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/denysvitali/ekz-tesla/history"
+)
+
+// MatchOptions tunes how EKZ sessions are matched to TeslaMate charging processes
+type MatchOptions struct {
+	// TimeTolerance widens the session on both sides, as the EKZ and TeslaMate clocks and
+	// the moments the charge is seen to start and stop differ
+	TimeTolerance time.Duration
+	// EnergyTolerance is the accepted relative difference between the energy metered by EKZ
+	// and the energy TeslaMate recorded. It covers the charging losses when TeslaMate only
+	// knows the energy added to the battery.
+	EnergyTolerance float64
+}
+
+// DefaultMatchOptions are the options used when none are given
+var DefaultMatchOptions = MatchOptions{
+	TimeTolerance:   15 * time.Minute,
+	EnergyTolerance: 0.25,
+}
+
+// CostMatch is an EKZ session and the charging processes TeslaMate recorded during it.
+// TeslaMate records several processes when the car paused charging.
+type CostMatch struct {
+	Session history.Session
+	Charges []ChargingProcess
+	// Costs are the costs of Charges in CHF: the cost of the session split by energy
+	Costs []float64
+	// Reason tells why the session wasn't matched, empty if it was
+	Reason string
+}
+
+// Matched tells whether charging processes were found for the session
+func (m CostMatch) Matched() bool {
+	return m.Reason == ""
+}
+
+// CostUpdate is a cost of a charging process that differs from the cost of its EKZ session
+type CostUpdate struct {
+	Charge ChargingProcess
+	// Cost is the cost in CHF to write
+	Cost float64
+}
+
+// Updates returns the charging processes whose cost differs from the one of the session,
+// none if the session wasn't matched
+func (m CostMatch) Updates() []CostUpdate {
+	if !m.Matched() {
+		return nil
+	}
+	var updates []CostUpdate
+	for i, charge := range m.Charges {
+		if charge.Cost == nil || roundCents(*charge.Cost) != m.Costs[i] {
+			updates = append(updates, CostUpdate{Charge: charge, Cost: m.Costs[i]})
+		}
+	}
+	return updates
+}
+
+// MatchSessions matches the EKZ sessions to the finished charging processes that overlap
+// them in time. Each process is attributed to the session it overlaps most, and a session
+// only matches when the energy of its processes agrees with the energy metered by EKZ.
+// Sessions and processes of different cars, when both are known, never match.
+func MatchSessions(sessions []history.Session, charges []ChargingProcess, opts MatchOptions) []CostMatch {
+	matches := make([]CostMatch, len(sessions))
+	for i, session := range sessions {
+		matches[i].Session = session
+	}
+
+	for _, charge := range charges {
+		if charge.Ongoing() {
+			continue
+		}
+		best, bestOverlap := -1, time.Duration(0)
+		for i, session := range sessions {
+			if session.CarID != 0 && charge.CarID != 0 && session.CarID != charge.CarID {
+				continue
+			}
+			overlap := overlap(session.Start.Add(-opts.TimeTolerance), session.Stop.Add(opts.TimeTolerance), charge.Start, charge.End)
+			if overlap > bestOverlap {
+				best, bestOverlap = i, overlap
+			}
+		}
+		if best >= 0 {
+			matches[best].Charges = append(matches[best].Charges, charge)
+		}
+	}
+
+	for i := range matches {
+		matches[i].split(opts.EnergyTolerance)
+	}
+	return matches
+}
+
+// split checks the energy of the charges against the session and splits its cost
+func (m *CostMatch) split(tolerance float64) {
+	if len(m.Charges) == 0 {
+		m.Reason = "no TeslaMate charge during the session"
+		return
+	}
+	energies := make([]float64, len(m.Charges))
+	var total float64
+	for i, charge := range m.Charges {
+		energies[i] = charge.energy()
+		total += energies[i]
+	}
+	if math.Abs(total-m.Session.Energy) > tolerance*m.Session.Energy {
+		m.Reason = fmt.Sprintf("TeslaMate recorded %.2f kWh, EKZ %.2f kWh", total, m.Session.Energy)
+		return
+	}
+
+	cost := roundCents(m.Session.Cost)
+	m.Costs = make([]float64, len(m.Charges))
+	remaining := cost
+	for i := range m.Charges {
+		if i == len(m.Charges)-1 {
+			// The last charge takes the rounding remainder, so that the costs add up
+			m.Costs[i] = roundCents(remaining)
+			break
+		}
+		share := 1 / float64(len(m.Charges))
+		if total > 0 {
+			share = energies[i] / total
+		}
+		m.Costs[i] = roundCents(cost * share)
+		remaining -= m.Costs[i]
+	}
+}
+
+// energy returns the energy to compare with the EKZ meter: the energy drawn from the
+// charger if TeslaMate knows it, the energy added to the battery otherwise
+func (c ChargingProcess) energy() float64 {
+	if c.ChargeEnergyUsed > 0 {
+		return c.ChargeEnergyUsed
+	}
+	return c.ChargeEnergyAdded
+}
+
+func overlap(start1, end1, start2, end2 time.Time) time.Duration {
+	start, end := start1, end1
+	if start2.After(start) {
+		start = start2
+	}
+	if end2.Before(end) {
+		end = end2
+	}
+	if !end.After(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+// roundCents rounds to the precision of the cost column
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// SetChargingProcessCost sets the cost of the charging process. It fails on databases
+// opened with Open, which are read-only; use OpenWritable.
+func (d *DB) SetChargingProcessCost(ctx context.Context, id int, cost float64) error {
+	result, err := d.db.ExecContext(ctx, `UPDATE charging_processes SET cost = $1 WHERE id = $2`, cost, id)
+	if err != nil {
+		return fmt.Errorf("failed to update the cost of charging process %d: %w", id, err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("charging process %d: %w", id, ErrNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package teslamatedb_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/ekz-tesla/history"
+	"github.com/denysvitali/ekz-tesla/teslamatedb"
+)
+
+func at(hour, minute int) time.Time {
+	return time.Date(2026, 3, 1, hour, minute, 0, 0, time.UTC)
+}
+
+func cost(v float64) *float64 {
+	return &v
+}
+
+func TestMatchSessions(t *testing.T) {
+	sessions := []history.Session{
+		{TransactionID: 1, Start: at(1, 0), Stop: at(5, 0), Energy: 30, Cost: 6.3},
+		{TransactionID: 2, Start: at(10, 0), Stop: at(12, 0), Energy: 20, Cost: 4},
+		{TransactionID: 3, Start: at(14, 0), Stop: at(15, 0), Energy: 10, Cost: 2},
+		{TransactionID: 4, Start: at(20, 0), Stop: at(21, 0), Energy: 5, Cost: 1.5, CarID: 2},
+	}
+	charges := []teslamatedb.ChargingProcess{
+		// The car paused charging: two processes for the first session, the first one
+		// starting a bit before the EKZ session
+		{ID: 10, CarID: 1, Start: at(0, 55), End: at(2, 0), ChargeEnergyAdded: 9, ChargeEnergyUsed: 10},
+		{ID: 11, CarID: 1, Start: at(2, 30), End: at(5, 5), ChargeEnergyAdded: 18, ChargeEnergyUsed: 20, Cost: cost(1)},
+		// Only the energy added to the battery is known, already with the right cost
+		{ID: 12, CarID: 1, Start: at(10, 2), End: at(11, 58), ChargeEnergyAdded: 17.5, Cost: cost(4)},
+		// Far too little energy for the third session
+		{ID: 13, CarID: 1, Start: at(14, 0), End: at(14, 20), ChargeEnergyAdded: 3},
+		// Another car, and an ongoing charge
+		{ID: 14, CarID: 1, Start: at(20, 0), End: at(21, 0), ChargeEnergyAdded: 5},
+		{ID: 15, CarID: 2, Start: at(20, 5)},
+	}
+
+	matches := teslamatedb.MatchSessions(sessions, charges, teslamatedb.DefaultMatchOptions)
+	require.Len(t, matches, 4)
+
+	assert.True(t, matches[0].Matched())
+	require.Len(t, matches[0].Charges, 2)
+	assert.Equal(t, []float64{2.1, 4.2}, matches[0].Costs)
+	updates := matches[0].Updates()
+	require.Len(t, updates, 2)
+	assert.Equal(t, 10, updates[0].Charge.ID)
+	assert.InDelta(t, 2.1, updates[0].Cost, 1e-9)
+	assert.InDelta(t, 4.2, updates[1].Cost, 1e-9)
+
+	assert.True(t, matches[1].Matched())
+	assert.Equal(t, []float64{4}, matches[1].Costs)
+	assert.Empty(t, matches[1].Updates())
+
+	assert.False(t, matches[2].Matched())
+	assert.Equal(t, "TeslaMate recorded 3.00 kWh, EKZ 10.00 kWh", matches[2].Reason)
+	assert.Empty(t, matches[2].Updates())
+
+	assert.False(t, matches[3].Matched())
+	assert.Equal(t, "no TeslaMate charge during the session", matches[3].Reason)
+}
+
+func TestMatchSessions_RoundingRemainder(t *testing.T) {
+	sessions := []history.Session{{Start: at(1, 0), Stop: at(4, 0), Energy: 3, Cost: 1}}
+	charges := []teslamatedb.ChargingProcess{
+		{ID: 1, Start: at(1, 0), End: at(2, 0), ChargeEnergyUsed: 1},
+		{ID: 2, Start: at(2, 0), End: at(3, 0), ChargeEnergyUsed: 1},
+		{ID: 3, Start: at(3, 0), End: at(4, 0), ChargeEnergyUsed: 1},
+	}
+	matches := teslamatedb.MatchSessions(sessions, charges, teslamatedb.DefaultMatchOptions)
+	require.True(t, matches[0].Matched())
+	assert.Equal(t, []float64{0.33, 0.33, 0.34}, matches[0].Costs)
+}
+
+func TestDB_SetChargingProcessCost(t *testing.T) {
+	readOnly := fixtureDB(t)
+	assert.ErrorContains(t, readOnly.SetChargingProcessCost(context.Background(), 1, 5), "read-only transaction")
+
+	db, err := teslamatedb.OpenWritable(fixtureDSN(t))
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	require.NoError(t, db.SetChargingProcessCost(context.Background(), 1, 5.25))
+	charge, err := db.ChargingProcess(context.Background(), 1)
+	require.NoError(t, err)
+	require.NotNil(t, charge.Cost)
+	assert.InDelta(t, 5.25, *charge.Cost, 1e-9)
+
+	assert.ErrorIs(t, db.SetChargingProcessCost(context.Background(), 42, 1), teslamatedb.ErrNotFound)
+}
@@ -0,0 +1,84 @@
+package teslamatedb_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/ekz-tesla/teslamatedb"
+)
+
+// testSchema is the schema the fixtures are loaded into, so that the test database may be shared
+const testSchema = "ekz_tesla_test"
+
+// fixtureDB loads testdata/schema.sql and testdata/fixtures.sql into the Postgres database at
+// TESLAMATE_DB_TEST_DSN and opens it read-only
+func fixtureDB(t *testing.T) *teslamatedb.DB {
+	t.Helper()
+	db, err := teslamatedb.Open(fixtureDSN(t))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+// fixtureDSN loads the fixtures and returns the key=value DSN of the test schema
+func fixtureDSN(t *testing.T) string {
+	t.Helper()
+	dsn := os.Getenv("TESLAMATE_DB_TEST_DSN")
+	if dsn == "" {
+		t.Skip("TESLAMATE_DB_TEST_DSN is not set")
+	}
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		var err error
+		dsn, err = pq.ParseURL(dsn)
+		require.NoError(t, err)
+	}
+	dsn += " search_path=" + testSchema
+
+	admin, err := sql.Open("postgres", dsn)
+	require.NoError(t, err)
+	defer func() { _ = admin.Close() }()
+	_, err = admin.Exec(`DROP SCHEMA IF EXISTS ` + testSchema + ` CASCADE; CREATE SCHEMA ` + testSchema)
+	require.NoError(t, err)
+	for _, file := range []string{"testdata/schema.sql", "testdata/fixtures.sql"} {
+		script, err := os.ReadFile(file)
+		require.NoError(t, err)
+		_, err = admin.Exec(string(script))
+		require.NoError(t, err, file)
+	}
+	return dsn
+}
+
+func TestReadOnlyDSN(t *testing.T) {
+	dsn, err := teslamatedb.ReadOnlyDSN("host=db user=teslamate dbname=teslamate")
+	require.NoError(t, err)
+	assert.Equal(t, "host=db user=teslamate dbname=teslamate default_transaction_read_only=on", dsn)
+
+	dsn, err = teslamatedb.ReadOnlyDSN("postgres://teslamate:secret@db:5432/teslamate?sslmode=disable")
+	require.NoError(t, err)
+	assert.Equal(t, "dbname='teslamate' host='db' password='secret' port='5432' sslmode='disable' user='teslamate'"+
+		" default_transaction_read_only=on", dsn)
+
+	_, err = teslamatedb.ReadOnlyDSN("")
+	assert.ErrorContains(t, err, "DSN is empty")
+	_, err = teslamatedb.ReadOnlyDSN("postgres://db:port/teslamate")
+	assert.ErrorContains(t, err, "invalid TeslaMate database DSN")
+}
+
+func TestReadOnlyDSN_Postgres(t *testing.T) {
+	dsn, err := teslamatedb.ReadOnlyDSN(fixtureDSN(t))
+	require.NoError(t, err)
+	db, err := sql.Open("postgres", dsn)
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	require.NoError(t, db.PingContext(context.Background()))
+	_, err = db.Exec(`DELETE FROM cars`)
+	assert.ErrorContains(t, err, "read-only transaction")
+}
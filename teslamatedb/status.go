@@ -0,0 +1,114 @@
+package teslamatedb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Position is a position of the car recorded by TeslaMate, with the data logged along
+type Position struct {
+	Date         time.Time
+	Latitude     float64
+	Longitude    float64
+	BatteryLevel int
+	// EstBatteryRange is the estimated range in km
+	EstBatteryRange float64
+	InsideTemp      float64
+	OutsideTemp     float64
+	IsClimateOn     bool
+}
+
+// State is the state of the car recorded by TeslaMate: online, asleep or offline
+type State struct {
+	State string
+	Since time.Time
+}
+
+// CarState is the current state of a car as far as the database tells
+type CarState struct {
+	// Position is the last recorded position
+	Position Position
+	// State is nil if TeslaMate didn't record any state yet
+	State *State
+	// Driving tells whether a drive is in progress
+	Driving bool
+	// Charging is the charging process in progress, nil if the car isn't charging
+	Charging *ChargingProcess
+	// Geofence is the name of the TeslaMate geofence containing the last position
+	Geofence string
+}
+
+// CarState returns the current state of the car. It returns ErrNotFound if TeslaMate
+// didn't record any position of the car yet.
+func (d *DB) CarState(ctx context.Context, carID int) (*CarState, error) {
+	var state CarState
+	var err error
+	if state.Position, err = d.lastPosition(ctx, carID); err != nil {
+		return nil, err
+	}
+	if state.State, err = d.lastState(ctx, carID); err != nil {
+		return nil, err
+	}
+	if err := d.db.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM drives WHERE car_id = $1 AND end_date IS NULL)`, carID).Scan(&state.Driving); err != nil {
+		return nil, fmt.Errorf("failed to query drives: %w", err)
+	}
+	if state.Charging, err = d.openChargingProcess(ctx, carID); err != nil {
+		return nil, err
+	}
+
+	geofences, err := d.Geofences(ctx)
+	if err != nil {
+		return nil, err
+	}
+	state.Geofence = ContainingGeofence(geofences, state.Position.Latitude, state.Position.Longitude)
+	return &state, nil
+}
+
+func (d *DB) lastPosition(ctx context.Context, carID int) (Position, error) {
+	var p Position
+	var batteryLevel sql.NullInt64
+	var estRange, insideTemp, outsideTemp sql.NullFloat64
+	var climateOn sql.NullBool
+	err := d.db.QueryRowContext(ctx, `
+		SELECT date, latitude, longitude, battery_level, est_battery_range_km, inside_temp, outside_temp, is_climate_on
+		FROM positions
+		WHERE car_id = $1
+		ORDER BY date DESC
+		LIMIT 1`, carID).
+		Scan(&p.Date, &p.Latitude, &p.Longitude, &batteryLevel, &estRange, &insideTemp, &outsideTemp, &climateOn)
+	if errors.Is(err, sql.ErrNoRows) {
+		return p, fmt.Errorf("position of car %d: %w", carID, ErrNotFound)
+	}
+	if err != nil {
+		return p, fmt.Errorf("failed to query positions: %w", err)
+	}
+	p.Date = p.Date.UTC()
+	p.BatteryLevel = int(batteryLevel.Int64)
+	p.EstBatteryRange = estRange.Float64
+	p.InsideTemp = insideTemp.Float64
+	p.OutsideTemp = outsideTemp.Float64
+	p.IsClimateOn = climateOn.Bool
+	return p, nil
+}
+
+func (d *DB) lastState(ctx context.Context, carID int) (*State, error) {
+	var s State
+	err := d.db.QueryRowContext(ctx, `
+		SELECT state, start_date
+		FROM states
+		WHERE car_id = $1
+		ORDER BY start_date DESC
+		LIMIT 1`, carID).Scan(&s.State, &s.Since)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query states: %w", err)
+	}
+	s.Since = s.Since.UTC()
+	return &s, nil
+}
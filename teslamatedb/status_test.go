@@ -0,0 +1,42 @@
+package teslamatedb_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/ekz-tesla/teslamatedb"
+)
+
+func TestDB_CarState(t *testing.T) {
+	db := fixtureDB(t)
+
+	charging, err := db.CarState(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 3, 1, 20, 0, 0, 0, time.UTC), charging.Position.Date)
+	assert.Equal(t, 45, charging.Position.BatteryLevel)
+	assert.InDelta(t, 200, charging.Position.EstBatteryRange, 1e-9)
+	require.NotNil(t, charging.State)
+	assert.Equal(t, "online", charging.State.State)
+	assert.Equal(t, time.Date(2026, 3, 1, 17, 55, 0, 0, time.UTC), charging.State.Since)
+	assert.False(t, charging.Driving)
+	assert.Equal(t, "Home", charging.Geofence)
+	require.NotNil(t, charging.Charging)
+	assert.Equal(t, 2, charging.Charging.ID)
+	assert.True(t, charging.Charging.Ongoing())
+	assert.Equal(t, 46, charging.Charging.EndBatteryLevel)
+	assert.InDelta(t, 4.2, charging.Charging.ChargeEnergyAdded, 1e-9)
+
+	driving, err := db.CarState(context.Background(), 2)
+	require.NoError(t, err)
+	assert.True(t, driving.Driving)
+	assert.True(t, driving.Position.IsClimateOn)
+	assert.Nil(t, driving.Charging)
+	assert.Empty(t, driving.Geofence)
+
+	_, err = db.CarState(context.Background(), 3)
+	assert.ErrorIs(t, err, teslamatedb.ErrNotFound)
+}
@@ -0,0 +1,30 @@
+-- Two cars: car 1 is charging at home, car 2 is driving
+INSERT INTO cars (id, eid, vid, model, vin, name, trim_badging) VALUES
+    (1, 101, 201, '3', '5YJ3E7EA1KF000001', 'Blue', 'P74D'),
+    (2, 102, 202, 'Y', '7SAYGDEE1PF000002', 'White', '74D');
+
+INSERT INTO geofences (id, name, latitude, longitude, radius) VALUES
+    (1, 'Zurich', 47.376900, 8.541700, 2000),
+    (2, 'Home', 47.376900, 8.541700, 50);
+
+INSERT INTO positions (id, date, latitude, longitude, battery_level, est_battery_range_km, inside_temp, outside_temp, is_climate_on, car_id) VALUES
+    (1, '2026-03-01 18:00:00', 47.376900, 8.541700, 40, 180.50, 19.5, 7.0, false, 1),
+    (2, '2026-03-01 20:00:00', 47.377000, 8.541700, 45, 200.00, 18.0, 6.5, false, 1),
+    (3, '2026-03-01 20:30:00', 47.500000, 8.700000, 70, 320.00, 21.0, 6.0, true, 2);
+
+INSERT INTO states (state, start_date, end_date, car_id) VALUES
+    ('asleep', '2026-03-01 12:00:00', '2026-03-01 17:55:00', 1),
+    ('online', '2026-03-01 17:55:00', NULL, 1),
+    ('online', '2026-03-01 19:00:00', NULL, 2);
+
+INSERT INTO drives (start_date, end_date, car_id) VALUES
+    ('2026-03-01 17:00:00', '2026-03-01 17:55:00', 1),
+    ('2026-03-01 20:10:00', NULL, 2);
+
+INSERT INTO charging_processes (id, start_date, end_date, charge_energy_added, charge_energy_used, start_battery_level, end_battery_level, cost, car_id, position_id, geofence_id) VALUES
+    (1, '2026-02-28 22:00:00', '2026-03-01 04:00:00', 30.50, 33.10, 30, 75, 6.10, 1, 1, 2),
+    (2, '2026-03-01 18:00:00', NULL, NULL, NULL, 40, NULL, NULL, 1, 1, 2);
+
+INSERT INTO charges (date, battery_level, charge_energy_added, charger_power, charging_process_id) VALUES
+    ('2026-03-01 19:00:00', 43, 2.10, 11, 2),
+    ('2026-03-01 20:00:00', 46, 4.20, 11, 2);
@@ -0,0 +1,119 @@
+-- Subset of the TeslaMate schema read by teslamatedb
+CREATE TYPE states_status AS ENUM ('online', 'offline', 'asleep');
+
+CREATE TABLE cars (
+    id           smallserial PRIMARY KEY,
+    eid          bigint NOT NULL,
+    vid          bigint NOT NULL,
+    model        character varying(255),
+    efficiency   double precision,
+    inserted_at  timestamp NOT NULL DEFAULT now(),
+    updated_at   timestamp NOT NULL DEFAULT now(),
+    vin          text,
+    name         text,
+    trim_badging text
+);
+
+CREATE TABLE geofences (
+    id          serial PRIMARY KEY,
+    name        character varying(255) NOT NULL,
+    latitude    numeric(8,6) NOT NULL,
+    longitude   numeric(9,6) NOT NULL,
+    radius      smallint NOT NULL DEFAULT 25,
+    inserted_at timestamp NOT NULL DEFAULT now(),
+    updated_at  timestamp NOT NULL DEFAULT now()
+);
+
+CREATE TABLE positions (
+    id                   serial PRIMARY KEY,
+    date                 timestamp NOT NULL,
+    latitude             numeric(8,6) NOT NULL,
+    longitude            numeric(9,6) NOT NULL,
+    speed                smallint,
+    power                smallint,
+    odometer             double precision,
+    ideal_battery_range_km numeric(6,2),
+    battery_level        smallint,
+    outside_temp         numeric(4,1),
+    elevation            smallint,
+    fan_status           integer,
+    driver_temp_setting  numeric(4,1),
+    passenger_temp_setting numeric(4,1),
+    is_climate_on        boolean,
+    is_rear_defroster_on boolean,
+    is_front_defroster_on boolean,
+    car_id               smallint NOT NULL REFERENCES cars(id),
+    drive_id             integer,
+    inside_temp          numeric(4,1),
+    battery_heater       boolean,
+    battery_heater_on    boolean,
+    battery_heater_no_power boolean,
+    est_battery_range_km numeric(6,2),
+    rated_battery_range_km numeric(6,2),
+    usable_battery_level smallint,
+    tpms_pressure_fl     numeric(4,1),
+    tpms_pressure_fr     numeric(4,1),
+    tpms_pressure_rl     numeric(4,1),
+    tpms_pressure_rr     numeric(4,1)
+);
+
+CREATE TABLE states (
+    id         serial PRIMARY KEY,
+    state      states_status NOT NULL,
+    start_date timestamp NOT NULL,
+    end_date   timestamp,
+    car_id     smallint NOT NULL REFERENCES cars(id)
+);
+
+CREATE TABLE drives (
+    id         serial PRIMARY KEY,
+    start_date timestamp NOT NULL,
+    end_date   timestamp,
+    car_id     smallint NOT NULL REFERENCES cars(id)
+);
+
+CREATE TABLE charging_processes (
+    id                    serial PRIMARY KEY,
+    start_date            timestamp NOT NULL,
+    end_date              timestamp,
+    charge_energy_added   numeric(8,2),
+    start_ideal_range_km  numeric(6,2),
+    end_ideal_range_km    numeric(6,2),
+    start_battery_level   smallint,
+    end_battery_level     smallint,
+    duration_min          smallint,
+    outside_temp_avg      numeric(4,1),
+    car_id                smallint NOT NULL REFERENCES cars(id),
+    position_id           integer NOT NULL REFERENCES positions(id),
+    address_id            integer,
+    start_rated_range_km  numeric(6,2),
+    end_rated_range_km    numeric(6,2),
+    geofence_id           integer REFERENCES geofences(id),
+    charge_energy_used    numeric(8,2),
+    cost                  numeric(6,2)
+);
+
+CREATE TABLE charges (
+    id                  serial PRIMARY KEY,
+    date                timestamp NOT NULL,
+    battery_heater_on   boolean,
+    battery_level       smallint,
+    charge_energy_added numeric(8,2) NOT NULL,
+    charger_actual_current smallint,
+    charger_phases      smallint,
+    charger_pilot_current smallint,
+    charger_power       smallint NOT NULL,
+    charger_voltage     smallint,
+    fast_charger_present boolean,
+    conn_charge_cable   character varying(255),
+    fast_charger_brand  character varying(255),
+    fast_charger_type   character varying(255),
+    ideal_battery_range_km numeric(6,2),
+    not_enough_power_to_heat boolean,
+    outside_temp        numeric(4,1),
+    charging_process_id integer NOT NULL REFERENCES charging_processes(id),
+    battery_heater      boolean,
+    battery_heater_no_power boolean,
+    rated_battery_range_km numeric(6,2),
+    usable_battery_level smallint
+);
@@ -0,0 +1,49 @@
+package vehicle
+
+import "math"
+
+const (
+	// minChargeEfficiency and maxChargeEfficiency bound the ratio between the energy added to
+	// the battery and the energy metered by the station, for a car to be the one charging
+	minChargeEfficiency = 0.6
+	maxChargeEfficiency = 1.1
+	// typicalChargeEfficiency is the ratio expected at home, with the usual charging losses
+	typicalChargeEfficiency = 0.9
+)
+
+// EnergyAddedSince returns how much charge_energy_added grew since start. The car resets the
+// value when a new charge begins, in which case the whole current value was added.
+func EnergyAddedSince(start, now float64) float64 {
+	if now < start {
+		return now
+	}
+	return now - start
+}
+
+// CorrelateEnergy returns the car that charged the energy metered by the station, given the
+// charge_energy_added of the cars, keyed by car ID, when the session started and now. Only
+// the car whose battery received the metered energy minus the charging losses matches. Cars
+// without a start value are skipped, as their added energy is unknown. It returns false if no
+// car or several cars match.
+func CorrelateEnergy(start, now map[int]float64, charged float64) (int, bool) {
+	if charged <= 0 {
+		return 0, false
+	}
+	match, matches := 0, 0
+	bestDiff := math.Inf(1)
+	for id, current := range now {
+		baseline, ok := start[id]
+		if !ok {
+			continue
+		}
+		ratio := EnergyAddedSince(baseline, current) / charged
+		if ratio < minChargeEfficiency || ratio > maxChargeEfficiency {
+			continue
+		}
+		matches++
+		if diff := math.Abs(ratio - typicalChargeEfficiency); diff < bestDiff {
+			match, bestDiff = id, diff
+		}
+	}
+	return match, matches == 1
+}
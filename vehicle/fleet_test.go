@@ -0,0 +1,51 @@
+package vehicle_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/denysvitali/ekz-tesla/vehicle"
+)
+
+func TestEnergyAddedSince(t *testing.T) {
+	assert.InDelta(t, 2.5, vehicle.EnergyAddedSince(10, 12.5), 1e-9)
+	// A new charge began and reset the counter
+	assert.InDelta(t, 3, vehicle.EnergyAddedSince(10, 3), 1e-9)
+}
+
+func TestCorrelateEnergy(t *testing.T) {
+	start := map[int]float64{1: 12.4, 2: 0}
+
+	// Car 2 started a new charge, car 1 didn't charge since its last session
+	id, ok := vehicle.CorrelateEnergy(start, map[int]float64{1: 12.4, 2: 4.5}, 5)
+	assert.True(t, ok)
+	assert.Equal(t, 2, id)
+
+	// Car 1 reset its counter and charged
+	id, ok = vehicle.CorrelateEnergy(start, map[int]float64{1: 4.4, 2: 0}, 5)
+	assert.True(t, ok)
+	assert.Equal(t, 1, id)
+
+	// Both cars charged, e.g. one of them on another charger
+	_, ok = vehicle.CorrelateEnergy(start, map[int]float64{1: 16.9, 2: 4.5}, 5)
+	assert.False(t, ok)
+
+	// Nothing charged yet, or no car received the energy
+	_, ok = vehicle.CorrelateEnergy(start, map[int]float64{1: 12.4, 2: 0}, 0)
+	assert.False(t, ok)
+	_, ok = vehicle.CorrelateEnergy(start, map[int]float64{1: 12.4, 2: 0.5}, 5)
+	assert.False(t, ok)
+}
+
+func TestCorrelateEnergy_MissingBaseline(t *testing.T) {
+	// The status of car 2 couldn't be read when the session started: the 4.6 kWh of its
+	// earlier charge must not count as added in this session
+	start := map[int]float64{1: 12.4}
+	id, ok := vehicle.CorrelateEnergy(start, map[int]float64{1: 16.9, 2: 4.6}, 5)
+	assert.True(t, ok)
+	assert.Equal(t, 1, id)
+
+	_, ok = vehicle.CorrelateEnergy(start, map[int]float64{1: 12.4, 2: 4.5}, 5)
+	assert.False(t, ok, "car 2 can't be identified without its baseline")
+}
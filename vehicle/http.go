@@ -0,0 +1,264 @@
+package vehicle
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultHTTPTimeout bounds a request to the HTTP endpoint
+const defaultHTTPTimeout = 30 * time.Second
+
+// HTTPConfig configures a generic HTTP endpoint returning the car data as JSON
+type HTTPConfig struct {
+	URL string `yaml:"url"`
+	// Headers are sent with every request, e.g. Authorization
+	Headers map[string]string `yaml:"headers,omitempty"`
+	Timeout time.Duration     `yaml:"timeout,omitempty"`
+	Paths   HTTPPaths         `yaml:"paths"`
+}
+
+// HTTPPaths locate the values in the JSON response. A path is made of object keys and
+// array indexes separated by dots, e.g. data.vehicles.0.soc. Empty paths are not read.
+type HTTPPaths struct {
+	SoC           string `yaml:"soc"`
+	PluggedIn     string `yaml:"plugged_in"`
+	ChargeLimit   string `yaml:"charge_limit,omitempty"`
+	ChargingState string `yaml:"charging_state,omitempty"`
+	State         string `yaml:"state,omitempty"`
+	Latitude      string `yaml:"latitude,omitempty"`
+	Longitude     string `yaml:"longitude,omitempty"`
+	Geofence      string `yaml:"geofence,omitempty"`
+	// Updated is when the data was measured, as RFC 3339 time or Unix seconds
+	Updated string `yaml:"updated,omitempty"`
+}
+
+// Validate checks that the URL and the required paths are set
+func (c HTTPConfig) Validate() error {
+	if c.URL == "" {
+		return fmt.Errorf("http: url is required")
+	}
+	if c.Paths.SoC == "" || c.Paths.PluggedIn == "" {
+		return fmt.Errorf("http: paths.soc and paths.plugged_in are required")
+	}
+	if (c.Paths.Latitude == "") != (c.Paths.Longitude == "") {
+		return fmt.Errorf("http: paths.latitude and paths.longitude must be set together")
+	}
+	return nil
+}
+
+// HTTP reads the car data from a JSON endpoint
+type HTTP struct {
+	config HTTPConfig
+	client *http.Client
+}
+
+// NewHTTP creates a provider for the endpoint
+func NewHTTP(config HTTPConfig) (*HTTP, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	timeout := config.Timeout
+	if timeout == 0 {
+		timeout = defaultHTTPTimeout
+	}
+	return &HTTP{config: config, client: &http.Client{Timeout: timeout}}, nil
+}
+
+// Status requests the endpoint and reads the values at the configured paths
+func (p *HTTP) Status(ctx context.Context) (*Status, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.config.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	for key, value := range p.config.Headers {
+		req.Header.Set(key, value)
+	}
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = res.Body.Close() }()
+	if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return nil, fmt.Errorf("unexpected status %s from %s: %s", res.Status, p.config.URL, body)
+	}
+
+	var doc any
+	if err := json.NewDecoder(res.Body).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to decode response from %s: %w", p.config.URL, err)
+	}
+	return p.parse(doc)
+}
+
+// parse reads the status from the decoded JSON document
+func (p *HTTP) parse(doc any) (*Status, error) {
+	paths := p.config.Paths
+	r := reader{doc: doc}
+	r.required(paths.SoC)
+	r.required(paths.PluggedIn)
+	status := Status{
+		SoC:           r.int(paths.SoC),
+		PluggedIn:     r.bool(paths.PluggedIn),
+		ChargeLimit:   r.int(paths.ChargeLimit),
+		ChargingState: ChargingState(r.string(paths.ChargingState)),
+		State:         r.string(paths.State),
+		Geofence:      r.string(paths.Geofence),
+		Updated:       r.time(paths.Updated),
+	}
+	// A null position is unknown, not 0°N 0°E
+	if paths.Latitude != "" && r.present(paths.Latitude) && r.present(paths.Longitude) {
+		status.Location = &Location{Latitude: r.float(paths.Latitude), Longitude: r.float(paths.Longitude)}
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return &status, nil
+}
+
+// reader reads typed values from a JSON document, keeping the first error
+type reader struct {
+	doc any
+	err error
+}
+
+// lookup returns the value at path, nil if path is empty
+func (r *reader) lookup(path string) any {
+	if path == "" || r.err != nil {
+		return nil
+	}
+	value := r.doc
+	for _, key := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]any:
+			var ok bool
+			if value, ok = v[key]; !ok {
+				r.err = fmt.Errorf("path %s: key %q not found", path, key)
+				return nil
+			}
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				r.err = fmt.Errorf("path %s: invalid index %q into an array of %d", path, key, len(v))
+				return nil
+			}
+			value = v[i]
+		default:
+			r.err = fmt.Errorf("path %s: can't look up %q in %T", path, key, value)
+			return nil
+		}
+	}
+	return value
+}
+
+// present tells whether the value at path is neither null nor empty
+func (r *reader) present(path string) bool {
+	switch v := r.lookup(path).(type) {
+	case nil:
+		return false
+	case string:
+		return strings.TrimSpace(v) != ""
+	default:
+		return true
+	}
+}
+
+// required fails if the value at path is null or empty, which the typed readers take as unset
+func (r *reader) required(path string) {
+	switch v := r.lookup(path).(type) {
+	case nil:
+		if path != "" && r.err == nil {
+			r.err = fmt.Errorf("path %s: null", path)
+		}
+	case string:
+		if strings.TrimSpace(v) == "" && r.err == nil {
+			r.err = fmt.Errorf("path %s: empty", path)
+		}
+	}
+}
+
+func (r *reader) float(path string) float64 {
+	switch v := r.lookup(path).(type) {
+	case nil:
+		return 0
+	case float64:
+		return v
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			r.fail(path, v, "a number")
+		}
+		return f
+	default:
+		r.fail(path, v, "a number")
+		return 0
+	}
+}
+
+func (r *reader) int(path string) int {
+	return int(math.Round(r.float(path)))
+}
+
+func (r *reader) bool(path string) bool {
+	switch v := r.lookup(path).(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	case string:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			r.fail(path, v, "a boolean")
+		}
+		return b
+	default:
+		r.fail(path, v, "a boolean")
+		return false
+	}
+}
+
+func (r *reader) string(path string) string {
+	switch v := r.lookup(path).(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		r.fail(path, v, "a string")
+		return ""
+	}
+}
+
+func (r *reader) time(path string) time.Time {
+	switch v := r.lookup(path).(type) {
+	case nil:
+		return time.Time{}
+	case float64:
+		return time.Unix(int64(v), 0)
+	case string:
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			r.fail(path, v, "an RFC 3339 time")
+		}
+		return t
+	default:
+		r.fail(path, v, "a time")
+		return time.Time{}
+	}
+}
+
+func (r *reader) fail(path string, value any, want string) {
+	if r.err == nil {
+		r.err = fmt.Errorf("path %s: %v is not %s", path, value, want)
+	}
+}
@@ -0,0 +1,132 @@
+package vehicle_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/ekz-tesla/vehicle"
+)
+
+// jsonServer serves the file at any path, checking the Authorization header
+func jsonServer(t *testing.T, file string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		http.ServeFile(w, r, file)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHTTP_Status(t *testing.T) {
+	server := jsonServer(t, "testdata/http.json")
+	p, err := vehicle.NewHTTP(vehicle.HTTPConfig{
+		URL:     server.URL,
+		Headers: map[string]string{"Authorization": "Bearer secret"},
+		Paths: vehicle.HTTPPaths{
+			SoC:           "vehicles.0.charge.soc",
+			PluggedIn:     "vehicles.0.charge.plugged",
+			ChargeLimit:   "vehicles.0.charge.limit",
+			ChargingState: "vehicles.0.charge.state",
+			Latitude:      "vehicles.0.position.lat",
+			Longitude:     "vehicles.0.position.lon",
+			Updated:       "vehicles.0.updated",
+		},
+	})
+	require.NoError(t, err)
+
+	status, err := p.Status(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 55, status.SoC)
+	assert.Equal(t, 80, status.ChargeLimit)
+	assert.True(t, status.PluggedIn)
+	assert.Equal(t, vehicle.ChargingStateStopped, status.CurrentChargingState())
+	require.NotNil(t, status.Location)
+	assert.Equal(t, 47.3769, status.Location.Latitude)
+	assert.Equal(t, 8.5417, status.Location.Longitude)
+	assert.Equal(t, time.Date(2025, 1, 13, 21, 55, 0, 0, time.UTC), status.Updated)
+	assert.Empty(t, status.Geofence)
+}
+
+func TestHTTP_StatusNullLocation(t *testing.T) {
+	server := jsonServer(t, "testdata/http.json")
+	p, err := vehicle.NewHTTP(vehicle.HTTPConfig{
+		URL:     server.URL,
+		Headers: map[string]string{"Authorization": "Bearer secret"},
+		Paths: vehicle.HTTPPaths{
+			SoC:       "vehicles.0.charge.soc",
+			PluggedIn: "vehicles.0.charge.plugged",
+			Latitude:  "vehicles.0.last_position.lat",
+			Longitude: "vehicles.0.last_position.lon",
+		},
+	})
+	require.NoError(t, err)
+
+	status, err := p.Status(context.Background())
+	require.NoError(t, err)
+	assert.Nil(t, status.Location, "the location of the car is unknown")
+}
+
+func TestHTTP_StatusErrors(t *testing.T) {
+	server := jsonServer(t, "testdata/http.json")
+	tests := []struct {
+		name  string
+		paths vehicle.HTTPPaths
+		err   string
+	}{
+		{
+			name:  "missing key",
+			paths: vehicle.HTTPPaths{SoC: "vehicles.0.charge.battery", PluggedIn: "vehicles.0.charge.plugged"},
+			err:   `key "battery" not found`,
+		},
+		{
+			name:  "index out of range",
+			paths: vehicle.HTTPPaths{SoC: "vehicles.1.charge.soc", PluggedIn: "vehicles.0.charge.plugged"},
+			err:   `invalid index "1" into an array of 1`,
+		},
+		{
+			name:  "wrong type",
+			paths: vehicle.HTTPPaths{SoC: "vehicles.0.name", PluggedIn: "vehicles.0.charge.plugged"},
+			err:   "Snowflake is not a number",
+		},
+		{
+			name:  "null value",
+			paths: vehicle.HTTPPaths{SoC: "vehicles.0.charge.usable_soc", PluggedIn: "vehicles.0.charge.plugged"},
+			err:   "path vehicles.0.charge.usable_soc: null",
+		},
+		{
+			name:  "empty value",
+			paths: vehicle.HTTPPaths{SoC: "vehicles.0.charge.soc", PluggedIn: "vehicles.0.charge.cable"},
+			err:   "path vehicles.0.charge.cable: empty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := vehicle.NewHTTP(vehicle.HTTPConfig{
+				URL:     server.URL,
+				Headers: map[string]string{"Authorization": "Bearer secret"},
+				Paths:   tt.paths,
+			})
+			require.NoError(t, err)
+			_, err = p.Status(context.Background())
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func TestHTTPConfig_Validate(t *testing.T) {
+	assert.ErrorContains(t, vehicle.HTTPConfig{}.Validate(), "url is required")
+	assert.ErrorContains(t, vehicle.HTTPConfig{URL: "http://car", Paths: vehicle.HTTPPaths{SoC: "soc"}}.Validate(), "plugged_in")
+	assert.ErrorContains(t, vehicle.HTTPConfig{
+		URL:   "http://car",
+		Paths: vehicle.HTTPPaths{SoC: "soc", PluggedIn: "plugged", Latitude: "lat"},
+	}.Validate(), "set together")
+}
@@ -0,0 +1,258 @@
+package vehicle
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultTopicPrefix is the prefix of the topics TeslaMate publishes to
+	DefaultTopicPrefix = "teslamate"
+
+	// maxReconnectInterval bounds the wait between reconnection attempts after the connection was lost
+	maxReconnectInterval = time.Minute
+)
+
+var log = logrus.StandardLogger()
+
+// ErrDisconnected is returned while the connection to the broker is down, as the last
+// received data may be outdated
+var ErrDisconnected = errors.New("disconnected from the MQTT broker")
+
+// relevantTopics are the values whose changes may change the autostart decision
+var relevantTopics = map[string]bool{
+	"plugged_in":     true,
+	"battery_level":  true,
+	"charging_state": true,
+	"latitude":       true,
+	"longitude":      true,
+	"location":       true,
+	"geofence":       true,
+	"state":          true,
+}
+
+// MQTTConfig configures the connection to the MQTT broker TeslaMate publishes to
+type MQTTConfig struct {
+	// Broker is the address of the broker, e.g. tcp://mosquitto:1883
+	Broker   string `yaml:"broker"`
+	Username string `yaml:"username,omitempty"`
+	Password string `yaml:"password,omitempty"`
+	// ClientID defaults to ekz-tesla-<hostname>-<car ID>
+	ClientID string `yaml:"client_id,omitempty"`
+	// TopicPrefix is the MQTT_NAMESPACE of TeslaMate prepended by teslamate, by default teslamate
+	TopicPrefix string `yaml:"topic_prefix,omitempty"`
+}
+
+// Validate checks that the broker is set
+func (c MQTTConfig) Validate() error {
+	if c.Broker == "" {
+		return fmt.Errorf("mqtt: broker is required")
+	}
+	return nil
+}
+
+// MQTT keeps the latest car data published by TeslaMate to teslamate/cars/<id>/... and
+// notifies the changes of the values that matter to autostart
+type MQTT struct {
+	config MQTTConfig
+	carID  int
+	client mqtt.Client
+
+	mu       sync.Mutex
+	status   Status
+	received bool
+	// values holds the last payload per topic, to tell changes from repeated messages
+	values map[string]string
+	// latitude and longitude are kept apart until both are known
+	latitude, longitude *float64
+	onChange            []func(Change)
+}
+
+// NewMQTT creates a provider for the car with the given TeslaMate ID, see Connect
+func NewMQTT(config MQTTConfig, carID int) (*MQTT, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if carID <= 0 {
+		return nil, fmt.Errorf("mqtt: the TeslaMate car ID is required")
+	}
+	if config.TopicPrefix == "" {
+		config.TopicPrefix = DefaultTopicPrefix
+	}
+	if config.ClientID == "" {
+		hostname, _ := os.Hostname()
+		config.ClientID = fmt.Sprintf("ekz-tesla-%s-%d", hostname, carID)
+	}
+
+	p := &MQTT{config: config, carID: carID, values: make(map[string]string)}
+	opts := mqtt.NewClientOptions().
+		AddBroker(config.Broker).
+		SetClientID(config.ClientID).
+		SetUsername(config.Username).
+		SetPassword(config.Password).
+		SetAutoReconnect(true).
+		SetMaxReconnectInterval(maxReconnectInterval).
+		SetOnConnectHandler(p.subscribe).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			log.Warnf("Lost the connection to the MQTT broker %s, reconnecting: %v", config.Broker, err)
+		}).
+		SetReconnectingHandler(func(_ mqtt.Client, _ *mqtt.ClientOptions) {
+			log.Debugf("Reconnecting to the MQTT broker %s", config.Broker)
+		})
+	p.client = mqtt.NewClient(opts)
+	return p, nil
+}
+
+// subscribe subscribes to the topics of the car on every connection, as the subscription
+// is lost with the session. TeslaMate retains its messages, so the current values are
+// received again after a reconnect.
+func (p *MQTT) subscribe(client mqtt.Client) {
+	log.Infof("Connected to the MQTT broker %s", p.config.Broker)
+	token := client.Subscribe(p.topic("#"), 0, func(_ mqtt.Client, msg mqtt.Message) {
+		p.handle(msg.Topic(), msg.Payload())
+	})
+	if token.Wait() && token.Error() != nil {
+		log.Errorf("Failed to subscribe to %s: %v", p.topic("#"), token.Error())
+	}
+}
+
+// OnChange calls fn when plugged_in, battery_level, charging_state, the location,
+// geofence or state of the car change, including when they are first received.
+// fn must not block.
+func (p *MQTT) OnChange(fn func(Change)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onChange = append(p.onChange, fn)
+}
+
+// Connect connects to the broker. TeslaMate retains its messages, so the car data
+// is available shortly after.
+func (p *MQTT) Connect(ctx context.Context) error {
+	token := p.client.Connect()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-token.Done():
+	}
+	if err := token.Error(); err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", p.config.Broker, err)
+	}
+	return nil
+}
+
+// Close disconnects from the broker
+func (p *MQTT) Close() {
+	p.client.Disconnect(250)
+}
+
+// Status returns the latest car data, ErrNoData until the battery level was received
+// and ErrDisconnected while the connection to the broker is down
+func (p *MQTT) Status(ctx context.Context) (*Status, error) {
+	if !p.client.IsConnectionOpen() {
+		return nil, ErrDisconnected
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.received {
+		return nil, ErrNoData
+	}
+	status := p.status
+	if status.Location != nil {
+		location := *status.Location
+		status.Location = &location
+	}
+	return &status, nil
+}
+
+// topic returns the topic of the car below its prefix
+func (p *MQTT) topic(name string) string {
+	return fmt.Sprintf("%s/cars/%d/%s", p.config.TopicPrefix, p.carID, name)
+}
+
+// handle updates the car data with a message of TeslaMate and notifies relevant changes
+func (p *MQTT) handle(topic string, payload []byte) {
+	name, ok := strings.CutPrefix(topic, p.topic(""))
+	if !ok {
+		return
+	}
+	value := string(payload)
+
+	p.mu.Lock()
+	previous, known := p.values[name]
+	p.values[name] = value
+	p.update(name, value)
+	onChange := p.onChange
+	p.mu.Unlock()
+
+	if relevantTopics[name] && (!known || previous != value) {
+		for _, fn := range onChange {
+			fn(Change{Name: name, Value: value})
+		}
+	}
+}
+
+// update sets the value of the topic name in the car data. Malformed values are ignored.
+func (p *MQTT) update(name string, value string) {
+	s := &p.status
+	switch name {
+	case "battery_level":
+		if v, err := strconv.Atoi(value); err == nil {
+			s.SoC = v
+			p.received = true
+		}
+	case "charge_limit_soc":
+		s.ChargeLimit, _ = strconv.Atoi(value)
+	case "plugged_in":
+		s.PluggedIn = value == "true"
+	case "charging_state":
+		s.ChargingState = ChargingState(value)
+	case "state":
+		s.State = value
+	case "since":
+		s.StateSince, _ = time.Parse(time.RFC3339, value)
+	case "geofence":
+		s.Geofence = value
+	case "charge_energy_added":
+		s.ChargeEnergyAdded, _ = strconv.ParseFloat(value, 64)
+	case "sentry_mode":
+		s.SentryMode = value == "true"
+	case "locked":
+		s.Locked = value == "true"
+	case "is_user_present":
+		s.UserPresent = value == "true"
+	case "is_climate_on":
+		s.ClimateOn = value == "true"
+	case "inside_temp":
+		s.InsideTemp, _ = strconv.ParseFloat(value, 64)
+	case "outside_temp":
+		s.OutsideTemp, _ = strconv.ParseFloat(value, 64)
+	case "est_battery_range_km":
+		s.Range, _ = strconv.ParseFloat(value, 64)
+	case "latitude":
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			p.latitude = &v
+		}
+	case "longitude":
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			p.longitude = &v
+		}
+	case "location":
+		var location Location
+		if err := json.Unmarshal([]byte(value), &location); err == nil {
+			p.latitude, p.longitude = &location.Latitude, &location.Longitude
+		}
+	}
+	if p.latitude != nil && p.longitude != nil {
+		s.Location = &Location{Latitude: *p.latitude, Longitude: *p.longitude}
+	}
+}
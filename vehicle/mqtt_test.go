@@ -0,0 +1,185 @@
+package vehicle_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	mochi "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/hooks/auth"
+	"github.com/mochi-mqtt/server/v2/listeners"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/ekz-tesla/vehicle"
+)
+
+// startBroker starts a local MQTT broker on addr, e.g. 127.0.0.1:0, and returns it with
+// its address and a function stopping it
+func startBroker(t *testing.T, addr string) (*mochi.Server, string, func()) {
+	t.Helper()
+	server := mochi.New(&mochi.Options{
+		InlineClient: true,
+		Logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	require.NoError(t, server.AddHook(new(auth.AllowHook), nil))
+	tcp := listeners.NewTCP(listeners.Config{ID: "tcp", Address: addr})
+	require.NoError(t, server.AddListener(tcp))
+	go func() { _ = server.Serve() }()
+	var once sync.Once
+	stop := func() { once.Do(func() { _ = server.Close() }) }
+	t.Cleanup(stop)
+	return server, tcp.Address(), stop
+}
+
+// publishCar publishes retained values below teslamate/cars/1
+func publishCar(t *testing.T, server *mochi.Server, values map[string]string) {
+	t.Helper()
+	for name, value := range values {
+		require.NoError(t, server.Publish("teslamate/cars/1/"+name, []byte(value), true, 0))
+	}
+}
+
+// changeRecorder records the changes notified by a provider
+type changeRecorder struct {
+	mu      sync.Mutex
+	changes map[string]string
+	count   int
+}
+
+func (r *changeRecorder) record(change vehicle.Change) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.changes == nil {
+		r.changes = make(map[string]string)
+	}
+	r.changes[change.Name] = change.Value
+	r.count++
+}
+
+func (r *changeRecorder) get(name string) (string, int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.changes[name], r.count
+}
+
+func connectMQTT(t *testing.T, addr string) *vehicle.MQTT {
+	t.Helper()
+	p, err := vehicle.NewMQTT(vehicle.MQTTConfig{Broker: "tcp://" + addr, ClientID: t.Name()}, 1)
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, p.Connect(ctx))
+	t.Cleanup(p.Close)
+	return p
+}
+
+func TestMQTT_Status(t *testing.T) {
+	server, addr, _ := startBroker(t, "127.0.0.1:0")
+	p := connectMQTT(t, addr)
+
+	_, err := p.Status(context.Background())
+	assert.ErrorIs(t, err, vehicle.ErrNoData)
+
+	publishCar(t, server, map[string]string{
+		"battery_level":    "54",
+		"charge_limit_soc": "80",
+		"plugged_in":       "true",
+		"state":            "online",
+		"since":            "2025-01-13T21:00:00.123456Z",
+		"geofence":         "Home",
+		"latitude":         "47.3769",
+		"longitude":        "8.5417",
+		"sentry_mode":      "true",
+	})
+	// Other cars are ignored
+	require.NoError(t, server.Publish("teslamate/cars/2/battery_level", []byte("10"), true, 0))
+
+	require.EventuallyWithT(t, func(c *assert.CollectT) {
+		status, err := p.Status(context.Background())
+		require.NoError(c, err)
+		assert.Equal(c, 54, status.SoC)
+		assert.Equal(c, 80, status.ChargeLimit)
+		assert.True(c, status.PluggedIn)
+		assert.Equal(c, vehicle.ChargingStateStopped, status.CurrentChargingState())
+		assert.Equal(c, "Home", status.Geofence)
+		assert.Equal(c, &vehicle.Location{Latitude: 47.3769, Longitude: 8.5417}, status.Location)
+		assert.Equal(c, time.Date(2025, 1, 13, 21, 0, 0, 123456000, time.UTC), status.StateSince)
+		assert.True(c, status.SentryMode)
+	}, 5*time.Second, 20*time.Millisecond)
+}
+
+func TestMQTT_RetainedOnConnect(t *testing.T) {
+	server, addr, _ := startBroker(t, "127.0.0.1:0")
+	publishCar(t, server, map[string]string{"battery_level": "33", "plugged_in": "false"})
+
+	p := connectMQTT(t, addr)
+	require.EventuallyWithT(t, func(c *assert.CollectT) {
+		status, err := p.Status(context.Background())
+		require.NoError(c, err)
+		assert.Equal(c, 33, status.SoC)
+		assert.Equal(c, vehicle.ChargingStateDisconnected, status.CurrentChargingState())
+	}, 5*time.Second, 20*time.Millisecond)
+}
+
+func TestMQTT_OnChange(t *testing.T) {
+	server, addr, _ := startBroker(t, "127.0.0.1:0")
+	p := connectMQTT(t, addr)
+	var recorder changeRecorder
+	p.OnChange(recorder.record)
+
+	publishCar(t, server, map[string]string{"plugged_in": "false", "battery_level": "40"})
+	require.Eventually(t, func() bool {
+		_, count := recorder.get("")
+		return count == 2
+	}, 5*time.Second, 20*time.Millisecond)
+
+	// Repeated values and irrelevant topics are not changes
+	publishCar(t, server, map[string]string{"plugged_in": "false", "inside_temp": "21.5"})
+	publishCar(t, server, map[string]string{"plugged_in": "true"})
+	require.Eventually(t, func() bool {
+		value, _ := recorder.get("plugged_in")
+		return value == "true"
+	}, 5*time.Second, 20*time.Millisecond)
+	_, count := recorder.get("")
+	assert.Equal(t, 3, count)
+}
+
+func TestMQTT_Reconnect(t *testing.T) {
+	server, addr, stop := startBroker(t, "127.0.0.1:0")
+	publishCar(t, server, map[string]string{"battery_level": "50", "plugged_in": "false"})
+	p := connectMQTT(t, addr)
+	var recorder changeRecorder
+	p.OnChange(recorder.record)
+	require.Eventually(t, func() bool {
+		_, err := p.Status(context.Background())
+		return err == nil
+	}, 5*time.Second, 20*time.Millisecond)
+
+	stop()
+	require.Eventually(t, func() bool {
+		_, err := p.Status(context.Background())
+		return err == vehicle.ErrDisconnected
+	}, 5*time.Second, 20*time.Millisecond)
+
+	// The car was plugged in while the broker was down, the retained value arrives after the reconnect
+	server, _, _ = startBroker(t, addr)
+	publishCar(t, server, map[string]string{"battery_level": "50", "plugged_in": "true"})
+	require.EventuallyWithT(t, func(c *assert.CollectT) {
+		status, err := p.Status(context.Background())
+		require.NoError(c, err)
+		assert.True(c, status.PluggedIn)
+	}, 15*time.Second, 50*time.Millisecond)
+	value, _ := recorder.get("plugged_in")
+	assert.Equal(t, "true", value)
+}
+
+func TestNewMQTT(t *testing.T) {
+	_, err := vehicle.NewMQTT(vehicle.MQTTConfig{Broker: "tcp://localhost:1883"}, 0)
+	assert.ErrorContains(t, err, "car ID is required")
+	_, err = vehicle.NewMQTT(vehicle.MQTTConfig{}, 1)
+	assert.ErrorContains(t, err, "broker is required")
+}
@@ -0,0 +1,72 @@
+package vehicle
+
+import (
+	"context"
+
+	"github.com/denysvitali/ekz-tesla/teslamatedb"
+)
+
+// PostgresConfig configures the provider reading the TeslaMate database, whose DSN is
+// configured in teslamate_db
+type PostgresConfig struct {
+	// AssumePluggedIn treats the parked car as plugged in. TeslaMate only records charges,
+	// not whether the cable is plugged in, so without it the car is only seen as plugged in
+	// while it charges.
+	AssumePluggedIn bool `yaml:"assume_plugged_in,omitempty"`
+}
+
+// Postgres reads the car data from the TeslaMate database
+type Postgres struct {
+	db     *teslamatedb.DB
+	carID  int
+	config PostgresConfig
+}
+
+// NewPostgres creates a provider for the car with the given TeslaMate ID
+func NewPostgres(db *teslamatedb.DB, carID int, config PostgresConfig) *Postgres {
+	return &Postgres{db: db, carID: carID, config: config}
+}
+
+// Status returns the car data recorded by TeslaMate
+func (p *Postgres) Status(ctx context.Context) (*Status, error) {
+	car, err := p.db.CarState(ctx, p.carID)
+	if err != nil {
+		return nil, err
+	}
+	status := FromTeslaMateDB(*car)
+	if p.config.AssumePluggedIn && !car.Driving {
+		status.PluggedIn = true
+	}
+	return &status, nil
+}
+
+// FromTeslaMateDB converts the state of the car read from the TeslaMate database
+func FromTeslaMateDB(car teslamatedb.CarState) Status {
+	status := Status{
+		SoC:         car.Position.BatteryLevel,
+		Location:    &Location{Latitude: car.Position.Latitude, Longitude: car.Position.Longitude},
+		Geofence:    car.Geofence,
+		ClimateOn:   car.Position.IsClimateOn,
+		InsideTemp:  car.Position.InsideTemp,
+		OutsideTemp: car.Position.OutsideTemp,
+		Range:       car.Position.EstBatteryRange,
+		Updated:     car.Position.Date,
+	}
+	if car.State != nil {
+		status.State = car.State.State
+		status.StateSince = car.State.Since
+	}
+	switch {
+	case car.Driving:
+		status.State = "driving"
+	case car.Charging != nil:
+		status.State = "charging"
+		status.PluggedIn = true
+		status.ChargingState = ChargingStateCharging
+		status.ChargeEnergyAdded = car.Charging.ChargeEnergyAdded
+		if car.Charging.EndBatteryLevel > 0 {
+			status.SoC = car.Charging.EndBatteryLevel
+		}
+	}
+	return status
+}
@@ -0,0 +1,62 @@
+package vehicle_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/ekz-tesla/teslamatedb"
+	"github.com/denysvitali/ekz-tesla/vehicle"
+)
+
+func TestFromTeslaMateDB(t *testing.T) {
+	since := time.Date(2026, 3, 1, 17, 55, 0, 0, time.UTC)
+	updated := time.Date(2026, 3, 1, 20, 0, 0, 0, time.UTC)
+	parked := teslamatedb.CarState{
+		Position: teslamatedb.Position{Date: updated, Latitude: 47.3769, Longitude: 8.5417, BatteryLevel: 45, EstBatteryRange: 200},
+		State:    &teslamatedb.State{State: "asleep", Since: since},
+		Geofence: "Home",
+	}
+	status := vehicle.FromTeslaMateDB(parked)
+	assert.Equal(t, 45, status.SoC)
+	assert.False(t, status.PluggedIn)
+	assert.Equal(t, "asleep", status.State)
+	assert.Equal(t, since, status.StateSince)
+	assert.Equal(t, updated, status.Updated)
+	assert.Equal(t, &vehicle.Location{Latitude: 47.3769, Longitude: 8.5417}, status.Location)
+	assert.Equal(t, "Home", status.Geofence)
+	assert.InDelta(t, 200, status.Range, 1e-9)
+	assert.Equal(t, vehicle.ChargingStateDisconnected, status.CurrentChargingState())
+
+	charging := parked
+	charging.Charging = &teslamatedb.ChargingProcess{ID: 2, EndBatteryLevel: 46, ChargeEnergyAdded: 4.2}
+	status = vehicle.FromTeslaMateDB(charging)
+	assert.Equal(t, 46, status.SoC)
+	assert.True(t, status.PluggedIn)
+	assert.True(t, status.Charging())
+	assert.Equal(t, "charging", status.State)
+	assert.InDelta(t, 4.2, status.ChargeEnergyAdded, 1e-9)
+
+	driving := parked
+	driving.Driving = true
+	status = vehicle.FromTeslaMateDB(driving)
+	assert.Equal(t, "driving", status.State)
+	assert.False(t, status.PluggedIn)
+}
+
+func TestNew_Postgres(t *testing.T) {
+	config := vehicle.Config{Provider: vehicle.ProviderPostgres}
+	require.NoError(t, config.Validate())
+	_, err := vehicle.New(context.Background(), config, 1, vehicle.Sources{})
+	assert.ErrorContains(t, err, "requires the TeslaMate database DSN")
+
+	db, err := teslamatedb.Open("host=localhost dbname=teslamate")
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+	p, err := vehicle.New(context.Background(), config, 1, vehicle.Sources{DB: db})
+	require.NoError(t, err)
+	assert.IsType(t, &vehicle.Postgres{}, p)
+}
@@ -0,0 +1,62 @@
+package vehicle
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// StaticConfig holds car data entered by hand, for cars without TeslaMate
+type StaticConfig struct {
+	SoC           int           `yaml:"soc"`
+	ChargeLimit   int           `yaml:"charge_limit,omitempty"`
+	PluggedIn     bool          `yaml:"plugged_in"`
+	ChargingState ChargingState `yaml:"charging_state,omitempty"`
+	// Latitude and Longitude locate the car, the car is assumed at the station if unset
+	Latitude  *float64 `yaml:"latitude,omitempty"`
+	Longitude *float64 `yaml:"longitude,omitempty"`
+	Geofence  string   `yaml:"geofence,omitempty"`
+	// File is read on every status request and replaces the values above, so that
+	// the data can be updated by hand or by a script without restarting autostart
+	File string `yaml:"file,omitempty"`
+}
+
+// Static returns fixed car data
+type Static struct {
+	config StaticConfig
+}
+
+// NewStatic creates a provider returning the configured data
+func NewStatic(config StaticConfig) *Static {
+	return &Static{config: config}
+}
+
+// Status returns the configured data, read from the file if set
+func (p *Static) Status(ctx context.Context) (*Status, error) {
+	config := p.config
+	if config.File != "" {
+		data, err := os.ReadFile(config.File)
+		if err != nil {
+			return nil, err
+		}
+		config = StaticConfig{}
+		if err := yaml.Unmarshal(data, &config); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", p.config.File, err)
+		}
+	}
+
+	status := Status{
+		SoC:           config.SoC,
+		ChargeLimit:   config.ChargeLimit,
+		PluggedIn:     config.PluggedIn,
+		ChargingState: config.ChargingState,
+		State:         "online",
+		Geofence:      config.Geofence,
+	}
+	if config.Latitude != nil && config.Longitude != nil {
+		status.Location = &Location{Latitude: *config.Latitude, Longitude: *config.Longitude}
+	}
+	return &status, nil
+}
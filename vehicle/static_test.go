@@ -0,0 +1,50 @@
+package vehicle_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/ekz-tesla/vehicle"
+)
+
+func TestStatic_Status(t *testing.T) {
+	lat, lon := 47.3769, 8.5417
+	p := vehicle.NewStatic(vehicle.StaticConfig{SoC: 40, PluggedIn: true, Latitude: &lat, Longitude: &lon})
+
+	status, err := p.Status(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 40, status.SoC)
+	assert.True(t, status.PluggedIn)
+	assert.Equal(t, vehicle.ChargingStateStopped, status.CurrentChargingState())
+	assert.Equal(t, &vehicle.Location{Latitude: lat, Longitude: lon}, status.Location)
+
+	status, err = vehicle.NewStatic(vehicle.StaticConfig{SoC: 40}).Status(context.Background())
+	require.NoError(t, err)
+	assert.Nil(t, status.Location, "location is unknown without coordinates")
+}
+
+func TestStatic_StatusFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "car.yaml")
+	p := vehicle.NewStatic(vehicle.StaticConfig{SoC: 40, File: path})
+
+	_, err := p.Status(context.Background())
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	require.NoError(t, os.WriteFile(path, []byte("soc: 65\nplugged_in: true\ncharging_state: Complete\n"), 0o644))
+	status, err := p.Status(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 65, status.SoC)
+	assert.Equal(t, vehicle.ChargingStateComplete, status.CurrentChargingState())
+
+	// The file is read again on every request
+	require.NoError(t, os.WriteFile(path, []byte("soc: 70\nplugged_in: false\n"), 0o644))
+	status, err = p.Status(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 70, status.SoC)
+	assert.Equal(t, vehicle.ChargingStateDisconnected, status.CurrentChargingState())
+}
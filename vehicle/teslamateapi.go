@@ -0,0 +1,65 @@
+package vehicle
+
+import (
+	"context"
+
+	"github.com/denysvitali/ekz-tesla/teslamateapi"
+)
+
+// TeslaMateAPI reads the car data from TeslaMateApi and sends commands through it
+type TeslaMateAPI struct {
+	client *teslamateapi.Client
+	carID  int
+}
+
+// NewTeslaMateAPI creates a provider for the car with the given TeslaMate ID
+func NewTeslaMateAPI(client *teslamateapi.Client, carID int) *TeslaMateAPI {
+	return &TeslaMateAPI{client: client, carID: carID}
+}
+
+// Status returns the car status reported by TeslaMateApi
+func (p *TeslaMateAPI) Status(ctx context.Context) (*Status, error) {
+	response, err := p.client.GetCarStatusContext(ctx, p.carID)
+	if err != nil {
+		return nil, err
+	}
+	status := FromTeslaMateAPI(response.Status)
+	return &status, nil
+}
+
+// WakeUp wakes the car up and returns its state
+func (p *TeslaMateAPI) WakeUp(ctx context.Context) (string, error) {
+	return p.client.WakeUp(ctx, p.carID)
+}
+
+// SetChargeLimit sets the charge limit of the car
+func (p *TeslaMateAPI) SetChargeLimit(ctx context.Context, percent int) error {
+	return p.client.SetChargeLimit(ctx, p.carID, percent)
+}
+
+// ChargeStart makes the car start charging
+func (p *TeslaMateAPI) ChargeStart(ctx context.Context) error {
+	return p.client.ChargeStart(ctx, p.carID)
+}
+
+// FromTeslaMateAPI converts the car status reported by TeslaMateApi
+func FromTeslaMateAPI(s teslamateapi.CarStatus) Status {
+	return Status{
+		SoC:               s.BatteryDetails.BatteryLevel,
+		ChargeLimit:       int(s.ChargingDetails.ChargeLimitSoc),
+		PluggedIn:         s.ChargingDetails.PluggedIn,
+		ChargingState:     ChargingState(s.CurrentChargingState()),
+		State:             s.State,
+		StateSince:        s.StateSince,
+		Location:          &Location{Latitude: s.CarGeodata.Latitude, Longitude: s.CarGeodata.Longitude},
+		Geofence:          s.CarGeodata.Geofence,
+		ChargeEnergyAdded: float64(s.ChargingDetails.ChargeEnergyAdded),
+		SentryMode:        s.CarStatus.SentryMode,
+		Locked:            s.CarStatus.Locked,
+		UserPresent:       s.CarStatus.IsUserPresent,
+		ClimateOn:         s.ClimateDetails.IsClimateOn,
+		InsideTemp:        s.ClimateDetails.InsideTemp,
+		OutsideTemp:       s.ClimateDetails.OutsideTemp,
+		Range:             s.BatteryDetails.EstBatteryRange,
+	}
+}
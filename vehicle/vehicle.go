@@ -0,0 +1,216 @@
+// Package vehicle provides the car data autostart decides on. The data is read from
+// TeslaMateApi, TeslaMate's MQTT topics or database, a generic HTTP/JSON endpoint or
+// static values.
+package vehicle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/denysvitali/ekz-tesla/teslamateapi"
+	"github.com/denysvitali/ekz-tesla/teslamatedb"
+)
+
+// Provider names, as selected in the configuration
+const (
+	ProviderTeslaMateAPI = "teslamateapi"
+	ProviderMQTT         = "mqtt"
+	ProviderPostgres     = "postgres"
+	ProviderHTTP         = "http"
+	ProviderStatic       = "static"
+)
+
+// ErrNoData is returned by providers that didn't receive any data for the car yet
+var ErrNoData = errors.New("no data received for the car yet")
+
+// ChargingState is the charging state reported by the car
+type ChargingState string
+
+const (
+	ChargingStateCharging     ChargingState = "Charging"
+	ChargingStateStarting     ChargingState = "Starting"
+	ChargingStateComplete     ChargingState = "Complete"
+	ChargingStateStopped      ChargingState = "Stopped"
+	ChargingStateDisconnected ChargingState = "Disconnected"
+	ChargingStateNoPower      ChargingState = "NoPower"
+)
+
+// Location is the position of the car
+type Location struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// Status is the car data autostart decides on. Providers leave the fields they
+// don't know at their zero value.
+type Status struct {
+	// SoC is the battery level in percent
+	SoC int `json:"soc"`
+	// ChargeLimit is the charge limit set in the car in percent, 0 if unknown
+	ChargeLimit int  `json:"charge_limit,omitempty"`
+	PluggedIn   bool `json:"plugged_in"`
+	// ChargingState is empty when the provider doesn't report it, see CurrentChargingState
+	ChargingState ChargingState `json:"charging_state,omitempty"`
+	// State is the TeslaMate state of the car: online, asleep, offline, charging, driving, ...
+	State      string    `json:"state,omitempty"`
+	StateSince time.Time `json:"state_since,omitzero"`
+	// Location is nil when the provider doesn't know where the car is
+	Location *Location `json:"location,omitempty"`
+	// Geofence is the TeslaMate geofence the car is in
+	Geofence string `json:"geofence,omitempty"`
+	// ChargeEnergyAdded is the energy added by the current or last charge in kWh
+	ChargeEnergyAdded float64 `json:"charge_energy_added,omitempty"`
+
+	SentryMode  bool    `json:"sentry_mode,omitempty"`
+	Locked      bool    `json:"locked,omitempty"`
+	UserPresent bool    `json:"user_present,omitempty"`
+	ClimateOn   bool    `json:"climate_on,omitempty"`
+	InsideTemp  float64 `json:"inside_temp,omitempty"`
+	OutsideTemp float64 `json:"outside_temp,omitempty"`
+	// Range is the estimated range in km
+	Range float64 `json:"range,omitempty"`
+
+	// Updated is when the data was measured, zero if the provider doesn't report it
+	Updated time.Time `json:"updated,omitzero"`
+}
+
+// CurrentChargingState returns the charging state of the car, derived from the
+// car state when the provider doesn't report it
+func (s Status) CurrentChargingState() ChargingState {
+	if s.ChargingState != "" {
+		return s.ChargingState
+	}
+	switch {
+	case s.State == "charging":
+		return ChargingStateCharging
+	case !s.PluggedIn:
+		return ChargingStateDisconnected
+	default:
+		return ChargingStateStopped
+	}
+}
+
+// Charging tells whether the car is charging or about to
+func (s Status) Charging() bool {
+	state := s.CurrentChargingState()
+	return state == ChargingStateCharging || state == ChargingStateStarting
+}
+
+// DataAge returns how old the data may be. While the car is asleep or offline, the
+// data dates back to StateSince; otherwise to Updated, if the provider reports it.
+func (s Status) DataAge(now time.Time) time.Duration {
+	switch s.State {
+	case "asleep", "offline", "suspended":
+		if !s.StateSince.IsZero() {
+			return now.Sub(s.StateSince)
+		}
+	}
+	if s.Updated.IsZero() {
+		return 0
+	}
+	return now.Sub(s.Updated)
+}
+
+// Provider returns the current data of a car
+type Provider interface {
+	Status(ctx context.Context) (*Status, error)
+}
+
+// Change is a change of a value of the car data
+type Change struct {
+	// Name is the name of the value, e.g. plugged_in
+	Name  string
+	Value string
+}
+
+// Notifier is implemented by providers that push the changes of the car data, so that
+// autostart can react immediately instead of waiting for the next check
+type Notifier interface {
+	OnChange(fn func(Change))
+}
+
+// Commander sends commands to a car. Only some providers support it.
+type Commander interface {
+	// WakeUp wakes the car up and returns its state
+	WakeUp(ctx context.Context) (string, error)
+	SetChargeLimit(ctx context.Context, percent int) error
+	ChargeStart(ctx context.Context) error
+}
+
+// Config selects and configures the provider of the car data
+type Config struct {
+	// Provider is teslamateapi (default), mqtt, postgres, http or static
+	Provider string         `yaml:"provider,omitempty"`
+	MQTT     MQTTConfig     `yaml:"mqtt,omitempty"`
+	Postgres PostgresConfig `yaml:"postgres,omitempty"`
+	HTTP     HTTPConfig     `yaml:"http,omitempty"`
+	Static   StaticConfig   `yaml:"static,omitempty"`
+}
+
+// ProviderName returns the selected provider, TeslaMateApi by default
+func (c Config) ProviderName() string {
+	if c.Provider == "" {
+		return ProviderTeslaMateAPI
+	}
+	return c.Provider
+}
+
+// Validate checks the configuration of the selected provider
+func (c Config) Validate() error {
+	switch c.ProviderName() {
+	case ProviderTeslaMateAPI, ProviderPostgres:
+		return nil
+	case ProviderMQTT:
+		return c.MQTT.Validate()
+	case ProviderHTTP:
+		return c.HTTP.Validate()
+	case ProviderStatic:
+		return nil
+	default:
+		return fmt.Errorf("unknown vehicle provider %q, expected %s, %s, %s, %s or %s",
+			c.Provider, ProviderTeslaMateAPI, ProviderMQTT, ProviderPostgres, ProviderHTTP, ProviderStatic)
+	}
+}
+
+// Sources are the TeslaMate clients the providers read from, nil if not configured
+type Sources struct {
+	// API is required by the teslamateapi provider
+	API *teslamateapi.Client
+	// DB is required by the postgres provider
+	DB *teslamatedb.DB
+}
+
+// New creates the configured provider for the car with the given TeslaMate ID and
+// connects it if needed
+func New(ctx context.Context, config Config, carID int, sources Sources) (Provider, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	switch config.ProviderName() {
+	case ProviderMQTT:
+		p, err := NewMQTT(config.MQTT, carID)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.Connect(ctx); err != nil {
+			return nil, err
+		}
+		return p, nil
+	case ProviderPostgres:
+		if sources.DB == nil {
+			return nil, fmt.Errorf("the %s provider requires the TeslaMate database DSN", ProviderPostgres)
+		}
+		return NewPostgres(sources.DB, carID, config.Postgres), nil
+	case ProviderHTTP:
+		return NewHTTP(config.HTTP)
+	case ProviderStatic:
+		return NewStatic(config.Static), nil
+	default:
+		if sources.API == nil {
+			return nil, fmt.Errorf("the %s provider requires the TeslaMate API URL", ProviderTeslaMateAPI)
+		}
+		return NewTeslaMateAPI(sources.API, carID), nil
+	}
+}
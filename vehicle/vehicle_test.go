@@ -0,0 +1,86 @@
+package vehicle_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/ekz-tesla/teslamateapi"
+	"github.com/denysvitali/ekz-tesla/vehicle"
+)
+
+func TestStatus_DataAge(t *testing.T) {
+	now := time.Date(2025, 1, 13, 22, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		status   vehicle.Status
+		expected time.Duration
+	}{
+		{"online", vehicle.Status{State: "online", StateSince: now.Add(-time.Hour)}, 0},
+		{"asleep", vehicle.Status{State: "asleep", StateSince: now.Add(-time.Hour)}, time.Hour},
+		{"measured", vehicle.Status{Updated: now.Add(-10 * time.Minute)}, 10 * time.Minute},
+		{"unknown", vehicle.Status{}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.status.DataAge(now))
+		})
+	}
+}
+
+func TestStatus_Charging(t *testing.T) {
+	assert.True(t, vehicle.Status{ChargingState: vehicle.ChargingStateStarting}.Charging())
+	assert.True(t, vehicle.Status{State: "charging", PluggedIn: true}.Charging())
+	assert.False(t, vehicle.Status{PluggedIn: true}.Charging())
+}
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, vehicle.Config{}.Validate())
+	assert.Equal(t, vehicle.ProviderTeslaMateAPI, vehicle.Config{}.ProviderName())
+	assert.NoError(t, vehicle.Config{Provider: vehicle.ProviderStatic}.Validate())
+	assert.ErrorContains(t, vehicle.Config{Provider: vehicle.ProviderMQTT}.Validate(), "broker is required")
+	assert.ErrorContains(t, vehicle.Config{Provider: vehicle.ProviderHTTP}.Validate(), "url is required")
+	assert.ErrorContains(t, vehicle.Config{Provider: "carrier-pigeon"}.Validate(), `unknown vehicle provider "carrier-pigeon"`)
+}
+
+func TestNew(t *testing.T) {
+	_, err := vehicle.New(context.Background(), vehicle.Config{}, 1, vehicle.Sources{})
+	assert.ErrorContains(t, err, "requires the TeslaMate API URL")
+
+	p, err := vehicle.New(context.Background(), vehicle.Config{Provider: vehicle.ProviderStatic}, 0, vehicle.Sources{})
+	require.NoError(t, err)
+	assert.IsType(t, &vehicle.Static{}, p)
+}
+
+func TestTeslaMateAPI_Status(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/cars/2/status", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data": {"car": {"car_id": 2}, "status": {
+			"state": "online",
+			"battery_details": {"battery_level": 62},
+			"charging_details": {"plugged_in": true, "charge_limit_soc": 80, "charge_energy_added": 4.5},
+			"car_geodata": {"geofence": "Home", "latitude": 47.3769, "longitude": 8.5417},
+			"car_status": {"sentry_mode": true}
+		}}}`))
+	}))
+	defer server.Close()
+
+	client, err := teslamateapi.New(server.URL)
+	require.NoError(t, err)
+	status, err := vehicle.NewTeslaMateAPI(client, 2).Status(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 62, status.SoC)
+	assert.Equal(t, 80, status.ChargeLimit)
+	assert.True(t, status.PluggedIn)
+	assert.Equal(t, vehicle.ChargingStateStopped, status.ChargingState)
+	assert.Equal(t, "Home", status.Geofence)
+	assert.Equal(t, &vehicle.Location{Latitude: 47.3769, Longitude: 8.5417}, status.Location)
+	assert.InDelta(t, 4.5, status.ChargeEnergyAdded, 0.001)
+	assert.True(t, status.SentryMode)
+}